@@ -33,26 +33,29 @@ const (
 
 // 32-bit Syscall codes
 const (
-	SysMmap         = 4090
-	SysBrk          = 4045
-	SysClone        = 4120
-	SysExitGroup    = 4246
-	SysRead         = 4003
-	SysWrite        = 4004
-	SysFcntl        = 4055
-	SysExit         = 4001
-	SysSchedYield   = 4162
-	SysGetTID       = 4222
-	SysFutex        = 4238
-	SysOpen         = 4005
-	SysNanosleep    = 4166
-	SysClockGetTime = 4263
-	SysGetpid       = 4020
+	SysMmap           = 4090
+	SysBrk            = 4045
+	SysClone          = 4120
+	SysExitGroup      = 4246
+	SysRead           = 4003
+	SysWrite          = 4004
+	SysFcntl          = 4055
+	SysExit           = 4001
+	SysSchedYield     = 4162
+	SysGetTID         = 4222
+	SysFutex          = 4238
+	SysOpen           = 4005
+	SysNanosleep      = 4166
+	SysClockGetTime   = 4263
+	SysClockNanosleep = 4265
+	SysGetTimeOfDay   = 4078
+	SysMunmap         = 4091
+	SysRseq           = 4367
+	SysGetpid         = 4020
 )
 
 // Noop Syscall codes
 const (
-	SysMunmap        = 4091
 	SysGetAffinity   = 4240
 	SysMadvise       = 4218
 	SysRtSigprocmask = 4195
@@ -72,16 +75,25 @@ const (
 	SysPipe2         = 4328
 	SysEpollCtl      = 4249
 	SysEpollPwait    = 4313
+	SysEpollCreate   = 4248
+	SysEpollWait     = 4250
 	SysGetRandom     = 4353
 	SysUname         = 4122
 	SysStat64        = 4213
 	SysGetuid        = 4024
 	SysGetgid        = 4047
+	SysGeteuid       = 4049
+	SysGetegid       = 4050
 	SysLlseek        = 4140
 	SysMinCore       = 4217
 	SysTgkill        = 4266
 	SysGetRLimit     = 4076
 	SysLseek         = 4019
+	SysSetRobustList = 4309
+	SysPrctl         = 4192
+	SysFaccessat     = 4300
+	SysPoll          = 4188
+	SysPpoll         = 4302
 	// Profiling-related syscalls
 	SysSetITimer    = 4104
 	SysTimerCreate  = 4257
@@ -89,6 +101,25 @@ const (
 	SysTimerDelete  = 4261
 )
 
+// Network syscall codes - there is no network, so these fail with EAFNOSUPPORT
+const (
+	SysSocket   = 4183
+	SysConnect  = 4170
+	SysAccept   = 4168
+	SysBind     = 4169
+	SysListen   = 4174
+	SysSendto   = 4180
+	SysRecvfrom = 4176
+)
+
+// SysV shared memory syscall codes - shared memory is not modeled, so these fail with ENOSYS
+const (
+	SysShmget = 4395
+	SysShmat  = 4397
+	SysShmctl = 4396
+	SysShmdt  = 4398
+)
+
 var ByteOrderWord = byteOrder32{}
 
 type byteOrder32 struct{}
@@ -38,21 +38,25 @@ const (
 
 // 64-bit Syscall numbers - new
 const (
-	SysMmap         = 5009
-	SysBrk          = 5012
-	SysClone        = 5055
-	SysExitGroup    = 5205
-	SysRead         = 5000
-	SysWrite        = 5001
-	SysFcntl        = 5070
-	SysExit         = 5058
-	SysSchedYield   = 5023
-	SysGetTID       = 5178
-	SysFutex        = 5194
-	SysOpen         = 5002
-	SysNanosleep    = 5034
-	SysClockGetTime = 5222
-	SysGetpid       = 5038
+	SysMmap           = 5009
+	SysBrk            = 5012
+	SysClone          = 5055
+	SysExitGroup      = 5205
+	SysRead           = 5000
+	SysWrite          = 5001
+	SysFcntl          = 5070
+	SysExit           = 5058
+	SysSchedYield     = 5023
+	SysGetTID         = 5178
+	SysFutex          = 5194
+	SysOpen           = 5002
+	SysNanosleep      = 5034
+	SysClockGetTime   = 5222
+	SysClockNanosleep = 5224
+	SysGetTimeOfDay   = 5094
+	SysMunmap         = 5011
+	SysRseq           = 5327
+	SysGetpid         = 5038
 )
 
 // Noop Syscall numbers
@@ -60,7 +64,6 @@ const (
 	// UndefinedSysNr is the value used for 32-bit syscall numbers that aren't supported for 64-bits
 	UndefinedSysNr = ^Word(0)
 
-	SysMunmap        = 5011
 	SysGetAffinity   = 5196
 	SysMadvise       = 5027
 	SysRtSigprocmask = 5014
@@ -80,16 +83,25 @@ const (
 	SysPipe2         = 5287
 	SysEpollCtl      = 5208
 	SysEpollPwait    = 5272
+	SysEpollCreate   = 5207
+	SysEpollWait     = 5209
 	SysGetRandom     = 5313
 	SysUname         = 5061
 	SysStat64        = UndefinedSysNr
 	SysGetuid        = 5100
 	SysGetgid        = 5102
+	SysGeteuid       = 5105
+	SysGetegid       = 5106
 	SysLlseek        = UndefinedSysNr
 	SysMinCore       = 5026
 	SysTgkill        = 5225
 	SysGetRLimit     = 5095
 	SysLseek         = 5008
+	SysSetRobustList = 5268
+	SysPrctl         = 5153
+	SysFaccessat     = 5259
+	SysPoll          = 5007
+	SysPpoll         = 5261
 	// Profiling-related syscalls
 	SysSetITimer    = 5036
 	SysTimerCreate  = 5216
@@ -97,6 +109,25 @@ const (
 	SysTimerDelete  = 5220
 )
 
+// Network syscall numbers - there is no network, so these fail with EAFNOSUPPORT
+const (
+	SysSocket   = 5040
+	SysConnect  = 5041
+	SysAccept   = 5042
+	SysBind     = 5048
+	SysListen   = 5049
+	SysSendto   = 5043
+	SysRecvfrom = 5044
+)
+
+// SysV shared memory syscall codes - shared memory is not modeled, so these fail with ENOSYS
+const (
+	SysShmget = 5028
+	SysShmat  = 5029
+	SysShmctl = 5030
+	SysShmdt  = 5065
+)
+
 var ByteOrderWord = byteOrder64{}
 
 type byteOrder64 struct{}
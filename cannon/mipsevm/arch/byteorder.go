@@ -1,5 +1,11 @@
 package arch
 
+import "debug/elf"
+
+// ElfData is the data encoding of the programs that can be loaded. Only big-endian MIPS is supported:
+// ByteOrderWord packs memory words, and thus instructions and witnesses, in big-endian order.
+const ElfData = elf.ELFDATA2MSB
+
 type ByteOrder interface {
 	Word([]byte) Word
 	AppendWord([]byte, Word) []byte
@@ -7,4 +7,6 @@ type DebugInfo struct {
 	MemoryUsed          hexutil.Uint64 `json:"memory_used"`
 	NumPreimageRequests int            `json:"num_preimage_requests"`
 	TotalPreimageSize   int            `json:"total_preimage_size"`
+	// SyscallCounts maps each syscall number to the number of times it was invoked, if tracked by the VM
+	SyscallCounts map[uint64]uint64 `json:"syscall_counts,omitempty"`
 }
@@ -15,6 +15,11 @@ type MemoryTrackerImpl struct {
 	memory          *memory.Memory
 	lastMemAccess   Word
 	memProofEnabled bool
+	// addresses of the first and second proven memory accesses, or ^0 if unused
+	memProofAddr  Word
+	memProof2Addr Word
+	// size of the block zeroed at memProofAddr, or 0 if none
+	memZeroSize Word
 	// proof of first unique memory access
 	memProof [memory.MemProofSize]byte
 	// proof of second unique memory access
@@ -31,6 +36,7 @@ func (m *MemoryTrackerImpl) TrackMemAccess(effAddr Word) {
 			panic(fmt.Errorf("unexpected different mem access at %08x, already have access at %08x buffered", effAddr, m.lastMemAccess))
 		}
 		m.lastMemAccess = effAddr
+		m.memProofAddr = effAddr
 		m.memProof = m.memory.MerkleProof(effAddr)
 	}
 }
@@ -42,12 +48,37 @@ func (m *MemoryTrackerImpl) TrackMemAccess2(effAddr Word) {
 		panic(fmt.Errorf("unexpected disjointed mem access at %08x, last memory access is at %08x buffered", effAddr, m.lastMemAccess))
 	}
 	m.lastMemAccess = effAddr
+	if m.memProofEnabled {
+		m.memProof2Addr = effAddr
+	}
 	m.memProof2 = m.memory.MerkleProof(effAddr)
 }
 
+// TrackDisjointMemAccess2 creates a proof for a second memory access within the same step, at any address
+// Like TrackMemAccess2, the proof is taken after the first access is applied
+func (m *MemoryTrackerImpl) TrackDisjointMemAccess2(effAddr Word) {
+	if m.memProofEnabled {
+		m.lastMemAccess = effAddr
+		m.memProof2Addr = effAddr
+		m.memProof2 = m.memory.MerkleProof(effAddr)
+	}
+}
+
+// TrackMemZero creates a proof for zeroing the block of size bytes at effAddr, see memory.Memory.Zero.
+// The block is a subtree of the memory merkle tree, so the proof of its first leaf proves the whole block.
+func (m *MemoryTrackerImpl) TrackMemZero(effAddr Word, size Word) {
+	m.TrackMemAccess(effAddr)
+	if m.memProofEnabled {
+		m.memZeroSize = size
+	}
+}
+
 func (m *MemoryTrackerImpl) Reset(enableProof bool) {
 	m.memProofEnabled = enableProof
 	m.lastMemAccess = ^Word(0)
+	m.memProofAddr = ^Word(0)
+	m.memProof2Addr = ^Word(0)
+	m.memZeroSize = 0
 }
 
 func (m *MemoryTrackerImpl) MemProof() [memory.MemProofSize]byte {
@@ -58,6 +89,25 @@ func (m *MemoryTrackerImpl) MemProof2() [memory.MemProofSize]byte {
 	return m.memProof2
 }
 
+// RestoreMemProofs sets the proofs returned by MemProof and MemProof2 until they are replaced by tracked accesses.
+// Steps that do not access memory reuse the proofs of earlier steps, so a run resumed from a checkpoint restores them
+// to produce identical witnesses.
+func (m *MemoryTrackerImpl) RestoreMemProofs(memProof, memProof2 [memory.MemProofSize]byte) {
+	m.memProof = memProof
+	m.memProof2 = memProof2
+}
+
+// MemProofAddrs returns the addresses proven by MemProof and MemProof2 since the last Reset.
+// An address is ^0 if the corresponding proof was not used.
+func (m *MemoryTrackerImpl) MemProofAddrs() (addr Word, addr2 Word) {
+	return m.memProofAddr, m.memProof2Addr
+}
+
+// MemZeroSize returns the size of the block zeroed at the address of MemProof since the last Reset, or 0 if none.
+func (m *MemoryTrackerImpl) MemZeroSize() Word {
+	return m.memZeroSize
+}
+
 type NoopMemoryTracker struct{}
 
 func (n *NoopMemoryTracker) TrackMemAccess(Word) {}
@@ -29,15 +29,21 @@ const (
 	FdHintWrite     = 4
 	FdPreimageRead  = 5
 	FdPreimageWrite = 6
+	// FdEpoll is returned for every epoll instance. No fd is ever registered as ready, so it is never read.
+	FdEpoll = 7
 )
 
 // Errors
 const (
-	SysErrorSignal = ^Word(0)
-	MipsEBADF      = 0x9
-	MipsEINVAL     = 0x16
-	MipsEAGAIN     = 0xb
-	MipsETIMEDOUT  = 0x91
+	SysErrorSignal   = ^Word(0)
+	MipsEBADF        = 0x9
+	MipsEINVAL       = 0x16
+	MipsEAGAIN       = 0xb
+	MipsETIMEDOUT    = 0x91
+	MipsEAFNOSUPPORT = 0x7c
+	MipsENOENT       = 0x2
+	MipsEFAULT       = 0xe
+	MipsENOSYS       = 0x59
 )
 
 // SysFutex-related constants
@@ -47,6 +53,36 @@ const (
 	FutexTimeoutSteps = 10_000
 	FutexNoTimeout    = ^uint64(0)
 	FutexEmptyAddr    = ^Word(0)
+
+	// Waiters record the bitset of their wait, and a wake only reaches waiters whose bitset intersects its own.
+	// The plain variants use FutexBitsetMatchAny. A zero bitset is rejected with EINVAL.
+	FutexWaitBitsetPrivate = 137
+	FutexWakeBitsetPrivate = 138
+	FutexBitsetMatchAny    = 0xFFFF_FFFF
+)
+
+// SysReadlinkAt paths
+const (
+	// ProcSelfExe is the only path that resolves to a link, including its NUL terminator. Go programs read it to find
+	// their executable.
+	ProcSelfExe = "/proc/self/exe\x00"
+	// ExePath is the fixed target of ProcSelfExe. It fits in a single word, which is all that a step writes to the
+	// buffer.
+	ExePath = "/exe"
+)
+
+// Signals
+const (
+	// SigAbrt is raised via tgkill by guests that abort, e.g. Go's runtime.abort or libc abort().
+	SigAbrt = 6
+)
+
+// SysPrctl options
+const (
+	// PrSetName names the calling thread. Thread names are not modeled, so it has no effect.
+	PrSetName = 15
+	// PrSetVma names an anonymous memory mapping, as done by the Go runtime. Mapping names are not modeled either.
+	PrSetVma = 0x53564d41
 )
 
 // SysClone flags
@@ -97,6 +133,12 @@ const (
 	ClockGettimeRealtimeFlag = 0
 	// ClockGettimeMonotonicFlag is the clock_gettime clock id for Linux's monotonic clock: https://github.com/torvalds/linux/blob/ad618736883b8970f66af799e34007475fe33a68/include/uapi/linux/time.h#L50
 	ClockGettimeMonotonicFlag = 1
+	// TimerAbstime is the clock_nanosleep flag for an absolute wakeup time, TIMER_ABSTIME in Linux's include/uapi/linux/time.h
+	TimerAbstime = 1
+	// TimezoneSize is the size in bytes of Linux's struct timezone, two ints, written by gettimeofday
+	TimezoneSize = 8
+	// RseqFlagUnregister is the rseq flag to unregister a restartable sequence area, RSEQ_FLAG_UNREGISTER in Linux's include/uapi/linux/rseq.h
+	RseqFlagUnregister = 1
 )
 
 func GetSyscallArgs(registers *[32]Word) (syscallNum, a0, a1, a2, a3 Word) {
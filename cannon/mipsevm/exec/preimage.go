@@ -2,10 +2,35 @@ package exec
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
 )
 
+// ErrPreimageOffsetOutOfBounds is returned when a read offset lies beyond the end of the length-prefixed preimage,
+// e.g. because a state was loaded from a corrupt checkpoint.
+var ErrPreimageOffsetOutOfBounds = errors.New("preimage offset out-of-bounds")
+
+// ErrPreimageBudgetExceeded is returned when the preimages served exceed the configured byte budget,
+// see TrackingPreimageOracleReader.SetMaxPreimageBytes.
+var ErrPreimageBudgetExceeded = errors.New("preimage byte budget exceeded")
+
+// ErrTooManyPreimages is returned when more distinct preimages were requested than allowed,
+// see TrackingPreimageOracleReader.SetMaxDistinctPreimages.
+var ErrTooManyPreimages = errors.New("too many distinct preimages")
+
+// ErrInvalidPreimageKeyType is returned when a preimage is read with a key of a type that is not allowed,
+// see TrackingPreimageOracleReader.SetAllowedKeyTypes.
+var ErrInvalidPreimageKeyType = errors.New("invalid preimage key type")
+
+// PreimageInterceptor transforms the data of a preimage before it is served, e.g. to inject faults in tests.
+// It must return the data to serve, and may modify data in place.
+type PreimageInterceptor func(key [32]byte, data []byte) []byte
+
 type PreimageReader interface {
 	ReadPreimage(key [32]byte, offset Word) (dat [32]byte, datLen Word)
 }
@@ -17,6 +42,20 @@ type TrackingPreimageOracleReader struct {
 
 	totalPreimageSize   int
 	numPreimageRequests int
+	// maximum total preimage size that may be read, or 0 if unlimited
+	maxPreimageBytes int
+	// keys of the preimages requested from the oracle
+	distinctKeys map[[32]byte]struct{}
+	// maximum number of distinct preimages that may be read, or 0 if unlimited
+	maxDistinctPreimages int
+	// key types of the preimages that may be read, or nil if any key type may be read
+	allowedKeyTypes []preimage.KeyType
+	// transforms preimage data before it is served, or nil to serve it as-is
+	interceptor PreimageInterceptor
+	// preimages served by the oracle, by key, or nil if they are not recorded
+	recorded map[[32]byte][]byte
+	// furthest offset into the length-prefixed preimage reached by reads, by key
+	maxOffsets map[[32]byte]Word
 
 	// cached pre-image data, including 8 byte length prefix
 	lastPreimage []byte
@@ -27,41 +66,189 @@ type TrackingPreimageOracleReader struct {
 }
 
 func NewTrackingPreimageOracleReader(po mipsevm.PreimageOracle) *TrackingPreimageOracleReader {
-	return &TrackingPreimageOracleReader{po: po}
+	return &TrackingPreimageOracleReader{po: po, distinctKeys: make(map[[32]byte]struct{})}
+}
+
+// SetMaxPreimageBytes bounds the total size of the preimages that may be read. Once TotalPreimageSize exceeds max,
+// CheckLimits fails with ErrPreimageBudgetExceeded. A max of 0 disables the limit.
+func (p *TrackingPreimageOracleReader) SetMaxPreimageBytes(max int) {
+	p.maxPreimageBytes = max
+}
+
+// SetMaxDistinctPreimages bounds the number of distinct preimage keys that may be read, independently of their size.
+// Once NumDistinctPreimages exceeds max, CheckLimits fails with ErrTooManyPreimages. A max of 0 disables the limit.
+func (p *TrackingPreimageOracleReader) SetMaxDistinctPreimages(max int) {
+	p.maxDistinctPreimages = max
+}
+
+// SetAllowedKeyTypes restricts the key types of the preimages that may be read. CheckKeyType fails with
+// ErrInvalidPreimageKeyType for a key of any other type. Passing no types, the default, allows any key type.
+func (p *TrackingPreimageOracleReader) SetAllowedKeyTypes(types ...preimage.KeyType) {
+	p.allowedKeyTypes = slices.Clone(types)
+}
+
+// SetPreimageInterceptor installs fn to transform every preimage fetched from the oracle. A nil fn, the default,
+// serves preimages unmodified. Interception is off-chain only: the on-chain VM reads the preimage oracle contract,
+// so witnesses of steps that read intercepted data do not verify.
+func (p *TrackingPreimageOracleReader) SetPreimageInterceptor(fn PreimageInterceptor) {
+	p.interceptor = fn
+}
+
+// SetRecordPreimages enables or disables recording the preimages served, to be exported with ExportBundle.
+// Recording keeps every distinct preimage in memory, so it is disabled by default. Disabling drops the recording.
+func (p *TrackingPreimageOracleReader) SetRecordPreimages(enabled bool) {
+	if !enabled {
+		p.recorded = nil
+	} else if p.recorded == nil {
+		p.recorded = make(map[[32]byte][]byte)
+	}
+}
+
+// CopyConfig configures the reader with the limits, allowed key types and interceptor of other.
+// The accounting and whether preimages are recorded are not copied.
+func (p *TrackingPreimageOracleReader) CopyConfig(other *TrackingPreimageOracleReader) {
+	p.maxPreimageBytes = other.maxPreimageBytes
+	p.maxDistinctPreimages = other.maxDistinctPreimages
+	p.allowedKeyTypes = slices.Clone(other.allowedKeyTypes)
+	p.interceptor = other.interceptor
+}
+
+// PreimageTrackerCheckpoint is a copy of the accounting of a TrackingPreimageOracleReader, see Checkpoint.
+type PreimageTrackerCheckpoint struct {
+	totalPreimageSize   int
+	numPreimageRequests int
+	distinctKeys        map[[32]byte]struct{}
+	recorded            map[[32]byte][]byte
+	maxOffsets          map[[32]byte]Word
+	lastPreimage        []byte
+	lastPreimageKey     [32]byte
+	lastPreimageOffset  Word
+}
+
+// Checkpoint copies the request counters, recorded preimages, read offsets and the cached last preimage.
+// The configured limits, interceptor and whether preimages are recorded are not part of the checkpoint.
+func (p *TrackingPreimageOracleReader) Checkpoint() *PreimageTrackerCheckpoint {
+	return &PreimageTrackerCheckpoint{
+		totalPreimageSize:   p.totalPreimageSize,
+		numPreimageRequests: p.numPreimageRequests,
+		distinctKeys:        maps.Clone(p.distinctKeys),
+		recorded:            maps.Clone(p.recorded),
+		maxOffsets:          maps.Clone(p.maxOffsets),
+		lastPreimage:        p.lastPreimage,
+		lastPreimageKey:     p.lastPreimageKey,
+		lastPreimageOffset:  p.lastPreimageOffset,
+	}
+}
+
+// Restore resets the accounting to that of cp. cp is not modified and may be restored again.
+func (p *TrackingPreimageOracleReader) Restore(cp *PreimageTrackerCheckpoint) {
+	p.totalPreimageSize = cp.totalPreimageSize
+	p.numPreimageRequests = cp.numPreimageRequests
+	p.distinctKeys = maps.Clone(cp.distinctKeys)
+	if p.recorded != nil {
+		p.recorded = make(map[[32]byte][]byte, len(cp.recorded))
+		maps.Copy(p.recorded, cp.recorded)
+	}
+	p.maxOffsets = maps.Clone(cp.maxOffsets)
+	p.lastPreimage = cp.lastPreimage
+	p.lastPreimageKey = cp.lastPreimageKey
+	p.lastPreimageOffset = cp.lastPreimageOffset
 }
 
 func (p *TrackingPreimageOracleReader) Reset() {
 	p.lastPreimageOffset = ^Word(0)
 }
 
+// Unwrap returns the oracle wrapped by the reader, as passed to NewTrackingPreimageOracleReader.
+func (p *TrackingPreimageOracleReader) Unwrap() mipsevm.PreimageOracle {
+	return p.po
+}
+
 func (p *TrackingPreimageOracleReader) Hint(v []byte) {
 	p.po.Hint(v)
 }
 
 func (p *TrackingPreimageOracleReader) GetPreimage(k [32]byte) []byte {
 	p.numPreimageRequests++
+	p.distinctKeys[k] = struct{}{}
 	preimage := p.po.GetPreimage(k)
+	if p.interceptor != nil {
+		preimage = p.interceptor(k, preimage)
+	}
 	p.totalPreimageSize += len(preimage)
+	if p.recorded != nil {
+		p.recorded[k] = preimage
+	}
 	return preimage
 }
 
 func (p *TrackingPreimageOracleReader) ReadPreimage(key [32]byte, offset Word) (dat [32]byte, datLen Word) {
-	preimage := p.lastPreimage
+	preimage := p.loadPreimage(key)
+	p.lastPreimageOffset = offset
+	if offset >= Word(len(preimage)) {
+		panic("Preimage offset out-of-bounds")
+	}
+	datLen = Word(copy(dat[:], preimage[offset:]))
+	return
+}
+
+// RecordReadOffset notes that a read of the preimage for key advanced the preimage offset to offset.
+func (p *TrackingPreimageOracleReader) RecordReadOffset(key [32]byte, offset Word) {
+	if p.maxOffsets == nil {
+		p.maxOffsets = make(map[[32]byte]Word)
+	}
+	if offset > p.maxOffsets[key] {
+		p.maxOffsets[key] = offset
+	}
+}
+
+// MaxOffsetForKey returns the furthest offset into the length-prefixed preimage for key that reads have reached,
+// or 0 if it was never read. A guest that streamed the whole preimage reaches its length plus 8.
+func (p *TrackingPreimageOracleReader) MaxOffsetForKey(key [32]byte) Word {
+	return p.maxOffsets[key]
+}
+
+// CheckOffset returns ErrPreimageOffsetOutOfBounds if ReadPreimage would fail for the given key and offset.
+func (p *TrackingPreimageOracleReader) CheckOffset(key [32]byte, offset Word) error {
+	preimage := p.loadPreimage(key)
+	if offset >= Word(len(preimage)) {
+		return fmt.Errorf("%w: offset %d, length-prefixed preimage size %d", ErrPreimageOffsetOutOfBounds, offset, len(preimage))
+	}
+	return nil
+}
+
+// CheckKeyType returns ErrInvalidPreimageKeyType if the type of key is not allowed by SetAllowedKeyTypes.
+func (p *TrackingPreimageOracleReader) CheckKeyType(key [32]byte) error {
+	if keyType := preimage.KeyType(key[0]); len(p.allowedKeyTypes) > 0 && !slices.Contains(p.allowedKeyTypes, keyType) {
+		return fmt.Errorf("%w: key %x has type %d", ErrInvalidPreimageKeyType, key, keyType)
+	}
+	return nil
+}
+
+// CheckLimits returns ErrPreimageBudgetExceeded if the preimages served exceed the budget set with
+// SetMaxPreimageBytes, and ErrTooManyPreimages if they exceed the limit set with SetMaxDistinctPreimages.
+func (p *TrackingPreimageOracleReader) CheckLimits() error {
+	if p.maxPreimageBytes > 0 && p.totalPreimageSize > p.maxPreimageBytes {
+		return fmt.Errorf("%w: read %d bytes, budget %d", ErrPreimageBudgetExceeded, p.totalPreimageSize, p.maxPreimageBytes)
+	}
+	if p.maxDistinctPreimages > 0 && len(p.distinctKeys) > p.maxDistinctPreimages {
+		return fmt.Errorf("%w: read %d, limit %d", ErrTooManyPreimages, len(p.distinctKeys), p.maxDistinctPreimages)
+	}
+	return nil
+}
+
+// loadPreimage returns the length-prefixed preimage for key, fetching it from the oracle if it is not cached.
+func (p *TrackingPreimageOracleReader) loadPreimage(key [32]byte) []byte {
 	if key != p.lastPreimageKey {
 		p.lastPreimageKey = key
 		data := p.GetPreimage(key)
 		// add the length prefix
-		preimage = make([]byte, 0, 8+len(data))
+		preimage := make([]byte, 0, 8+len(data))
 		preimage = binary.BigEndian.AppendUint64(preimage, uint64(len(data)))
 		preimage = append(preimage, data...)
 		p.lastPreimage = preimage
 	}
-	p.lastPreimageOffset = offset
-	if offset >= Word(len(preimage)) {
-		panic("Preimage offset out-of-bounds")
-	}
-	datLen = Word(copy(dat[:], preimage[offset:]))
-	return
+	return p.lastPreimage
 }
 
 func (p *TrackingPreimageOracleReader) LastPreimage() ([32]byte, []byte, Word) {
@@ -75,3 +262,14 @@ func (p *TrackingPreimageOracleReader) TotalPreimageSize() int {
 func (p *TrackingPreimageOracleReader) NumPreimageRequests() int {
 	return p.numPreimageRequests
 }
+
+// NumDistinctPreimages returns the number of distinct preimage keys requested from the oracle.
+func (p *TrackingPreimageOracleReader) NumDistinctPreimages() int {
+	return len(p.distinctKeys)
+}
+
+// RequestCountSince returns the number of preimage requests made since NumPreimageRequests returned baseline.
+// Bracketing a segment with it asserts that the segment does not depend on the oracle.
+func (p *TrackingPreimageOracleReader) RequestCountSince(baseline int) int {
+	return p.numPreimageRequests - baseline
+}
@@ -0,0 +1,87 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+)
+
+// ErrPreimagesNotRecorded is returned by ExportBundle when preimage recording was not enabled.
+var ErrPreimagesNotRecorded = errors.New("preimages are not recorded")
+
+// ExportBundle writes the preimages served since SetRecordPreimages was enabled, to be served again by the oracle
+// returned by LoadPreimageBundle. Each preimage is encoded as its key, its big-endian uint64 length and its data,
+// in ascending key order.
+func (p *TrackingPreimageOracleReader) ExportBundle(w io.Writer) error {
+	if p.recorded == nil {
+		return ErrPreimagesNotRecorded
+	}
+	keys := make([][32]byte, 0, len(p.recorded))
+	for k := range p.recorded {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b [32]byte) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	for _, k := range keys {
+		data := p.recorded[k]
+		if _, err := w.Write(k[:]); err != nil {
+			return fmt.Errorf("failed to write preimage key: %w", err)
+		}
+		if err := binary.Write(w, binary.BigEndian, uint64(len(data))); err != nil {
+			return fmt.Errorf("failed to write preimage length: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write preimage data: %w", err)
+		}
+	}
+	return nil
+}
+
+type bundleOracle map[[32]byte][]byte
+
+var _ mipsevm.PreimageOracle = bundleOracle(nil)
+
+func (o bundleOracle) Hint(v []byte) {}
+
+func (o bundleOracle) GetPreimage(k [32]byte) []byte {
+	data, ok := o[k]
+	if !ok {
+		panic(fmt.Errorf("preimage %x is not in the bundle", k))
+	}
+	return data
+}
+
+// LoadPreimageBundle reads a bundle written by ExportBundle, and returns an oracle serving its preimages.
+// Hints are ignored, and requesting a preimage that is not in the bundle panics.
+func LoadPreimageBundle(r io.Reader) (mipsevm.PreimageOracle, error) {
+	oracle := make(bundleOracle)
+	for {
+		var key [32]byte
+		if _, err := io.ReadFull(r, key[:]); errors.Is(err, io.EOF) {
+			return oracle, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read preimage key: %w", err)
+		}
+		var length uint64
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("failed to read length of preimage %x: %w", key, err)
+		}
+		if _, ok := oracle[key]; ok {
+			return nil, fmt.Errorf("duplicate preimage %x", key)
+		}
+		var data bytes.Buffer
+		if n, err := io.CopyN(&data, r, int64(length)); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("failed to read preimage %x, got %d of %d bytes: %w", key, n, length, err)
+		}
+		oracle[key] = data.Bytes()
+	}
+}
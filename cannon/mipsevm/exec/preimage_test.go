@@ -0,0 +1,146 @@
+package exec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
+)
+
+type mapOracle map[[32]byte][]byte
+
+var _ mipsevm.PreimageOracle = mapOracle(nil)
+
+func (o mapOracle) Hint(v []byte) {}
+
+func (o mapOracle) GetPreimage(k [32]byte) []byte {
+	return o[k]
+}
+
+func TestTrackingPreimageOracleReader_Unwrap(t *testing.T) {
+	oracle := mapOracle{{0x01}: []byte("hello")}
+	reader := NewTrackingPreimageOracleReader(oracle)
+	require.Equal(t, mipsevm.PreimageOracle(oracle), reader.Unwrap())
+
+	// The unwrapped oracle can be re-wrapped without carrying over the tracked statistics
+	reader.GetPreimage([32]byte{0x01})
+	rewrapped := NewTrackingPreimageOracleReader(reader.Unwrap())
+	require.Equal(t, 5, reader.TotalPreimageSize())
+	require.Zero(t, rewrapped.TotalPreimageSize())
+	require.Equal(t, []byte("hello"), rewrapped.GetPreimage([32]byte{0x01}))
+}
+
+func TestTrackingPreimageOracleReader_ExportBundle(t *testing.T) {
+	oracle := mapOracle{
+		{0x01}: []byte("hello"),
+		{0x02}: []byte("world"),
+		{0x03}: {},
+		{0x04}: []byte("unused"),
+	}
+	reader := NewTrackingPreimageOracleReader(oracle)
+	var buf bytes.Buffer
+	require.ErrorIs(t, reader.ExportBundle(&buf), ErrPreimagesNotRecorded)
+
+	reader.SetRecordPreimages(true)
+	for _, k := range [][32]byte{{0x02}, {0x01}, {0x03}, {0x02}} {
+		reader.GetPreimage(k)
+	}
+	require.NoError(t, reader.ExportBundle(&buf))
+	exported := bytes.Clone(buf.Bytes())
+
+	bundle, err := LoadPreimageBundle(&buf)
+	require.NoError(t, err)
+	for _, k := range [][32]byte{{0x01}, {0x02}, {0x03}} {
+		require.Equal(t, oracle[k], bundle.GetPreimage(k))
+	}
+	require.Panics(t, func() { bundle.GetPreimage([32]byte{0x04}) })
+
+	// The bundle is deterministic, and can be exported again after replaying from it
+	replay := NewTrackingPreimageOracleReader(bundle)
+	replay.SetRecordPreimages(true)
+	for _, k := range [][32]byte{{0x03}, {0x01}, {0x02}} {
+		replay.GetPreimage(k)
+	}
+	var reexported bytes.Buffer
+	require.NoError(t, replay.ExportBundle(&reexported))
+	require.Equal(t, exported, reexported.Bytes())
+
+	// The first preimage is "hello", truncate its data
+	_, err = LoadPreimageBundle(bytes.NewReader(exported[:42]))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = LoadPreimageBundle(bytes.NewReader(exported[:len(exported)-1]))
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = LoadPreimageBundle(bytes.NewReader(append(bytes.Clone(exported), exported[:45]...)))
+	require.ErrorContains(t, err, "duplicate preimage")
+}
+
+func TestTrackingPreimageOracleReader_Checkpoint(t *testing.T) {
+	oracle := mapOracle{
+		{0x01}: []byte("hello"),
+		{0x02}: []byte("world!"),
+	}
+	reader := NewTrackingPreimageOracleReader(oracle)
+	reader.SetRecordPreimages(true)
+	reader.ReadPreimage([32]byte{0x01}, 8)
+	reader.RecordReadOffset([32]byte{0x01}, 13)
+	cp := reader.Checkpoint()
+
+	reader.ReadPreimage([32]byte{0x02}, 0)
+	reader.RecordReadOffset([32]byte{0x02}, 14)
+	require.Equal(t, 11, reader.TotalPreimageSize())
+
+	for i := 0; i < 2; i++ {
+		reader.Restore(cp)
+		require.Equal(t, 5, reader.TotalPreimageSize())
+		require.Equal(t, 1, reader.NumPreimageRequests())
+		require.Equal(t, 1, reader.NumDistinctPreimages())
+		require.Equal(t, Word(13), reader.MaxOffsetForKey([32]byte{0x01}))
+		require.Zero(t, reader.MaxOffsetForKey([32]byte{0x02}))
+		key, preimage, offset := reader.LastPreimage()
+		require.Equal(t, [32]byte{0x01}, key)
+		require.Equal(t, append([]byte{0, 0, 0, 0, 0, 0, 0, 5}, "hello"...), preimage)
+		require.Equal(t, Word(8), offset)
+
+		// The cached preimage is served without another request
+		reader.ReadPreimage([32]byte{0x01}, 9)
+		require.Equal(t, 1, reader.NumPreimageRequests())
+		reader.ReadPreimage([32]byte{0x02}, 0)
+		require.Equal(t, 2, reader.NumPreimageRequests())
+	}
+
+	// Only the preimages recorded before the checkpoint are kept
+	reader.Restore(cp)
+	var buf bytes.Buffer
+	require.NoError(t, reader.ExportBundle(&buf))
+	bundle, err := LoadPreimageBundle(&buf)
+	require.NoError(t, err)
+	require.Equal(t, oracle[[32]byte{0x01}], bundle.GetPreimage([32]byte{0x01}))
+	require.Panics(t, func() { bundle.GetPreimage([32]byte{0x02}) })
+}
+
+func TestTrackingPreimageOracleReader_CopyConfig(t *testing.T) {
+	oracle := mapOracle{{0x02}: []byte("hello")}
+	reader := NewTrackingPreimageOracleReader(oracle)
+	reader.SetMaxPreimageBytes(4)
+	reader.SetMaxDistinctPreimages(1)
+	reader.SetAllowedKeyTypes(preimage.Keccak256KeyType)
+	reader.SetPreimageInterceptor(func(key [32]byte, data []byte) []byte {
+		return append(data, '!')
+	})
+	reader.GetPreimage([32]byte{0x02})
+
+	other := NewTrackingPreimageOracleReader(oracle)
+	other.CopyConfig(reader)
+	// The accounting is not copied
+	require.Zero(t, other.NumPreimageRequests())
+	require.NoError(t, other.CheckLimits())
+
+	require.Equal(t, []byte("hello!"), other.GetPreimage([32]byte{0x02}))
+	require.ErrorIs(t, other.CheckLimits(), ErrPreimageBudgetExceeded)
+	require.NoError(t, other.CheckKeyType([32]byte{0x02}))
+	require.ErrorIs(t, other.CheckKeyType([32]byte{0x01}), ErrInvalidPreimageKeyType)
+}
@@ -1,6 +1,7 @@
 package mipsevm
 
 import (
+	"encoding"
 	"io"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -61,6 +62,18 @@ type FPVMState interface {
 	CreateVM(logger log.Logger, po PreimageOracle, stdOut, stdErr io.Writer, meta Metadata) FPVM
 }
 
+// Checkpointable is a VM state that can be saved and restored by code that is generic over VM variants.
+type Checkpointable interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+
+	// StateHash returns the hash of the state witness
+	StateHash() common.Hash
+
+	// GetStep returns the current VM step
+	GetStep() uint64
+}
+
 type SymbolMatcher func(addr arch.Word) bool
 
 type Metadata interface {
@@ -9,6 +9,7 @@ import (
 	"slices"
 	"sort"
 
+	"github.com/cespare/xxhash/v2"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum/go-ethereum/crypto"
 	"golang.org/x/exp/maps"
@@ -58,6 +59,17 @@ type Memory struct {
 	// this prevents map lookups each instruction
 	lastPageKeys [2]Word
 	lastPage     [2]*CachedPage
+
+	// incremented by every Snapshot and Restore. Pages of an earlier generation may be shared with a snapshot,
+	// and are copied before they are modified.
+	generation uint64
+}
+
+// MemorySnapshot is a copy-on-write snapshot of a Memory, see Memory.Snapshot.
+type MemorySnapshot struct {
+	nodes      map[uint64]*[32]byte
+	pages      map[Word]*CachedPage
+	generation uint64
 }
 
 func NewMemory() *Memory {
@@ -138,6 +150,9 @@ func (m *Memory) MerkleizeSubtree(gindex uint64) [32]byte {
 	return r
 }
 
+// MerkleProof returns the proof for the word at addr in the format the MIPS contracts consume: the 32-byte leaf that
+// contains the word, followed by the sibling hashes on the path from the leaf to the root. Nodes are merkleized as
+// needed, as in MerkleRoot. See VerifyMerkleProof.
 func (m *Memory) MerkleProof(addr Word) (out [MemProofSize]byte) {
 	proof := m.traverseBranch(1, addr, 0)
 	// encode the proof
@@ -147,6 +162,22 @@ func (m *Memory) MerkleProof(addr Word) (out [MemProofSize]byte) {
 	return out
 }
 
+// VerifyMerkleProof reports whether proof, as returned by MerkleProof for addr, proves its leaf against root.
+func VerifyMerkleProof(root [32]byte, addr Word, proof [MemProofSize]byte) bool {
+	node := *(*[32]byte)(proof[:32])
+	path := addr >> 5
+	for i := 1; i < MemProofLeafCount; i++ {
+		sibling := *(*[32]byte)(proof[i*32 : (i+1)*32])
+		if path&1 != 0 {
+			node = HashPair(sibling, node)
+		} else {
+			node = HashPair(node, sibling)
+		}
+		path >>= 1
+	}
+	return node == root
+}
+
 func (m *Memory) traverseBranch(parent uint64, addr Word, depth uint8) (proof [][32]byte) {
 	if depth == WordSize-5 {
 		proof = make([][32]byte, 0, WordSize-5+1)
@@ -167,10 +198,30 @@ func (m *Memory) traverseBranch(parent uint64, addr Word, depth uint8) (proof []
 	return
 }
 
+// MerkleRoot returns the root of the memory merkle tree.
+// The tree is walked by generalized index, not by iterating over the pages map, so the root only depends on the
+// memory contents, and not on the order in which pages were allocated.
 func (m *Memory) MerkleRoot() [32]byte {
 	return m.MerkleizeSubtree(1)
 }
 
+// QuickHash returns a non-cryptographic checksum of the allocated pages and their contents.
+// It is much cheaper than MerkleRoot and is intended for detecting memory changes when debugging or caching.
+// Unlike MerkleRoot, an allocated page of zeroes changes the checksum.
+func (m *Memory) QuickHash() uint64 {
+	indexes := maps.Keys(m.pages)
+	// iterate sorted map keys for a consistent checksum
+	slices.Sort(indexes)
+	h := xxhash.New()
+	var indexBytes [8]byte
+	for _, pageIndex := range indexes {
+		binary.BigEndian.PutUint64(indexBytes[:], uint64(pageIndex))
+		_, _ = h.Write(indexBytes[:])
+		_, _ = h.Write(m.pages[pageIndex].Data[:])
+	}
+	return h.Sum64()
+}
+
 func (m *Memory) pageLookup(pageIndex Word) (*CachedPage, bool) {
 	// hit caches
 	if pageIndex == m.lastPageKeys[0] {
@@ -192,6 +243,46 @@ func (m *Memory) pageLookup(pageIndex Word) (*CachedPage, bool) {
 	return p, ok
 }
 
+// writablePageLookup is like pageLookup, but first copies a page that may be shared with a snapshot.
+func (m *Memory) writablePageLookup(pageIndex Word) (*CachedPage, bool) {
+	p, ok := m.pageLookup(pageIndex)
+	if !ok || p.generation == m.generation {
+		return p, ok
+	}
+	data := *p.Data
+	cp := &CachedPage{Data: &data, Cache: p.Cache, Ok: p.Ok, generation: m.generation}
+	m.pages[pageIndex] = cp
+	for i, key := range m.lastPageKeys {
+		if key == pageIndex {
+			m.lastPage[i] = cp
+		}
+	}
+	return cp, true
+}
+
+// Snapshot returns a snapshot of the memory, to Restore it to later. Pages are shared with the snapshot, and only
+// copied when they are first modified after the snapshot, so taking a snapshot does not copy the memory contents.
+func (m *Memory) Snapshot() *MemorySnapshot {
+	s := &MemorySnapshot{
+		nodes:      maps.Clone(m.nodes),
+		pages:      maps.Clone(m.pages),
+		generation: m.generation,
+	}
+	m.generation++
+	return s
+}
+
+// Restore resets the memory to the contents of s, so that its merkle root equals the root at the time of the
+// snapshot. Pages remain shared with s, which may be restored again.
+func (m *Memory) Restore(s *MemorySnapshot) {
+	m.nodes = maps.Clone(s.nodes)
+	m.pages = maps.Clone(s.pages)
+	m.lastPageKeys = [2]Word{^Word(0), ^Word(0)}
+	m.lastPage = [2]*CachedPage{nil, nil}
+	// s may have been taken from another memory, with later generations
+	m.generation = max(m.generation, s.generation) + 1
+}
+
 // SetWord stores [arch.Word] sized values at the specified address
 func (m *Memory) SetWord(addr Word, v Word) {
 	// addr must be aligned to WordSizeBytes bytes
@@ -201,7 +292,7 @@ func (m *Memory) SetWord(addr Word, v Word) {
 
 	pageIndex := addr >> PageAddrSize
 	pageAddr := addr & PageAddrMask
-	p, ok := m.pageLookup(pageIndex)
+	p, ok := m.writablePageLookup(pageIndex)
 	if !ok {
 		// allocate the page if we have not already.
 		// Go may mmap relatively large ranges, but we only allocate the pages just in time.
@@ -228,7 +319,7 @@ func (m *Memory) GetWord(addr Word) Word {
 }
 
 func (m *Memory) AllocPage(pageIndex Word) *CachedPage {
-	p := &CachedPage{Data: new(Page)}
+	p := &CachedPage{Data: new(Page), generation: m.generation}
 	m.pages[pageIndex] = p
 	// make nodes to root
 	k := (1 << PageKeySize) | uint64(pageIndex)
@@ -239,6 +330,72 @@ func (m *Memory) AllocPage(pageIndex Word) *CachedPage {
 	return p
 }
 
+// Reserve allocates zeroed pages for every page overlapping the range [addr, addr+size), so subsequent writes
+// to the range don't need to allocate pages. Existing pages are left untouched.
+// The merkle root is unchanged, as zeroed pages hash the same as unallocated memory.
+func (m *Memory) Reserve(addr Word, size Word) {
+	if size == 0 {
+		return
+	}
+	first := addr >> PageAddrSize
+	last := (addr + size - 1) >> PageAddrSize
+	if last < first { // the range wraps around the address space
+		last = ^Word(0) >> PageAddrSize
+	}
+	for pageIndex := first; ; pageIndex++ {
+		if _, ok := m.pages[pageIndex]; !ok {
+			m.AllocPage(pageIndex)
+		}
+		if pageIndex == last {
+			break
+		}
+	}
+}
+
+// Zero zeroes the block [addr, addr+size), whose size is a power of two of at least a page, and that is aligned to
+// its size. Such a block is a subtree of the merkle tree, so its pages and nodes are dropped, as if they were never
+// allocated.
+func (m *Memory) Zero(addr Word, size Word) {
+	if size < PageSize || size&(size-1) != 0 || addr&(size-1) != 0 {
+		panic(fmt.Errorf("invalid block to zero: %x of size %x", addr, size))
+	}
+	first := addr >> PageAddrSize
+	count := size >> PageAddrSize
+	var pageIndexes []Word
+	if uint64(count) <= uint64(len(m.pages)) {
+		for pageIndex := first; pageIndex-first < count; pageIndex++ {
+			if _, ok := m.pages[pageIndex]; ok {
+				pageIndexes = append(pageIndexes, pageIndex)
+			}
+		}
+	} else {
+		for pageIndex := range m.pages {
+			if pageIndex-first < count {
+				pageIndexes = append(pageIndexes, pageIndex)
+			}
+		}
+	}
+	if len(pageIndexes) == 0 {
+		return
+	}
+
+	// drop the nodes from each page up to the root of the block, and invalidate the nodes above
+	depth := bits.TrailingZeros64(uint64(count))
+	for _, pageIndex := range pageIndexes {
+		delete(m.pages, pageIndex)
+		gindex := (uint64(1) << PageKeySize) | uint64(pageIndex)
+		for i := 0; i <= depth; i++ {
+			delete(m.nodes, gindex)
+			gindex >>= 1
+		}
+	}
+	for gindex := ((uint64(1) << PageKeySize) | uint64(first)) >> (depth + 1); gindex > 0; gindex >>= 1 {
+		m.nodes[gindex] = nil
+	}
+	m.lastPageKeys = [2]Word{^Word(0), ^Word(0)}
+	m.lastPage = [2]*CachedPage{nil, nil}
+}
+
 type pageEntry struct {
 	Index Word  `json:"index"`
 	Data  *Page `json:"data"`
@@ -276,28 +433,26 @@ func (m *Memory) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// SetMemoryRange writes the contents of r to memory starting at addr, allocating pages as needed.
+// r is read until EOF before any memory is written, so if reading fails the error is returned and memory is unchanged.
 func (m *Memory) SetMemoryRange(addr Word, r io.Reader) error {
-	for {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for len(data) > 0 {
 		pageIndex := addr >> PageAddrSize
 		pageAddr := addr & PageAddrMask
-		readLen := PageSize - pageAddr
-		chunk := make([]byte, readLen)
-		n, err := r.Read(chunk)
-		if err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return err
-		}
-
-		p, ok := m.pageLookup(pageIndex)
+		p, ok := m.writablePageLookup(pageIndex)
 		if !ok {
 			p = m.AllocPage(pageIndex)
 		}
 		p.InvalidateFull()
-		copy(p.Data[pageAddr:], chunk[:n])
+		n := copy(p.Data[pageAddr:], data)
+		data = data[n:]
 		addr += Word(n)
 	}
+	return nil
 }
 
 // Serialize writes the memory in a simple binary format which can be read again using Deserialize
@@ -346,6 +501,43 @@ func (m *Memory) Deserialize(in io.Reader) error {
 	return nil
 }
 
+// LoadMemoryFromPages builds a memory from the format written by Serialize, reading one page at a time.
+// Unlike Deserialize, it rejects duplicate pages, and the merkle root is computed once all pages are loaded.
+func LoadMemoryFromPages(r io.Reader) (*Memory, error) {
+	var pageCount Word
+	if err := binary.Read(r, binary.BigEndian, &pageCount); err != nil {
+		return nil, fmt.Errorf("failed to read page count: %w", err)
+	}
+	m := NewMemory()
+	for i := Word(0); i < pageCount; i++ {
+		var pageIndex Word
+		if err := binary.Read(r, binary.BigEndian, &pageIndex); err != nil {
+			return nil, fmt.Errorf("failed to read index of page %d: %w", i, err)
+		}
+		if pageIndex > PageKeyMask {
+			return nil, fmt.Errorf("invalid page index %d", pageIndex)
+		}
+		if _, ok := m.pages[pageIndex]; ok {
+			return nil, fmt.Errorf("duplicate page index %d", pageIndex)
+		}
+		p := &CachedPage{Data: new(Page)}
+		if _, err := io.ReadFull(r, p.Data[:]); err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", pageIndex, err)
+		}
+		m.pages[pageIndex] = p
+		// Mark the nodes to the root as present but not yet computed. Nothing is computed until all pages are
+		// loaded, so the walk stops at the first node another page already marked.
+		for k := (1 << PageKeySize) | uint64(pageIndex); k > 0; k >>= 1 {
+			if _, ok := m.nodes[k]; ok {
+				break
+			}
+			m.nodes[k] = nil
+		}
+	}
+	m.MerkleRoot()
+	return m, nil
+}
+
 func (m *Memory) Copy() *Memory {
 	out := NewMemory()
 	out.nodes = make(map[uint64]*[32]byte)
@@ -8,9 +8,11 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/stretchr/testify/require"
@@ -51,6 +53,32 @@ func TestMemory64MerkleProof(t *testing.T) {
 	})
 }
 
+func TestMemory64VerifyMerkleProof(t *testing.T) {
+	t.Run("any address", func(t *testing.T) {
+		m := NewMemory()
+		m.SetWord(0x10000, 0xaabbccdd)
+		m.SetWord(0x80008, 42)
+		m.SetWord(0x13370000, 123)
+		m.SetWord(^Word(0)&^(arch.WordSizeBytes-1), 7)
+		root := m.MerkleRoot()
+		for _, addr := range []Word{0, 0x10000, 0x10003, 0x80008, 0x80ff8, 0x13370000, 0x5000_0000, ^Word(0)} {
+			proof := m.MerkleProof(addr)
+			require.Truef(t, VerifyMerkleProof(root, addr, proof), "proof for 0x%x must verify", addr)
+			// The proof commits to the leaf containing addr
+			proof[addr&31] ^= 1
+			require.Falsef(t, VerifyMerkleProof(root, addr, proof), "tampered proof for 0x%x must not verify", addr)
+		}
+	})
+	t.Run("other leaf", func(t *testing.T) {
+		m := NewMemory()
+		m.SetWord(0x10000, 0xaabbccdd)
+		root := m.MerkleRoot()
+		proof := m.MerkleProof(0x10000)
+		require.True(t, VerifyMerkleProof(root, 0x1001f, proof), "proof covers the whole leaf")
+		require.False(t, VerifyMerkleProof(root, 0x10020, proof), "proof must not verify for the next leaf")
+	})
+}
+
 func TestMemory64MerkleRoot(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		m := NewMemory()
@@ -185,6 +213,19 @@ func TestMemory64ReadWrite(t *testing.T) {
 		}
 	})
 
+	t.Run("reader error", func(t *testing.T) {
+		m := NewMemory()
+		m.SetWord(0x1000, 0x1234)
+		pre := m.MerkleRoot()
+		readErr := errors.New("read failed")
+		// The reader fails after several pages were read
+		r := io.MultiReader(bytes.NewReader(bytes.Repeat([]byte{0xAA}, 3*PageSize)), iotest.ErrReader(readErr))
+		require.ErrorIs(t, m.SetMemoryRange(0x800, r), readErr)
+		require.Equal(t, 1, m.PageCount(), "no pages allocated")
+		require.Equal(t, Word(0x1234), m.GetWord(0x1000))
+		require.Equal(t, pre, m.MerkleRoot())
+	})
+
 	t.Run("read-write", func(t *testing.T) {
 		m := NewMemory()
 		m.SetWord(16, 0xAABBCCDD_EEFF1122)
@@ -254,3 +295,168 @@ func TestMemory64Copy(t *testing.T) {
 	require.Equal(t, Word(0xAABB), mcpy.GetWord(0xAABBCCDD_8000))
 	require.Equal(t, m.MerkleRoot(), mcpy.MerkleRoot())
 }
+
+func TestMemory64QuickHash(t *testing.T) {
+	m := NewMemory()
+	m.SetWord(0xAABBCCDD_8000, 0x000000_AABB)
+	mcpy := m.Copy()
+	require.Equal(t, m.QuickHash(), mcpy.QuickHash())
+
+	mcpy.SetWord(0xAABBCCDD_8000, 0)
+	require.NotEqual(t, m.QuickHash(), mcpy.QuickHash())
+
+	mcpy.SetWord(0xAABBCCDD_8000, 0x000000_AABB)
+	require.Equal(t, m.QuickHash(), mcpy.QuickHash())
+
+	mcpy.AllocPage(0x42)
+	require.NotEqual(t, m.QuickHash(), mcpy.QuickHash())
+}
+
+func TestMemory64Reserve(t *testing.T) {
+	m := NewMemory()
+	m.SetWord(0xAABBCCDD_10_000, 0xAABB)
+	root := m.MerkleRoot()
+
+	m.Reserve(0xAABBCCDD_10_000-PageSize, 3*PageSize)
+	require.Equal(t, 3, m.PageCount())
+	require.Equal(t, root, m.MerkleRoot(), "reserved pages must not change the merkle root")
+	require.Equal(t, Word(0xAABB), m.GetWord(0xAABBCCDD_10_000), "existing pages must be untouched")
+
+	m.Reserve(0xAABBCCDD_10_000, 0)
+	require.Equal(t, 3, m.PageCount())
+
+	// unaligned range spanning a page boundary
+	m.Reserve(0xAABBCCDD_10_000+10*PageSize-1, 2)
+	require.Equal(t, 5, m.PageCount())
+	require.Equal(t, root, m.MerkleRoot())
+}
+
+func TestMemory64Zero(t *testing.T) {
+	m := NewMemory()
+	m.SetWord(0xAABBCCDD_10_000-PageSize, 0xAABB)
+	m.SetWord(0xAABBCCDD_10_000+4*PageSize, 0xCCDD)
+	expected := m.MerkleRoot()
+	m.SetWord(0xAABBCCDD_10_000, 0x1234)
+	m.SetWord(0xAABBCCDD_10_000+3*PageSize+8, 0x5678)
+	m.Reserve(0xAABBCCDD_10_000+PageSize, PageSize)
+	require.Equal(t, 5, m.PageCount())
+	require.NotEqual(t, expected, m.MerkleRoot())
+
+	m.Zero(0xAABBCCDD_10_000, 4*PageSize)
+	require.Equal(t, 2, m.PageCount())
+	require.Equal(t, expected, m.MerkleRoot(), "zeroed pages must hash as if never allocated")
+	require.Equal(t, Word(0), m.GetWord(0xAABBCCDD_10_000))
+	require.Equal(t, Word(0xCCDD), m.GetWord(0xAABBCCDD_10_000+4*PageSize), "pages outside the block must be kept")
+
+	// Zeroed pages are allocated again when written
+	m.SetWord(0xAABBCCDD_10_000+2*PageSize, 0x9999)
+	fresh := NewMemory()
+	fresh.SetWord(0xAABBCCDD_10_000-PageSize, 0xAABB)
+	fresh.SetWord(0xAABBCCDD_10_000+4*PageSize, 0xCCDD)
+	fresh.SetWord(0xAABBCCDD_10_000+2*PageSize, 0x9999)
+	require.Equal(t, fresh.MerkleRoot(), m.MerkleRoot())
+
+	// A block larger than the allocated memory
+	m.Zero(0, 1<<(WordSize-1))
+	require.Equal(t, 0, m.PageCount())
+	require.Equal(t, NewMemory().MerkleRoot(), m.MerkleRoot())
+
+	require.Panics(t, func() { m.Zero(0xAABBCCDD_10_000, 3*PageSize) }, "size must be a power of two")
+	require.Panics(t, func() { m.Zero(0xAABBCCDD_10_000+PageSize, 2*PageSize) }, "block must be aligned")
+	require.Panics(t, func() { m.Zero(0xAABBCCDD_10_000, 8) }, "block must cover pages")
+}
+
+func TestMemory64Snapshot(t *testing.T) {
+	m := NewMemory()
+	m.SetWord(0x10_000, 0xAABB)
+	m.SetWord(0x20_000, 0xCCDD)
+	m.SetWord(0x30_000, 0xEEFF)
+	root := m.MerkleRoot()
+	s := m.Snapshot()
+
+	// Modify an existing page, a new page and a range
+	m.SetWord(0x10_008, 0x1234)
+	m.SetWord(0x40_000, 0x5678)
+	require.NoError(t, m.SetMemoryRange(0x20_000-8, bytes.NewReader([]byte("0123456789abcdef"))))
+	modifiedRoot := m.MerkleRoot()
+	require.NotEqual(t, root, modifiedRoot)
+	// Only the modified pages are copied
+	require.Same(t, s.pages[0x30_000>>PageAddrSize], m.pages[0x30_000>>PageAddrSize])
+	require.NotSame(t, s.pages[0x10_000>>PageAddrSize], m.pages[0x10_000>>PageAddrSize])
+	require.Equal(t, make([]byte, 8), s.pages[0x10_000>>PageAddrSize].Data[8:16])
+	s2 := m.Snapshot()
+
+	for i := 0; i < 2; i++ {
+		m.Restore(s)
+		require.Equal(t, root, m.MerkleRoot())
+		require.Equal(t, Word(0xAABB), m.GetWord(0x10_000))
+		require.Equal(t, Word(0), m.GetWord(0x10_008))
+		require.Equal(t, Word(0xCCDD), m.GetWord(0x20_000))
+		require.Equal(t, 3, m.PageCount())
+		// Writes after the restore do not modify the snapshot
+		m.SetWord(0x10_000, 0x9999)
+		m.SetWord(0x30_000, 0x9999)
+	}
+
+	m.Restore(s2)
+	require.Equal(t, modifiedRoot, m.MerkleRoot())
+	require.Equal(t, Word(0x1234), m.GetWord(0x10_008))
+
+	// A snapshot can be restored into another memory
+	other := NewMemory()
+	other.SetWord(0x50_000, 1)
+	other.Restore(s)
+	other.SetWord(0x20_000, 0)
+	m.Restore(s)
+	require.Equal(t, root, m.MerkleRoot())
+	expected := NewMemory()
+	expected.SetWord(0x10_000, 0xAABB)
+	expected.SetWord(0x30_000, 0xEEFF)
+	require.Equal(t, expected.MerkleRoot(), other.MerkleRoot())
+}
+
+func TestMemory64LoadFromPages(t *testing.T) {
+	m := NewMemory()
+	for i := Word(0); i < 16; i++ {
+		// Spread pages across the address space, with some sharing subtrees
+		m.SetWord((i*0x1357<<PageAddrSize)|(i*8), 0x1000+i)
+		m.SetWord((i<<PageAddrSize)+PageSize-8, 0x2000+i)
+	}
+	var buf bytes.Buffer
+	require.NoError(t, m.Serialize(&buf))
+
+	loaded, err := LoadMemoryFromPages(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, m.PageCount(), loaded.PageCount())
+	require.Equal(t, m.MerkleRoot(), loaded.MerkleRoot())
+	require.Equal(t, m.MerkleProof(0x2000-8), loaded.MerkleProof(0x2000-8))
+
+	// Writes after loading must still update the root
+	m.SetWord(0x8, 0xdead)
+	loaded.SetWord(0x8, 0xdead)
+	require.Equal(t, m.MerkleRoot(), loaded.MerkleRoot())
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, NewMemory().Serialize(&buf))
+		loaded, err := LoadMemoryFromPages(&buf)
+		require.NoError(t, err)
+		require.Equal(t, NewMemory().MerkleRoot(), loaded.MerkleRoot())
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		_, err := LoadMemoryFromPages(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+
+	t.Run("duplicate page", func(t *testing.T) {
+		var dup bytes.Buffer
+		require.NoError(t, binary.Write(&dup, binary.BigEndian, Word(2)))
+		for i := 0; i < 2; i++ {
+			require.NoError(t, binary.Write(&dup, binary.BigEndian, Word(1)))
+			dup.Write(make([]byte, PageSize))
+		}
+		_, err := LoadMemoryFromPages(&dup)
+		require.ErrorContains(t, err, "duplicate page index 1")
+	})
+}
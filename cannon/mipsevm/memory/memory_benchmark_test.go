@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"bytes"
+	"testing"
+)
+
+func BenchmarkMemorySetMemoryRange(b *testing.B) {
+	const size = 16 << 20 // 16 MiB
+	data := make([]byte, size)
+	addr := Word(0x10_000_000)
+
+	b.Run("without reserve", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := NewMemory()
+			if err := m.SetMemoryRange(addr, bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("with reserve", func(b *testing.B) {
+		b.ReportAllocs()
+		// Reserve is timed too, so both runs measure the full cost of loading the range.
+		for i := 0; i < b.N; i++ {
+			m := NewMemory()
+			m.Reserve(addr, size)
+			if err := m.SetMemoryRange(addr, bytes.NewReader(data)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMemorySnapshot(b *testing.B) {
+	const size = 64 << 20 // 64 MiB
+	const writes = 16
+	addr := Word(0x10_000_000)
+	m := NewMemory()
+	if err := m.SetMemoryRange(addr, bytes.NewReader(make([]byte, size))); err != nil {
+		b.Fatal(err)
+	}
+	// Modify a few pages, as a short speculative run would
+	run := func(m *Memory) {
+		for i := Word(0); i < writes; i++ {
+			m.SetWord(addr+i*size/writes, Word(i))
+		}
+	}
+
+	b.Run("copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			speculative := m.Copy()
+			run(speculative)
+		}
+	})
+
+	b.Run("snapshot", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s := m.Snapshot()
+			run(m)
+			m.Restore(s)
+		}
+	})
+}
@@ -68,6 +68,9 @@ type CachedPage struct {
 	Cache [PageSize / 32][32]byte
 	// true if the intermediate node is valid
 	Ok [PageSize / 32]bool
+
+	// generation of the memory in which the page was allocated or last copied, see Memory.Snapshot
+	generation uint64
 }
 
 func (p *CachedPage) invalidate(pageAddr Word) {
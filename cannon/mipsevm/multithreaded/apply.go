@@ -0,0 +1,258 @@
+package multithreaded
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+)
+
+// ApplyStep executes the single instruction proven by pre and returns the resulting state hash.
+// The state is rebuilt from the witness and its proofs alone, as the contract does: memory holds only the proven leaves,
+// and the post-state memory root is recomputed along the proof paths. Of the threads, only the active thread is known;
+// the rest of the active stack and the inactive stack are carried by their roots.
+func ApplyStep(pre *mipsevm.StepWitness, oracle mipsevm.PreimageOracle) (common.Hash, error) {
+	if len(pre.State) != STATE_WITNESS_SIZE {
+		return common.Hash{}, fmt.Errorf("invalid witness length. Got %d, expected %d", len(pre.State), STATE_WITNESS_SIZE)
+	}
+	if hash := stateHashFromWitness(pre.State); hash != pre.StateHash {
+		return common.Hash{}, fmt.Errorf("witness hash %s does not match state hash %s", hash, pre.StateHash)
+	}
+	if expected := THREAD_WITNESS_SIZE + 3*memory.MemProofSize; len(pre.ProofData) != expected {
+		return common.Hash{}, fmt.Errorf("invalid proof data length. Got %d, expected %d", len(pre.ProofData), expected)
+	}
+	proofs := pre.ProofData[THREAD_WITNESS_SIZE:]
+	insnProof := [memory.MemProofSize]byte(proofs[:memory.MemProofSize])
+	memProof := [memory.MemProofSize]byte(proofs[memory.MemProofSize : 2*memory.MemProofSize])
+	memProof2 := [memory.MemProofSize]byte(proofs[2*memory.MemProofSize:])
+
+	state, placeholders, err := stateFromWitness(pre.State, pre.ProofData[:THREAD_WITNESS_SIZE])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	memRoot := common.Hash(pre.State[MEMROOT_WITNESS_OFFSET : MEMROOT_WITNESS_OFFSET+32])
+	pc := state.GetPC()
+	if proofRoot(pc, insnProof) != memRoot {
+		return common.Hash{}, fmt.Errorf("invalid instruction proof for pc 0x%x", pc)
+	}
+
+	// The proven addresses depend only on the instruction and registers, so find them by stepping over the
+	// instruction leaf alone. Hints were not part of the original step, and are dropped.
+	probe, _, err := stateFromWitness(pre.State, pre.ProofData[:THREAD_WITNESS_SIZE])
+	if err != nil {
+		return common.Hash{}, err
+	}
+	setProofLeaf(probe.Memory, pc, insnProof)
+	probeVM := NewInstrumentedState(probe, hintlessOracle{oracle}, io.Discard, io.Discard, log.NewLogger(log.DiscardHandler()), nil)
+	if _, err := probeVM.step(true); err != nil {
+		return common.Hash{}, err
+	}
+	addr, addr2 := probeVM.memoryTracker.MemProofAddrs()
+	usesProof2 := addr2 != ^Word(0) && addr2&^31 != addr&^31
+
+	state.Memory = memory.NewMemory()
+	setProofLeaf(state.Memory, pc, insnProof)
+	if addr != ^Word(0) {
+		if proofRoot(addr, memProof) != memRoot {
+			return common.Hash{}, fmt.Errorf("invalid memory proof for address 0x%x", addr)
+		}
+		setProofLeaf(state.Memory, addr, memProof)
+	}
+	if usesProof2 {
+		setProofLeaf(state.Memory, addr2, memProof2)
+	}
+
+	vm := NewInstrumentedState(state, oracle, io.Discard, io.Discard, log.NewLogger(log.DiscardHandler()), nil)
+	if _, err := vm.step(false); err != nil {
+		return common.Hash{}, err
+	}
+
+	postMemRoot := memRoot
+	if size := probeVM.memoryTracker.MemZeroSize(); size != 0 {
+		postMemRoot = zeroedProofRoot(addr, memProof, size)
+	} else if addr != ^Word(0) {
+		postMemRoot = proofRoot(addr, withLeaf(memProof, state.Memory, addr))
+	}
+	if usesProof2 {
+		// The second proof is taken after the first access is applied, so it must match the intermediate root
+		if proofRoot(addr2, memProof2) != postMemRoot {
+			return common.Hash{}, fmt.Errorf("invalid memory proof for address 0x%x", addr2)
+		}
+		postMemRoot = proofRoot(addr2, withLeaf(memProof2, state.Memory, addr2))
+	}
+
+	witness, _ := state.EncodeWitness()
+	copy(witness[MEMROOT_WITNESS_OFFSET:], postMemRoot[:])
+	leftRoot, rightRoot := placeholders.stackRoot(state.LeftThreadStack), placeholders.stackRoot(state.RightThreadStack)
+	copy(witness[LEFT_THREADS_ROOT_WITNESS_OFFSET:], leftRoot[:])
+	copy(witness[RIGHT_THREADS_ROOT_WITNESS_OFFSET:], rightRoot[:])
+	return stateHashFromWitness(witness), nil
+}
+
+// stateFromWitness decodes a state witness and the thread proof of its active thread.
+// The returned state has an empty memory. The threads other than the active thread are only known by the roots of
+// their stacks, and are stood in for by the returned placeholders.
+func stateFromWitness(sw []byte, threadProof []byte) (*State, threadPlaceholders, error) {
+	thread := new(ThreadState)
+	if err := thread.Deserialize(bytes.NewReader(threadProof[:SERIALIZED_THREAD_SIZE])); err != nil {
+		return nil, nil, fmt.Errorf("invalid thread proof: %w", err)
+	}
+	innerRoot := common.Hash(threadProof[SERIALIZED_THREAD_SIZE:])
+
+	state := &State{
+		Memory:                      memory.NewMemory(),
+		PreimageKey:                 common.Hash(sw[PREIMAGE_KEY_WITNESS_OFFSET : PREIMAGE_KEY_WITNESS_OFFSET+32]),
+		PreimageOffset:              arch.ByteOrderWord.Word(sw[PREIMAGE_OFFSET_WITNESS_OFFSET:]),
+		Heap:                        arch.ByteOrderWord.Word(sw[HEAP_WITNESS_OFFSET:]),
+		LLReservationStatus:         LLReservationStatus(sw[LL_RESERVATION_ACTIVE_OFFSET]),
+		LLAddress:                   arch.ByteOrderWord.Word(sw[LL_ADDRESS_OFFSET:]),
+		LLOwnerThread:               arch.ByteOrderWord.Word(sw[LL_OWNER_THREAD_OFFSET:]),
+		ExitCode:                    sw[EXITCODE_WITNESS_OFFSET],
+		Exited:                      sw[EXITED_WITNESS_OFFSET] != 0,
+		Step:                        binary.BigEndian.Uint64(sw[STEP_WITNESS_OFFSET:]),
+		StepsSinceLastContextSwitch: binary.BigEndian.Uint64(sw[STEPS_SINCE_CONTEXT_SWITCH_WITNESS_OFFSET:]),
+		Wakeup:                      arch.ByteOrderWord.Word(sw[WAKEUP_WITNESS_OFFSET:]),
+		WakeupBitset:                binary.BigEndian.Uint32(sw[WAKEUP_BITSET_WITNESS_OFFSET:]),
+		TraverseRight:               sw[TRAVERSE_RIGHT_WITNESS_OFFSET] != 0,
+		NextThreadId:                arch.ByteOrderWord.Word(sw[THREAD_ID_WITNESS_OFFSET:]),
+		LeftThreadStack:             []*ThreadState{},
+		RightThreadStack:            []*ThreadState{},
+	}
+
+	leftRoot := common.Hash(sw[LEFT_THREADS_ROOT_WITNESS_OFFSET : LEFT_THREADS_ROOT_WITNESS_OFFSET+32])
+	rightRoot := common.Hash(sw[RIGHT_THREADS_ROOT_WITNESS_OFFSET : RIGHT_THREADS_ROOT_WITNESS_OFFSET+32])
+	activeRoot, inactiveRoot := leftRoot, rightRoot
+	if state.TraverseRight {
+		activeRoot, inactiveRoot = rightRoot, leftRoot
+	}
+	if computeThreadRoot(innerRoot, thread) != activeRoot {
+		return nil, nil, errors.New("thread proof does not match the active thread stack root")
+	}
+	placeholders := make(threadPlaceholders)
+	activeStack := placeholders.stack(innerRoot)
+	activeStack = append(activeStack, thread)
+	inactiveStack := placeholders.stack(inactiveRoot)
+	if state.TraverseRight {
+		state.RightThreadStack, state.LeftThreadStack = activeStack, inactiveStack
+	} else {
+		state.LeftThreadStack, state.RightThreadStack = activeStack, inactiveStack
+	}
+	return state, placeholders, nil
+}
+
+// threadPlaceholders maps placeholder threads to the roots of the threads they stand in for.
+// A placeholder sits at the bottom of its stack, so the scheduler sees a non-empty stack, and a step touches at most
+// the top thread of each stack, so a placeholder is never executed.
+type threadPlaceholders map[*ThreadState]common.Hash
+
+// stack returns a thread stack that commits to root, with a placeholder for the threads of a non-empty root.
+func (p threadPlaceholders) stack(root common.Hash) []*ThreadState {
+	if root == EmptyThreadsRoot {
+		return []*ThreadState{}
+	}
+	placeholder := CreateEmptyThread()
+	p[placeholder] = root
+	return []*ThreadState{placeholder}
+}
+
+// stackRoot computes the root of a thread stack, substituting the root of each placeholder.
+func (p threadPlaceholders) stackRoot(stack []*ThreadState) common.Hash {
+	root := EmptyThreadsRoot
+	for _, thread := range stack {
+		if placeholderRoot, ok := p[thread]; ok {
+			root = placeholderRoot
+		} else {
+			root = computeThreadRoot(root, thread)
+		}
+	}
+	return root
+}
+
+// setProofLeaf writes the leaf of a memory proof for addr into mem.
+func setProofLeaf(mem *memory.Memory, addr Word, proof [memory.MemProofSize]byte) {
+	base := addr &^ 31
+	for i := Word(0); i < 32; i += arch.WordSizeBytes {
+		mem.SetWord(base+i, arch.ByteOrderWord.Word(proof[i:]))
+	}
+}
+
+// withLeaf returns proof with its leaf replaced by the leaf of mem containing addr.
+func withLeaf(proof [memory.MemProofSize]byte, mem *memory.Memory, addr Word) [memory.MemProofSize]byte {
+	base := addr &^ 31
+	for i := Word(0); i < 32; i += arch.WordSizeBytes {
+		arch.ByteOrderWord.PutWord(proof[i:], mem.GetWord(base+i))
+	}
+	return proof
+}
+
+// proofRoot computes the memory root committed to by a memory proof for addr.
+func proofRoot(addr Word, proof [memory.MemProofSize]byte) common.Hash {
+	node := [32]byte(proof[:32])
+	for i := 1; i < memory.MemProofLeafCount; i++ {
+		sibling := [32]byte(proof[i*32 : (i+1)*32])
+		if (addr>>(4+i))&1 != 0 {
+			node = memory.HashPair(sibling, node)
+		} else {
+			node = memory.HashPair(node, sibling)
+		}
+	}
+	return node
+}
+
+// zeroedProofRoot computes the memory root committed to by a memory proof for addr, with the block of size bytes
+// holding addr zeroed. The block is a subtree on the proof path, which is replaced by the root of a zeroed subtree.
+func zeroedProofRoot(addr Word, proof [memory.MemProofSize]byte, size Word) common.Hash {
+	depth := bits.TrailingZeros64(uint64(size)) - 5
+	var node [32]byte
+	for i := 0; i < depth; i++ {
+		node = memory.HashPair(node, node)
+	}
+	for i := depth + 1; i < memory.MemProofLeafCount; i++ {
+		sibling := [32]byte(proof[i*32 : (i+1)*32])
+		if (addr>>(4+i))&1 != 0 {
+			node = memory.HashPair(sibling, node)
+		} else {
+			node = memory.HashPair(node, sibling)
+		}
+	}
+	return node
+}
+
+// WitnessChainError reports the first witness of a chain that failed to apply or to link to its successor.
+type WitnessChainError struct {
+	Index int
+	Err   error
+}
+
+func (e *WitnessChainError) Error() string {
+	return fmt.Sprintf("witness %d: %v", e.Index, e.Err)
+}
+
+func (e *WitnessChainError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyWitnessChain checks that the witnesses form an execution: each witness is applied with ApplyStep, and its
+// post-state hash must match the pre-state hash of the next witness. The first failure is returned as a
+// *WitnessChainError.
+func VerifyWitnessChain(witnesses []mipsevm.StepWitness, oracle mipsevm.PreimageOracle) error {
+	for i := range witnesses {
+		post, err := ApplyStep(&witnesses[i], oracle)
+		if err != nil {
+			return &WitnessChainError{Index: i, Err: err}
+		}
+		if i+1 < len(witnesses) && post != witnesses[i+1].StateHash {
+			return &WitnessChainError{Index: i, Err: fmt.Errorf("post-state %s does not match next pre-state %s", post, witnesses[i+1].StateHash)}
+		}
+	}
+	return nil
+}
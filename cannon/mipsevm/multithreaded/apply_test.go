@@ -0,0 +1,207 @@
+package multithreaded
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
+)
+
+func TestApplyStep(t *testing.T) {
+	data := []byte("hello world")
+	// The two words written by clock_gettime straddle a memory proof leaf
+	const timespecAddr = Word(0x2020) - arch.WordSizeBytes
+
+	cases := []struct {
+		name  string
+		insn  uint32
+		setup func(state *State)
+	}{
+		{name: "alu", insn: 0x34_08_00_2a},  // ori $t0, $zero, 42
+		{name: "load", insn: 0x8c_08_01_00}, // lw $t0, 0x100($zero)
+		{name: "store", insn: 0xac_08_01_04, setup: func(state *State) { // sw $t0, 0x104($zero)
+			state.GetRegistersRef()[8] = 0xdead
+		}},
+		{name: "clock_gettime", insn: 0x00_00_00_0c, setup: func(state *State) { // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysClockGetTime
+			registers[4] = exec.ClockGettimeMonotonicFlag
+			registers[5] = timespecAddr
+		}},
+		{name: "readlinkat", insn: 0x00_00_00_0c, setup: func(state *State) { // syscall
+			require.NoError(t, state.Memory.SetMemoryRange(0x1010, strings.NewReader(exec.ProcSelfExe)))
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysReadlinkAt
+			registers[5] = 0x1010
+			registers[6] = 0x2005
+			registers[7] = 128
+		}},
+		{name: "preimage read", insn: 0x00_00_00_0c, setup: func(state *State) { // syscall
+			state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+			state.PreimageOffset = 4
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRead
+			registers[4] = exec.FdPreimageRead
+			registers[5] = 0x102
+			registers[6] = 4
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.Step = 1000
+			// Populate memory around the accessed addresses, so that the proofs have non-trivial siblings
+			for addr := Word(0x80); addr < 0x2100; addr += 0x40 {
+				state.Memory.SetWord(addr, addr*3+1)
+			}
+			state.Memory.SetWord(0x100, 0x12345678)
+			testutil.StoreInstruction(state.Memory, state.GetPC(), c.insn)
+			if c.setup != nil {
+				c.setup(state)
+			}
+			oracle := testutil.StaticOracle(t, data)
+
+			us := NewInstrumentedState(state, oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			wit, err := us.Step(true)
+			require.NoError(t, err)
+			_, expected := state.EncodeWitness()
+
+			actual, err := ApplyStep(wit, oracle)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestApplyStep_MultipleThreads(t *testing.T) {
+	cases := []struct {
+		name  string
+		insn  uint32
+		setup func(state *State)
+	}{
+		{name: "alu", insn: 0x34_08_00_2a}, // ori $t0, $zero, 42
+		{name: "preempt", insn: 0x34_08_00_2a, setup: func(state *State) {
+			state.StepsSinceLastContextSwitch = exec.SchedQuantum
+		}},
+		{name: "preempt last thread on stack", insn: 0x34_08_00_2a, setup: func(state *State) {
+			state.StepsSinceLastContextSwitch = exec.SchedQuantum
+			state.RightThreadStack = state.RightThreadStack[len(state.RightThreadStack)-1:]
+		}},
+		{name: "pop exited thread", insn: 0x34_08_00_2a, setup: func(state *State) {
+			state.GetCurrentThread().Exited = true
+		}},
+		{name: "clone", insn: 0x00_00_00_0c, setup: func(state *State) { // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysClone
+			registers[4] = exec.ValidCloneFlags
+			registers[5] = 0x1000
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.Step = 1000
+			// The active thread is on top of the right stack, with threads below it and on the left stack,
+			// so that the witness commits to threads other than the active thread.
+			newThread := func(id Word) *ThreadState {
+				thread := CreateEmptyThread()
+				thread.ThreadId = id
+				thread.Registers[16] = id + 1
+				return thread
+			}
+			state.LeftThreadStack = []*ThreadState{newThread(0), newThread(1)}
+			state.RightThreadStack = []*ThreadState{newThread(2), newThread(3)}
+			state.TraverseRight = true
+			state.NextThreadId = 4
+			testutil.StoreInstruction(state.Memory, state.GetPC(), c.insn)
+			if c.setup != nil {
+				c.setup(state)
+			}
+
+			us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			wit, err := us.Step(true)
+			require.NoError(t, err)
+			_, expected := state.EncodeWitness()
+
+			actual, err := ApplyStep(wit, nil)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestApplyStep_InvalidWitness(t *testing.T) {
+	newWitness := func(t *testing.T, state *State) *mipsevm.StepWitness {
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0xac_08_01_00) // sw $t0, 0x100($zero)
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		wit, err := us.Step(true)
+		require.NoError(t, err)
+		return wit
+	}
+
+	t.Run("state hash mismatch", func(t *testing.T) {
+		wit := newWitness(t, CreateEmptyState())
+		wit.StateHash[31] ^= 1
+		_, err := ApplyStep(wit, nil)
+		require.ErrorContains(t, err, "does not match state hash")
+	})
+
+	t.Run("invalid instruction proof", func(t *testing.T) {
+		wit := newWitness(t, CreateEmptyState())
+		wit.ProofData[THREAD_WITNESS_SIZE+32] ^= 1
+		_, err := ApplyStep(wit, nil)
+		require.ErrorContains(t, err, "invalid instruction proof")
+	})
+
+	t.Run("invalid memory proof", func(t *testing.T) {
+		wit := newWitness(t, CreateEmptyState())
+		wit.ProofData[THREAD_WITNESS_SIZE+arch.MemProofSize+32] ^= 1
+		_, err := ApplyStep(wit, nil)
+		require.ErrorContains(t, err, "invalid memory proof")
+	})
+}
+
+func TestVerifyWitnessChain(t *testing.T) {
+	state := CreateEmptyState()
+	insns := []uint32{
+		0x34_08_00_2a, // ori $t0, $zero, 42
+		0xac_08_01_00, // sw $t0, 0x100($zero)
+		0x8c_09_01_00, // lw $t1, 0x100($zero)
+		0x25_29_00_01, // addiu $t1, $t1, 1
+	}
+	for i, insn := range insns {
+		testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+	}
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	var witnesses []mipsevm.StepWitness
+	for range insns {
+		wit, err := us.Step(true)
+		require.NoError(t, err)
+		witnesses = append(witnesses, *wit)
+	}
+
+	require.NoError(t, VerifyWitnessChain(witnesses, nil))
+	require.NoError(t, VerifyWitnessChain(nil, nil))
+
+	// Dropping a step breaks the link from the step before it
+	broken := append(append([]mipsevm.StepWitness{}, witnesses[:2]...), witnesses[3:]...)
+	err := VerifyWitnessChain(broken, nil)
+	var chainErr *WitnessChainError
+	require.ErrorAs(t, err, &chainErr)
+	require.Equal(t, 1, chainErr.Index)
+
+	// A witness that fails to apply is reported at its own index
+	witnesses[3].ProofData[THREAD_WITNESS_SIZE+32] ^= 1
+	err = VerifyWitnessChain(witnesses, nil)
+	require.ErrorAs(t, err, &chainErr)
+	require.Equal(t, 3, chainErr.Index)
+	require.ErrorContains(t, err, "invalid instruction proof")
+}
@@ -0,0 +1,76 @@
+package multithreaded
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+)
+
+// InstrumentedCheckpoint is a copy of an InstrumentedState and its accounting, see InstrumentedState.Checkpoint.
+type InstrumentedCheckpoint struct {
+	state                  *State
+	preimages              *exec.PreimageTrackerCheckpoint
+	memProof               [memory.MemProofSize]byte
+	memProof2              [memory.MemProofSize]byte
+	syscallCounts          map[uint64]uint64
+	stepsPerThread         map[uint64]uint64
+	lastStepAllocatedPages int
+	scheduleDigest         common.Hash
+	loopVisits             map[ThreadState]loopVisit
+}
+
+// Checkpoint copies the state together with the accounting of the InstrumentedState: the preimage statistics, the
+// syscall and per-thread step counts, the schedule digest, the loop detection history and the memory proofs reused by
+// steps that do not access memory. A run continued from the restored checkpoint produces the same witnesses and
+// accounting as the original run. The configuration, such as limits and hooks, and the stack tracker of InitDebug are
+// not part of the checkpoint.
+func (m *InstrumentedState) Checkpoint() (*InstrumentedCheckpoint, error) {
+	state, err := m.state.clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy state: %w", err)
+	}
+	cp := &InstrumentedCheckpoint{
+		state:                  state,
+		preimages:              m.preimageOracle.Checkpoint(),
+		memProof:               m.memoryTracker.MemProof(),
+		memProof2:              m.memoryTracker.MemProof2(),
+		syscallCounts:          maps.Clone(m.syscallCounts),
+		stepsPerThread:         maps.Clone(m.stepsPerThread),
+		lastStepAllocatedPages: m.lastStepAllocatedPages,
+		scheduleDigest:         m.scheduleDigest,
+	}
+	if m.loopDetector != nil {
+		cp.loopVisits = maps.Clone(m.loopDetector.visits)
+	}
+	return cp, nil
+}
+
+// Restore resets the state and accounting to those of cp. The state is restored in place, so the state passed to
+// NewInstrumentedState remains in use. cp is not modified and may be restored again.
+func (m *InstrumentedState) Restore(cp *InstrumentedCheckpoint) error {
+	state, err := cp.state.clone()
+	if err != nil {
+		return fmt.Errorf("failed to copy checkpointed state: %w", err)
+	}
+	// The memory tracker refers to the memory of the state, so it is restored in place as well
+	mem := m.state.Memory
+	*mem = *state.Memory
+	state.Memory = mem
+	*m.state = *state
+
+	m.preimageOracle.Restore(cp.preimages)
+	m.memoryTracker.RestoreMemProofs(cp.memProof, cp.memProof2)
+	m.syscallCounts = maps.Clone(cp.syscallCounts)
+	m.stepsPerThread = maps.Clone(cp.stepsPerThread)
+	m.lastStepAllocatedPages = cp.lastStepAllocatedPages
+	m.scheduleDigest = cp.scheduleDigest
+	if m.loopDetector != nil {
+		m.loopDetector.visits = make(map[ThreadState]loopVisit, len(cp.loopVisits))
+		maps.Copy(m.loopDetector.visits, cp.loopVisits)
+	}
+	return nil
+}
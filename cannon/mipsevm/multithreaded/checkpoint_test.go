@@ -0,0 +1,78 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestInstrumentedState_Checkpoint(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+	oracle, _, _ := testutil.ClaimTestOracle(t)
+	us := NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	us.SetScheduleDigest(true)
+
+	// Checkpoint once preimages are being read
+	for us.preimageOracle.NumPreimageRequests() == 0 {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+		require.False(t, state.Exited)
+	}
+	cp, err := us.Checkpoint()
+	require.NoError(t, err)
+	_, cpHash := state.EncodeWitness()
+
+	type result struct {
+		witnesses      []*mipsevm.StepWitness
+		stateHash      [32]byte
+		debugInfo      *mipsevm.DebugInfo
+		stepsPerThread map[uint64]uint64
+		scheduleDigest [32]byte
+	}
+	run := func() result {
+		var res result
+		for i := 0; i < 1000; i++ {
+			wit, err := us.Step(true)
+			require.NoError(t, err)
+			res.witnesses = append(res.witnesses, wit)
+		}
+		for i := 0; i < 2_000_000 && !state.Exited; i++ {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		require.True(t, state.Exited, "must complete program")
+		require.Equal(t, uint8(0), state.ExitCode)
+		_, res.stateHash = state.EncodeWitness()
+		res.debugInfo = us.GetDebugInfo()
+		res.stepsPerThread = us.StepsPerThread()
+		res.scheduleDigest = us.ScheduleDigest()
+		return res
+	}
+	expected := run()
+
+	// Diverge from the checkpoint, then restore it again
+	require.NoError(t, us.Restore(cp))
+	_, restoredHash := state.EncodeWitness()
+	require.Equal(t, cpHash, restoredHash)
+	state.GetRegistersRef()[16] ^= 0xff
+	state.Memory.SetWord(0x1000, 0xdead)
+	for i := 0; i < 100; i++ {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	require.NoError(t, us.Restore(cp))
+
+	actual := run()
+	require.Len(t, actual.witnesses, len(expected.witnesses))
+	for i, wit := range expected.witnesses {
+		require.Equalf(t, *wit, *actual.witnesses[i], "witness %d", i)
+	}
+	require.Equal(t, expected.stateHash, actual.stateHash)
+	require.Equal(t, expected.debugInfo, actual.debugInfo)
+	require.Equal(t, expected.stepsPerThread, actual.stepsPerThread)
+	require.Equal(t, expected.scheduleDigest, actual.scheduleDigest)
+}
@@ -0,0 +1,17 @@
+package multithreaded
+
+import "github.com/ethereum/go-ethereum/common"
+
+// StepCollect steps m up to n times and returns the state hash after each step, for bisection down to single steps.
+// It stops early once the VM exits, so fewer than n hashes are returned if the VM exits first.
+func (m *InstrumentedState) StepCollect(n uint64) ([]common.Hash, error) {
+	var hashes []common.Hash
+	for i := uint64(0); i < n && !m.state.Exited; i++ {
+		if _, err := m.Step(false); err != nil {
+			return nil, err
+		}
+		_, hash := m.state.EncodeWitness()
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
@@ -0,0 +1,49 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestStepCollect(t *testing.T) {
+	newVM := func() (*InstrumentedState, *State) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+		return NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta), state
+	}
+
+	us, _ := newVM()
+	hashes, err := us.StepCollect(100)
+	require.NoError(t, err)
+	require.Len(t, hashes, 100)
+
+	ref, state := newVM()
+	for i, hash := range hashes {
+		_, err := ref.Step(false)
+		require.NoError(t, err)
+		_, expected := state.EncodeWitness()
+		require.Equalf(t, expected, hash, "hash after step %d", i+1)
+	}
+}
+
+func TestStepCollect_StopsOnExit(t *testing.T) {
+	state := CreateEmptyState()
+	testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+	state.GetRegistersRef()[2] = arch.SysExitGroup
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+
+	hashes, err := us.StepCollect(10)
+	require.NoError(t, err)
+	require.True(t, state.Exited)
+	_, finalHash := state.EncodeWitness()
+	require.Equal(t, []common.Hash{finalHash}, hashes)
+
+	hashes, err = us.StepCollect(10)
+	require.NoError(t, err)
+	require.Empty(t, hashes)
+}
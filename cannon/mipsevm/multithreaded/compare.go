@@ -0,0 +1,125 @@
+package multithreaded
+
+import (
+	"fmt"
+	"slices"
+)
+
+// RunOutcome classifies the result of CompareRuns.
+type RunOutcome uint8
+
+const (
+	// RunsIdentical means both runs had identical states at every compared step.
+	RunsIdentical RunOutcome = iota
+	// RunsDiverged means the states of the runs differed at some step.
+	RunsDiverged
+	// RunsExitCodesDiffer means the runs diverged, and then both exited with different exit codes.
+	RunsExitCodesDiffer
+)
+
+func (o RunOutcome) String() string {
+	switch o {
+	case RunsIdentical:
+		return "identical"
+	case RunsDiverged:
+		return "diverged"
+	case RunsExitCodesDiffer:
+		return "different-exit-code"
+	default:
+		return fmt.Sprintf("RunOutcome(%d)", uint8(o))
+	}
+}
+
+// RunComparison is the result of CompareRuns.
+type RunComparison struct {
+	Outcome RunOutcome
+	// Step is the step of the first differing states, or the number of steps compared if the runs are identical.
+	Step uint64
+	// Field names the first state witness field that differs. It is empty if the runs are identical.
+	Field string
+}
+
+// firstStateDiff returns the name of the first field, in state witness order, that differs between a and b, or ""
+// if none do. Thread stacks are compared thread by thread, which is equivalent to comparing their roots but cheaper.
+func firstStateDiff(a, b *State) string {
+	threadsEqual := func(x, y *ThreadState) bool { return *x == *y }
+	switch {
+	case a.Memory.MerkleRoot() != b.Memory.MerkleRoot():
+		return "MemRoot"
+	case a.PreimageKey != b.PreimageKey:
+		return "PreimageKey"
+	case a.PreimageOffset != b.PreimageOffset:
+		return "PreimageOffset"
+	case a.Heap != b.Heap:
+		return "Heap"
+	case a.LLReservationStatus != b.LLReservationStatus:
+		return "LLReservationStatus"
+	case a.LLAddress != b.LLAddress:
+		return "LLAddress"
+	case a.LLOwnerThread != b.LLOwnerThread:
+		return "LLOwnerThread"
+	case a.ExitCode != b.ExitCode:
+		return "ExitCode"
+	case a.Exited != b.Exited:
+		return "Exited"
+	case a.Step != b.Step:
+		return "Step"
+	case a.StepsSinceLastContextSwitch != b.StepsSinceLastContextSwitch:
+		return "StepsSinceLastContextSwitch"
+	case a.Wakeup != b.Wakeup:
+		return "Wakeup"
+	case a.WakeupBitset != b.WakeupBitset:
+		return "WakeupBitset"
+	case a.TraverseRight != b.TraverseRight:
+		return "TraverseRight"
+	case !slices.EqualFunc(a.LeftThreadStack, b.LeftThreadStack, threadsEqual):
+		return "LeftThreadStack"
+	case !slices.EqualFunc(a.RightThreadStack, b.RightThreadStack, threadsEqual):
+		return "RightThreadStack"
+	case a.NextThreadId != b.NextThreadId:
+		return "NextThreadId"
+	default:
+		return ""
+	}
+}
+
+// CompareRuns steps a and b in lockstep, for up to maxSteps steps or until both exit, and compares their states
+// before the first and after every step. It reports the first state that differs, and which field differs first.
+// Once the runs diverge, both continue independently within the remaining steps, to classify runs that then exit
+// with different exit codes. Use it to check that a change to the VM, such as an experimental option enabled on
+// only one of the runs, does not change execution. Guest output is not compared.
+func CompareRuns(a, b *InstrumentedState, maxSteps uint64) (RunComparison, error) {
+	for i := uint64(0); ; i++ {
+		if field := firstStateDiff(a.state, b.state); field != "" {
+			cmp := RunComparison{Outcome: RunsDiverged, Step: a.state.Step, Field: field}
+			if err := runUntilExit(a, maxSteps-i); err != nil {
+				return RunComparison{}, fmt.Errorf("run a failed at step %d: %w", a.state.Step, err)
+			}
+			if err := runUntilExit(b, maxSteps-i); err != nil {
+				return RunComparison{}, fmt.Errorf("run b failed at step %d: %w", b.state.Step, err)
+			}
+			if a.state.Exited && b.state.Exited && a.state.ExitCode != b.state.ExitCode {
+				cmp.Outcome = RunsExitCodesDiffer
+			}
+			return cmp, nil
+		}
+		if i == maxSteps || a.state.Exited {
+			return RunComparison{Outcome: RunsIdentical, Step: i}, nil
+		}
+		if _, err := a.Step(false); err != nil {
+			return RunComparison{}, fmt.Errorf("run a failed at step %d: %w", a.state.Step, err)
+		}
+		if _, err := b.Step(false); err != nil {
+			return RunComparison{}, fmt.Errorf("run b failed at step %d: %w", b.state.Step, err)
+		}
+	}
+}
+
+func runUntilExit(m *InstrumentedState, maxSteps uint64) error {
+	for i := uint64(0); i < maxSteps && !m.state.Exited; i++ {
+		if _, err := m.Step(false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package multithreaded
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
+)
+
+func TestCompareRuns(t *testing.T) {
+	newClaimVM := func() *InstrumentedState {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+		oracle, _, _ := testutil.ClaimTestOracle(t)
+		return NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	}
+
+	t.Run("identical", func(t *testing.T) {
+		if os.Getenv("SKIP_SLOW_TESTS") == "true" {
+			t.Skip("Skipping slow test because SKIP_SLOW_TESTS is enabled")
+		}
+		newHelloVM := func() *InstrumentedState {
+			state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+			return NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+		}
+		a, b := newHelloVM(), newHelloVM()
+		// An option that must not affect execution
+		b.SetScheduleDigest(true)
+		cmp, err := CompareRuns(a, b, 2_000_000)
+		require.NoError(t, err)
+		require.Equal(t, RunsIdentical, cmp.Outcome)
+		require.Empty(t, cmp.Field)
+		require.True(t, a.state.Exited, "must complete program")
+		require.Equal(t, a.state.Step, cmp.Step)
+	})
+
+	t.Run("max steps", func(t *testing.T) {
+		cmp, err := CompareRuns(newClaimVM(), newClaimVM(), 1000)
+		require.NoError(t, err)
+		require.Equal(t, RunComparison{Outcome: RunsIdentical, Step: 1000}, cmp)
+	})
+
+	t.Run("diverged", func(t *testing.T) {
+		data := []byte("hello world")
+		newReadVM := func() *InstrumentedState {
+			state := CreateEmptyState()
+			state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+			state.PreimageOffset = 8                                                // skip the length prefix
+			testutil.StoreInstruction(state.Memory, state.GetPC()+4, 0x00_00_00_0c) // syscall, after a nop
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRead
+			registers[4] = exec.FdPreimageRead
+			registers[5] = 0x1000
+			registers[6] = 4
+			return NewInstrumentedState(state, testutil.StaticOracle(t, data), io.Discard, io.Discard, testutil.CreateLogger(), nil)
+		}
+		a, b := newReadVM(), newReadVM()
+		b.SetPreimageInterceptor(corruptPreimage)
+		cmp, err := CompareRuns(a, b, 10)
+		require.NoError(t, err)
+		require.Equal(t, RunComparison{Outcome: RunsDiverged, Step: 2, Field: "MemRoot"}, cmp)
+	})
+
+	t.Run("different exit codes", func(t *testing.T) {
+		if os.Getenv("SKIP_SLOW_TESTS") == "true" {
+			t.Skip("Skipping slow test because SKIP_SLOW_TESTS is enabled")
+		}
+		claimKey := preimage.LocalIndexKey(2).PreimageKey()
+		a, b := newClaimVM(), newClaimVM()
+		b.SetPreimageInterceptor(func(key [32]byte, data []byte) []byte {
+			if key == claimKey {
+				return corruptPreimage(key, data)
+			}
+			return data
+		})
+		cmp, err := CompareRuns(a, b, 2_000_000)
+		require.NoError(t, err)
+		require.Equal(t, RunsExitCodesDiffer, cmp.Outcome)
+		// The corrupted claim is first visible in memory, once it is read
+		require.Equal(t, "MemRoot", cmp.Field)
+		require.NotZero(t, cmp.Step)
+		require.Equal(t, uint8(0), a.state.ExitCode)
+		require.Equal(t, uint8(1), b.state.ExitCode)
+	})
+}
+
+func corruptPreimage(key [32]byte, data []byte) []byte {
+	data = bytes.Clone(data)
+	data[0] ^= 1
+	return data
+}
@@ -0,0 +1,104 @@
+package multithreaded
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/op-service/serialize"
+)
+
+// CompressedStateVersion is the version of the format written by WriteStateCompressed.
+const CompressedStateVersion = 1
+
+// compressedStateMagic is the header identifying the format written by WriteStateCompressed.
+var compressedStateMagic = [4]byte{'C', 'M', 'T', 'S'}
+
+var (
+	ErrNotCompressedState            = errors.New("not a compressed state")
+	ErrUnknownCompressedStateVersion = errors.New("unknown compressed state version")
+)
+
+// WriteStateCompressed writes s in a compact format for storing snapshots, which can be read with ReadStateCompressed.
+// The format starts with a magic header and a version byte, followed by a gzip stream of:
+//
+// len(state JSON)    uint32
+// state JSON         the JSON encoding of the state, without the memory
+// memory             as per Memory.Serialize
+//
+// The memory pages are stored uncompressed within the gzip stream, rather than individually compressed as in the JSON
+// encoding, so that gzip can compress across pages.
+func WriteStateCompressed(w io.Writer, s *State) error {
+	if err := s.validateThreads(); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	withoutMemory := *s
+	withoutMemory.Memory = nil
+	stateJSON, err := json.Marshal((*stateMarshaling)(&withoutMemory))
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if _, err := w.Write(compressedStateMagic[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte{CompressedStateVersion}); err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(w)
+	if err := serialize.NewBinaryWriter(gw).WriteBytes(stateJSON); err != nil {
+		return err
+	}
+	if err := s.Memory.Serialize(gw); err != nil {
+		return fmt.Errorf("failed to write memory: %w", err)
+	}
+	return gw.Close()
+}
+
+// ReadStateCompressed reads a state written by WriteStateCompressed. The state is validated as in UnmarshalJSON.
+func ReadStateCompressed(r io.Reader) (*State, error) {
+	var header [len(compressedStateMagic) + 1]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if !bytes.Equal(header[:len(compressedStateMagic)], compressedStateMagic[:]) {
+		return nil, ErrNotCompressedState
+	}
+	if version := header[len(compressedStateMagic)]; version != CompressedStateVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownCompressedStateVersion, version)
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+	defer gr.Close()
+	var stateJSON []byte
+	if err := serialize.NewBinaryReader(gr).ReadBytes(&stateJSON); err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+	var sm stateMarshaling
+	if err := json.Unmarshal(stateJSON, &sm); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	decoded := (*State)(&sm)
+	decoded.Memory, err = memory.LoadMemoryFromPages(gr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	}
+	// Read to the end of the stream, so that the gzip checksum is verified
+	if n, err := io.Copy(io.Discard, gr); err != nil {
+		return nil, fmt.Errorf("failed to read memory: %w", err)
+	} else if n != 0 {
+		return nil, fmt.Errorf("unexpected %d bytes after memory", n)
+	}
+	state := new(State)
+	if err := state.setDecoded(decoded); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
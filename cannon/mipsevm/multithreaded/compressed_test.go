@@ -0,0 +1,68 @@
+package multithreaded
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestStateCompressed(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("mt-general"), CreateInitialState, false)
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	for i := 0; i < 1_000_000 && (len(state.LeftThreadStack) == 0 || len(state.RightThreadStack) == 0); i++ {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	state.LastHint = []byte{1, 2, 3}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteStateCompressed(&buf, state))
+	compressed := buf.Bytes()
+	stateJSON, err := json.Marshal(state)
+	require.NoError(t, err)
+	require.Less(t, len(compressed), len(stateJSON), "must be smaller than the JSON encoding")
+
+	decoded, err := ReadStateCompressed(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	expectedWitness, expectedHash := state.EncodeWitness()
+	witness, hash := decoded.EncodeWitness()
+	require.Equal(t, expectedWitness, witness)
+	require.Equal(t, expectedHash, hash)
+	require.Equal(t, state.LeftThreadStack, decoded.LeftThreadStack)
+	require.Equal(t, state.RightThreadStack, decoded.RightThreadStack)
+	require.Equal(t, state.LastHint, decoded.LastHint)
+	var expected, actual bytes.Buffer
+	require.NoError(t, state.Serialize(&expected))
+	require.NoError(t, decoded.Serialize(&actual))
+	require.Equal(t, expected.Bytes(), actual.Bytes())
+
+	t.Run("not compressed state", func(t *testing.T) {
+		_, err := ReadStateCompressed(bytes.NewReader(stateJSON))
+		require.ErrorIs(t, err, ErrNotCompressedState)
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		data := bytes.Clone(compressed)
+		data[len(compressedStateMagic)] = CompressedStateVersion + 1
+		_, err := ReadStateCompressed(bytes.NewReader(data))
+		require.ErrorIs(t, err, ErrUnknownCompressedStateVersion)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, n := range []int{0, len(compressedStateMagic), len(compressed) / 2, len(compressed) - 1} {
+			_, err := ReadStateCompressed(bytes.NewReader(compressed[:n]))
+			require.Errorf(t, err, "truncated to %d bytes", n)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		invalid := CreateEmptyState()
+		invalid.NextThreadId = 0
+		require.ErrorContains(t, WriteStateCompressed(io.Discard, invalid), "invalid state")
+	})
+}
@@ -0,0 +1,73 @@
+package multithreaded
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+)
+
+// EXEC_RECORD_SIZE is the size of an encoded ExecRecord in bytes.
+// 20 and 28 bytes for 32 and 64-bit respectively
+const EXEC_RECORD_SIZE = 8 + arch.WordSizeBytes + 4 + arch.WordSizeBytes
+
+// ExecRecord describes the active thread at the start of a step.
+// Steps that do not execute an instruction, such as preemptions, record the instruction the thread is waiting to run.
+type ExecRecord struct {
+	Step     uint64
+	PC       Word
+	Insn     uint32
+	ThreadId Word
+}
+
+func (r *ExecRecord) encode(out []byte) []byte {
+	out = binary.BigEndian.AppendUint64(out, r.Step)
+	out = arch.ByteOrderWord.AppendWord(out, r.PC)
+	out = binary.BigEndian.AppendUint32(out, r.Insn)
+	out = arch.ByteOrderWord.AppendWord(out, r.ThreadId)
+	return out
+}
+
+// SetExecLog appends a fixed-size binary record of every executed step to w, to be read back with ReadExecLog.
+// A nil w disables the log. Steps of an exited VM are not recorded.
+func (m *InstrumentedState) SetExecLog(w io.Writer) {
+	m.execLog = w
+}
+
+func (m *InstrumentedState) writeExecLog() error {
+	thread := m.state.GetCurrentThread()
+	insn, _, _ := exec.GetInstructionDetails(thread.Cpu.PC, m.state.Memory)
+	record := ExecRecord{
+		Step:     m.state.Step,
+		PC:       thread.Cpu.PC,
+		Insn:     insn,
+		ThreadId: thread.ThreadId,
+	}
+	m.execLogBuf = record.encode(m.execLogBuf[:0])
+	if _, err := m.execLog.Write(m.execLogBuf); err != nil {
+		return fmt.Errorf("failed to write exec log: %w", err)
+	}
+	return nil
+}
+
+// ReadExecLog reads all records written by an exec log, see InstrumentedState.SetExecLog.
+func ReadExecLog(r io.Reader) ([]ExecRecord, error) {
+	var records []ExecRecord
+	var buf [EXEC_RECORD_SIZE]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); errors.Is(err, io.EOF) {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read exec record %d: %w", len(records), err)
+		}
+		records = append(records, ExecRecord{
+			Step:     binary.BigEndian.Uint64(buf[0:8]),
+			PC:       arch.ByteOrderWord.Word(buf[8:]),
+			Insn:     binary.BigEndian.Uint32(buf[8+arch.WordSizeBytes:]),
+			ThreadId: arch.ByteOrderWord.Word(buf[12+arch.WordSizeBytes:]),
+		})
+	}
+}
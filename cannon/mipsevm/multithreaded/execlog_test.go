@@ -0,0 +1,51 @@
+package multithreaded
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestInstrumentedState_ExecLog(t *testing.T) {
+	state := CreateEmptyState()
+	state.GetCurrentThread().ThreadId = 5
+	state.NextThreadId = 6
+	insns := []uint32{
+		0x34_08_00_2a,                     // ori $t0, $zero, 42
+		0x24_04_00_00,                     // addiu $a0, $zero, 0
+		0x24_02_00_00 | arch.SysExitGroup, // addiu $v0, $zero, exit_group
+		0x00_00_00_0c,                     // syscall
+	}
+	for i, insn := range insns {
+		testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+	}
+	var log bytes.Buffer
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	us.SetExecLog(&log)
+	for !state.Exited {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	// Steps after exit are not logged
+	_, err := us.Step(false)
+	require.NoError(t, err)
+	require.Equal(t, len(insns)*EXEC_RECORD_SIZE, log.Len())
+
+	records, err := ReadExecLog(&log)
+	require.NoError(t, err)
+	require.Len(t, records, len(insns))
+	for i, insn := range insns {
+		require.Equal(t, ExecRecord{Step: uint64(i), PC: Word(4 * i), Insn: insn, ThreadId: 5}, records[i])
+	}
+
+	// A truncated record is an error
+	var buf bytes.Buffer
+	buf.Write(make([]byte, EXEC_RECORD_SIZE+1))
+	_, err = ReadExecLog(&buf)
+	require.ErrorContains(t, err, "failed to read exec record 1")
+}
@@ -0,0 +1,54 @@
+package multithreaded
+
+import (
+	"debug/elf"
+	"fmt"
+	"slices"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
+)
+
+// DiffELFImages loads a and b with program.LoadELF and returns the sorted indices of the memory pages that differ
+// between the two initial images. A page that is only allocated for one of the programs is compared against a zero
+// page. This tells apart changes to the guest binary from changes to the VM when debugging a behavior change.
+func DiffELFImages(a, b *elf.File) ([]uint64, error) {
+	stateA, err := program.LoadELF(a, CreateInitialState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load first ELF: %w", err)
+	}
+	stateB, err := program.LoadELF(b, CreateInitialState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load second ELF: %w", err)
+	}
+
+	pagesA := loadedPages(stateA.Memory)
+	pagesB := loadedPages(stateB.Memory)
+	var zero memory.Page
+	var diff []uint64
+	for pageIndex, pageA := range pagesA {
+		pageB, ok := pagesB[pageIndex]
+		if !ok {
+			pageB = &zero
+		}
+		if *pageA != *pageB {
+			diff = append(diff, uint64(pageIndex))
+		}
+	}
+	for pageIndex, pageB := range pagesB {
+		if _, ok := pagesA[pageIndex]; !ok && *pageB != zero {
+			diff = append(diff, uint64(pageIndex))
+		}
+	}
+	slices.Sort(diff)
+	return diff, nil
+}
+
+func loadedPages(mem *memory.Memory) map[Word]*memory.Page {
+	pages := make(map[Word]*memory.Page, mem.PageCount())
+	_ = mem.ForEachPage(func(pageIndex Word, page *memory.Page) error {
+		pages[pageIndex] = page
+		return nil
+	})
+	return pages
+}
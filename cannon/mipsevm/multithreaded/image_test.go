@@ -0,0 +1,64 @@
+package multithreaded
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestDiffELFImages(t *testing.T) {
+	data, err := os.ReadFile(testutil.ProgramPath("hello"))
+	require.NoError(t, err)
+	original, err := elf.Open(testutil.ProgramPath("hello"))
+	require.NoError(t, err)
+	defer original.Close()
+
+	t.Run("identical", func(t *testing.T) {
+		diff, err := DiffELFImages(original, original)
+		require.NoError(t, err)
+		require.Empty(t, diff)
+	})
+
+	t.Run("patched", func(t *testing.T) {
+		// Flip a byte in the first and last loaded segments
+		var loaded []*elf.Prog
+		for _, prog := range original.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Filesz > 0 {
+				loaded = append(loaded, prog)
+			}
+		}
+		require.GreaterOrEqual(t, len(loaded), 2)
+		patched := append([]byte(nil), data...)
+		first, last := loaded[0], loaded[len(loaded)-1]
+		patched[first.Off+0x10] ^= 0xff
+		patched[last.Off+last.Filesz-1] ^= 0xff
+		expected := []uint64{(first.Vaddr + 0x10) >> memory.PageAddrSize, (last.Vaddr + last.Filesz - 1) >> memory.PageAddrSize}
+		require.Less(t, expected[0], expected[1])
+
+		path := filepath.Join(t.TempDir(), "patched.elf")
+		require.NoError(t, os.WriteFile(path, patched, 0o644))
+		modified, err := elf.Open(path)
+		require.NoError(t, err)
+		defer modified.Close()
+
+		diff, err := DiffELFImages(original, modified)
+		require.NoError(t, err)
+		require.Equal(t, expected, diff)
+		diff, err = DiffELFImages(modified, original)
+		require.NoError(t, err)
+		require.Equal(t, expected, diff)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := *original
+		invalid.Data = elf.ELFDATA2LSB
+		_, err := DiffELFImages(original, &invalid)
+		require.ErrorContains(t, err, "failed to load second ELF")
+	})
+}
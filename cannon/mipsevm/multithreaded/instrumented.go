@@ -1,14 +1,24 @@
 package multithreaded
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"maps"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
 )
 
 type InstrumentedState struct {
@@ -23,8 +33,59 @@ type InstrumentedState struct {
 
 	preimageOracle *exec.TrackingPreimageOracleReader
 	meta           mipsevm.Metadata
+
+	// rawOracle is the untracked oracle, used to replay steps without affecting preimage statistics
+	rawOracle mipsevm.PreimageOracle
+
+	slowStepFn        SlowStepFn
+	slowStepThreshold time.Duration
+
+	verifyDeterminism bool
+
+	onExit OnExitFn
+
+	heapWatermarkFn       HeapWatermarkFn
+	heapWatermarkInterval Word
+
+	execLog    io.Writer
+	execLogBuf []byte
+
+	scheduleDigestEnabled bool
+	scheduleDigest        common.Hash
+
+	loopDetector *loopDetector
+
+	textSegments []program.TextSegment
+	// first store to a text segment in the current step, if any
+	textWrite *WriteToTextError
+
+	syscallCounts  map[uint64]uint64
+	stepsPerThread map[uint64]uint64
+	// number of memory pages allocated by the most recent step
+	lastStepAllocatedPages int
+
+	threadIdAllocator ThreadIdAllocator
+	maxThreads        int
 }
 
+// ErrStepUnreachable is returned by StepUntilProof when the target step cannot be reached.
+var ErrStepUnreachable = errors.New("step unreachable")
+
+// ErrThreadIdInUse is returned by Step when a ThreadIdAllocator returns the id of an existing thread.
+var ErrThreadIdInUse = errors.New("thread id in use")
+
+// SlowStepFn is called with the step number and duration of any step that exceeds the configured threshold.
+type SlowStepFn func(step uint64, dur time.Duration)
+
+// OnExitFn is called with the post-state of the step in which the VM exited.
+type OnExitFn func(s *State)
+
+// ThreadIdAllocator returns the id of a thread created by SysClone. It must not return the id of an existing thread.
+type ThreadIdAllocator func(s *State) Word
+
+// HeapWatermarkFn is called with the new heap pointer when an mmap grows the heap past a watermark.
+type HeapWatermarkFn func(heap Word)
+
 var _ mipsevm.FPVM = (*InstrumentedState)(nil)
 
 func NewInstrumentedState(state *State, po mipsevm.PreimageOracle, stdOut, stdErr io.Writer, log log.Logger, meta mipsevm.Metadata) *InstrumentedState {
@@ -37,6 +98,9 @@ func NewInstrumentedState(state *State, po mipsevm.PreimageOracle, stdOut, stdEr
 		stackTracker:   &NoopThreadedStackTracker{},
 		preimageOracle: exec.NewTrackingPreimageOracleReader(po),
 		meta:           meta,
+		rawOracle:      po,
+		syscallCounts:  make(map[uint64]uint64),
+		stepsPerThread: make(map[uint64]uint64),
 	}
 }
 
@@ -49,9 +113,224 @@ func (m *InstrumentedState) InitDebug() error {
 	return nil
 }
 
+// SetSlowStepFn registers fn to be called whenever a step, including witness generation, takes longer than threshold.
+// This is for observability only and does not affect execution. A nil fn disables the check.
+func (m *InstrumentedState) SetSlowStepFn(threshold time.Duration, fn SlowStepFn) {
+	m.slowStepThreshold = threshold
+	m.slowStepFn = fn
+}
+
+// SetOnExit registers fn to be called once the VM exits, so tooling can inspect exit conventions in memory or
+// registers for diagnostics. fn must not modify the state. A nil fn disables the hook.
+func (m *InstrumentedState) SetOnExit(fn OnExitFn) {
+	m.onExit = fn
+}
+
+// SetHeapWatermarkFn registers fn to be called whenever the heap grows past a multiple of interval.
+// An mmap that crosses several watermarks at once results in a single call. A nil fn or zero interval disables the check.
+func (m *InstrumentedState) SetHeapWatermarkFn(interval Word, fn HeapWatermarkFn) {
+	m.heapWatermarkInterval = interval
+	m.heapWatermarkFn = fn
+}
+
+func (m *InstrumentedState) checkHeapWatermark(oldHeap, newHeap Word) {
+	if m.heapWatermarkFn == nil || m.heapWatermarkInterval == 0 {
+		return
+	}
+	if newHeap/m.heapWatermarkInterval > oldHeap/m.heapWatermarkInterval {
+		m.heapWatermarkFn(newHeap)
+	}
+}
+
+// SetMaxPreimageBytes bounds the total size of the preimages served to the guest. Once the budget is exceeded, the
+// step that loaded the preimage completes as onchain, and Step then fails with exec.ErrPreimageBudgetExceeded.
+// A max of 0, the default, is unlimited.
+func (m *InstrumentedState) SetMaxPreimageBytes(max int) {
+	m.preimageOracle.SetMaxPreimageBytes(max)
+}
+
+// SetMaxDistinctPreimages bounds the number of distinct preimages served to the guest. Once the limit is exceeded,
+// the step that loaded the preimage completes as onchain, and Step then fails with exec.ErrTooManyPreimages.
+// A max of 0, the default, is unlimited.
+func (m *InstrumentedState) SetMaxDistinctPreimages(max int) {
+	m.preimageOracle.SetMaxDistinctPreimages(max)
+}
+
+// SetAllowedPreimageKeyTypes restricts the key types of the preimages served to the guest. Step fails with
+// exec.ErrInvalidPreimageKeyType when the guest reads a preimage with a key of any other type, which indicates a
+// guest bug. The onchain VM serves keys of any type. Passing no types, the default, allows any key type.
+func (m *InstrumentedState) SetAllowedPreimageKeyTypes(types ...preimage.KeyType) {
+	m.preimageOracle.SetAllowedKeyTypes(types...)
+}
+
+// SetPreimageInterceptor installs fn to transform the preimages served to the guest, for fault-injection testing.
+// See exec.TrackingPreimageOracleReader.SetPreimageInterceptor.
+func (m *InstrumentedState) SetPreimageInterceptor(fn exec.PreimageInterceptor) {
+	m.preimageOracle.SetPreimageInterceptor(fn)
+}
+
+// SetScheduleDigest enables or disables accumulating the id of the active thread of every step into ScheduleDigest.
+// Enabling resets the digest. Steps of an exited VM are not included.
+func (m *InstrumentedState) SetScheduleDigest(enabled bool) {
+	m.scheduleDigestEnabled = enabled
+	if enabled {
+		m.scheduleDigest = common.Hash{}
+	}
+}
+
+// ScheduleDigest returns a rolling hash of the sequence of active thread ids since SetScheduleDigest was enabled.
+// Runs with identical scheduling produce identical digests.
+func (m *InstrumentedState) ScheduleDigest() common.Hash {
+	return m.scheduleDigest
+}
+
+func (m *InstrumentedState) updateScheduleDigest() {
+	var buf [32 + arch.WordSizeBytes]byte
+	copy(buf[:32], m.scheduleDigest[:])
+	arch.ByteOrderWord.PutWord(buf[32:], m.state.GetCurrentThread().ThreadId)
+	m.scheduleDigest = crypto.Keccak256Hash(buf[:])
+}
+
+// SetThreadIdAllocator overrides how SysClone assigns thread ids, e.g. to reproduce the ids of a captured state.
+// NextThreadId is kept above every allocated id. If the allocator returns the id of an existing thread, Step fails
+// with ErrThreadIdInUse before the thread is created. Custom allocators diverge from the onchain VM and are for
+// offchain testing only. A nil allocator, the default, assigns NextThreadId. With SetVerifyDeterminism, the allocator
+// is called again to replay the step, so it must depend only on the state.
+func (m *InstrumentedState) SetThreadIdAllocator(fn ThreadIdAllocator) {
+	m.threadIdAllocator = fn
+}
+
+func (m *InstrumentedState) allocateThreadId() (Word, error) {
+	if m.threadIdAllocator == nil {
+		return m.state.NextThreadId, nil
+	}
+	id := m.threadIdAllocator(m.state)
+	for _, stack := range [][]*ThreadState{m.state.LeftThreadStack, m.state.RightThreadStack} {
+		for _, thread := range stack {
+			if thread.ThreadId == id {
+				return 0, fmt.Errorf("%w: %d", ErrThreadIdInUse, id)
+			}
+		}
+	}
+	return id, nil
+}
+
+// SetMaxThreads bounds the number of threads of the guest. A SysClone that would exceed the limit fails with
+// EAGAIN and leaves the existing threads untouched, so that a runaway guest cannot grow the thread stacks without
+// bound. The onchain VM does not count threads, so a limited run diverges from it once the limit is reached, and
+// is for offchain testing only. A max of 0, the default, is unlimited: a default such as 1024 would make every run
+// that exceeds it diverge from the onchain VM, so the limit must be opted into.
+func (m *InstrumentedState) SetMaxThreads(max int) {
+	m.maxThreads = max
+}
+
+// SetVerifyDeterminism enables re-executing every step on a copy of the pre-state and comparing the results.
+// Step returns an error if the two executions diverge. This is an expensive debugging aid and is off by default.
+func (m *InstrumentedState) SetVerifyDeterminism(enabled bool) {
+	m.verifyDeterminism = enabled
+}
+
 func (m *InstrumentedState) Step(proof bool) (wit *mipsevm.StepWitness, err error) {
+	if m.slowStepFn != nil {
+		step := m.state.Step
+		start := time.Now()
+		defer func() {
+			if dur := time.Since(start); dur > m.slowStepThreshold {
+				m.slowStepFn(step, dur)
+			}
+		}()
+	}
+	m.textWrite = nil
+	if m.verifyDeterminism {
+		wit, err = m.stepVerified(proof)
+	} else {
+		wit, err = m.step(proof)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.textWrite != nil {
+		return nil, m.textWrite
+	}
+	if err := m.preimageOracle.CheckLimits(); err != nil {
+		return nil, err
+	}
+	if m.loopDetector != nil {
+		if err := m.checkLoop(); err != nil {
+			return nil, err
+		}
+	}
+	return wit, nil
+}
+
+// StepUntilProof runs without generating witnesses until the state reaches targetStep, then executes that step and
+// returns its witness. Memory proofs that the target step does not use are zero, whereas Step(true) repeats the
+// memory proofs of an earlier step; neither is read when verifying the step.
+// It fails with ErrStepUnreachable if the state is already past targetStep, or exits before reaching it.
+func (m *InstrumentedState) StepUntilProof(targetStep uint64) (*mipsevm.StepWitness, error) {
+	if m.state.Step > targetStep {
+		return nil, fmt.Errorf("%w: state is at step %d, after target step %d", ErrStepUnreachable, m.state.Step, targetStep)
+	}
+	for m.state.Step < targetStep {
+		if m.state.Exited {
+			return nil, fmt.Errorf("%w: exited at step %d, before target step %d", ErrStepUnreachable, m.state.Step, targetStep)
+		}
+		if _, err := m.Step(false); err != nil {
+			return nil, err
+		}
+	}
+	m.memoryTracker.RestoreMemProofs([memory.MemProofSize]byte{}, [memory.MemProofSize]byte{})
+	return m.Step(true)
+}
+
+// stepVerified executes the step, then replays it from a copy of the pre-state and checks that both runs agree.
+func (m *InstrumentedState) stepVerified(proof bool) (*mipsevm.StepWitness, error) {
+	step := m.state.Step
+	preState, err := m.state.clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy pre-state: %w", err)
+	}
+	wit, err := m.step(proof)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := m.newReplay(preState)
+	replayWit, err := replay.step(proof)
+	if err != nil {
+		return nil, fmt.Errorf("replay of step %d failed: %w", step, err)
+	}
+
+	_, postHash := m.state.EncodeWitness()
+	_, replayHash := replay.state.EncodeWitness()
+	if postHash != replayHash {
+		return nil, fmt.Errorf("nondeterministic step %d: post-state %s, replayed post-state %s", step, postHash, replayHash)
+	}
+	// Memory proofs are not compared, as the tracker retains stale proofs from earlier steps that did not access memory
+	if proof && (wit.PreimageKey != replayWit.PreimageKey || wit.PreimageOffset != replayWit.PreimageOffset || !bytes.Equal(wit.PreimageValue, replayWit.PreimageValue)) {
+		return nil, fmt.Errorf("nondeterministic step %d: preimage witness does not match replayed witness", step)
+	}
+	return wit, nil
+}
+
+// newReplay returns a VM that executes steps from state with the offchain configuration of m that affects
+// execution: the preimage limits and interceptor, the thread id allocator, the thread limit and the text segments.
+// The replay must not produce side effects, so its output is discarded, hints are not sent again, and callbacks
+// such as OnExitFn are not copied.
+func (m *InstrumentedState) newReplay(state *State) *InstrumentedState {
+	replay := NewInstrumentedState(state, hintlessOracle{m.rawOracle}, io.Discard, io.Discard, m.log, m.meta)
+	replay.preimageOracle.CopyConfig(m.preimageOracle)
+	replay.threadIdAllocator = m.threadIdAllocator
+	replay.maxThreads = m.maxThreads
+	replay.textSegments = m.textSegments
+	return replay
+}
+
+func (m *InstrumentedState) step(proof bool) (wit *mipsevm.StepWitness, err error) {
 	m.preimageOracle.Reset()
 	m.memoryTracker.Reset(proof)
+	m.lastStepAllocatedPages = 0
+	pageCount := m.state.Memory.PageCount()
 
 	if proof {
 		proofData := make([]byte, 0)
@@ -67,10 +346,27 @@ func (m *InstrumentedState) Step(proof bool) (wit *mipsevm.StepWitness, err erro
 			ProofData: proofData,
 		}
 	}
+	exited := m.state.Exited
+	if m.execLog != nil && !exited {
+		if err := m.writeExecLog(); err != nil {
+			return nil, err
+		}
+	}
+	if m.scheduleDigestEnabled && !exited {
+		m.updateScheduleDigest()
+	}
+	if !exited {
+		m.stepsPerThread[uint64(m.state.GetCurrentThread().ThreadId)]++
+	}
 	err = m.mipsStep()
+	// a step that releases pages allocates none
+	m.lastStepAllocatedPages = max(0, m.state.Memory.PageCount()-pageCount)
 	if err != nil {
 		return nil, err
 	}
+	if !exited && m.state.Exited && m.onExit != nil {
+		m.onExit(m.state)
+	}
 
 	if proof {
 		memProof := m.memoryTracker.MemProof()
@@ -105,9 +401,27 @@ func (m *InstrumentedState) GetDebugInfo() *mipsevm.DebugInfo {
 		MemoryUsed:          hexutil.Uint64(m.state.Memory.UsageRaw()),
 		NumPreimageRequests: m.preimageOracle.NumPreimageRequests(),
 		TotalPreimageSize:   m.preimageOracle.TotalPreimageSize(),
+		SyscallCounts:       m.SyscallCounts(),
 	}
 }
 
+// SyscallCounts returns the number of times each syscall, by number, was invoked by the guest.
+func (m *InstrumentedState) SyscallCounts() map[uint64]uint64 {
+	return maps.Clone(m.syscallCounts)
+}
+
+// StepsPerThread returns the number of steps in which each thread, by id, was the active thread.
+// Steps spent waking, preempting or removing a thread count towards that thread.
+func (m *InstrumentedState) StepsPerThread() map[uint64]uint64 {
+	return maps.Clone(m.stepsPerThread)
+}
+
+// LastStepAllocatedPages returns the number of memory pages allocated by the most recent step, to locate the
+// instructions that grow the memory footprint.
+func (m *InstrumentedState) LastStepAllocatedPages() int {
+	return m.lastStepAllocatedPages
+}
+
 func (m *InstrumentedState) Traceback() {
 	m.stackTracker.Traceback()
 }
@@ -118,3 +432,10 @@ func (m *InstrumentedState) LookupSymbol(addr arch.Word) string {
 	}
 	return m.meta.LookupSymbol(addr)
 }
+
+// hintlessOracle serves preimages from the wrapped oracle, but drops hints.
+type hintlessOracle struct {
+	mipsevm.PreimageOracle
+}
+
+func (o hintlessOracle) Hint(v []byte) {}
@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/stretchr/testify/require"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
 )
 
 func vmFactory(state *State, po mipsevm.PreimageOracle, stdOut, stdErr io.Writer, log log.Logger, meta *program.Metadata) mipsevm.FPVM {
@@ -252,3 +259,1382 @@ func TestInstrumentedState_Alloc(t *testing.T) {
 		})
 	}
 }
+
+type slowOracle struct {
+	delay time.Duration
+	data  []byte
+}
+
+func (o *slowOracle) Hint(v []byte) {}
+
+func (o *slowOracle) GetPreimage(k [32]byte) []byte {
+	time.Sleep(o.delay)
+	return o.data
+}
+
+func TestInstrumentedState_SlowStepFn(t *testing.T) {
+	data := []byte("hello world")
+	oracle := &slowOracle{delay: 50 * time.Millisecond, data: data}
+
+	newState := func() *State {
+		state := CreateEmptyState()
+		state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		state.Step = 10
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysRead
+		registers[4] = exec.FdPreimageRead
+		registers[5] = 0x1000
+		registers[6] = 4
+		return state
+	}
+
+	t.Run("slow step", func(t *testing.T) {
+		us := NewInstrumentedState(newState(), oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		var calls int
+		us.SetSlowStepFn(10*time.Millisecond, func(step uint64, dur time.Duration) {
+			calls++
+			require.Equal(t, uint64(10), step)
+			require.GreaterOrEqual(t, dur, oracle.delay)
+		})
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("fast step", func(t *testing.T) {
+		us := NewInstrumentedState(newState(), oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		us.SetSlowStepFn(time.Hour, func(step uint64, dur time.Duration) {
+			t.Fatalf("unexpected slow step %d: %v", step, dur)
+		})
+		_, err := us.Step(true)
+		require.NoError(t, err)
+	})
+}
+
+type counterOracle struct {
+	calls byte
+}
+
+func (o *counterOracle) Hint(v []byte) {}
+
+func (o *counterOracle) GetPreimage(k [32]byte) []byte {
+	o.calls++
+	return []byte{o.calls, o.calls, o.calls, o.calls}
+}
+
+func TestInstrumentedState_WitnessMemoryProofs(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	for i := 0; i < 5000 && !state.Exited; i++ {
+		pc := state.GetPC()
+		root := state.Memory.MerkleRoot()
+		wit, err := us.Step(true)
+		require.NoError(t, err)
+		// The instruction proof, and the first memory proof, are taken from the pre-state
+		proofs := wit.ProofData[THREAD_WITNESS_SIZE:]
+		require.True(t, memory.VerifyMerkleProof(root, pc, [memory.MemProofSize]byte(proofs[:memory.MemProofSize])))
+		if addr, _ := us.memoryTracker.MemProofAddrs(); addr != ^Word(0) {
+			require.True(t, memory.VerifyMerkleProof(root, addr, [memory.MemProofSize]byte(proofs[memory.MemProofSize:])))
+		}
+	}
+}
+
+func TestInstrumentedState_StepUntilProof(t *testing.T) {
+	type provenStep struct {
+		wit      *mipsevm.StepWitness
+		memAddr  Word
+		memAddr2 Word
+	}
+	newVM := func() (*State, *InstrumentedState) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+		oracle, _, _ := testutil.ClaimTestOracle(t)
+		return state, NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	}
+	// proveSteps generates a witness for every step from the current step until the last step
+	proveSteps := func(us *InstrumentedState, last uint64, expected map[uint64]provenStep) {
+		for us.state.Step <= last {
+			step := us.state.Step
+			wit, err := us.Step(true)
+			require.NoError(t, err)
+			memAddr, memAddr2 := us.memoryTracker.MemProofAddrs()
+			expected[step] = provenStep{wit: wit, memAddr: memAddr, memAddr2: memAddr2}
+		}
+	}
+
+	expected := make(map[uint64]provenStep)
+	_, us := newVM()
+	proveSteps(us, 100_000, expected)
+	targets := []uint64{0, 1, 1000, 100_000}
+	// Prove the steps around the first preimage read, proving every step before it would be slow
+	_, us = newVM()
+	for us.preimageOracle.NumPreimageRequests() == 0 {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	preimageStep := us.state.Step - 1
+	_, us = newVM()
+	for us.state.Step < preimageStep-100 {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	proveSteps(us, preimageStep+1, expected)
+	require.True(t, expected[preimageStep].wit.HasPreimage())
+	targets = append(targets, preimageStep, preimageStep+1)
+
+	proofsOffset := THREAD_WITNESS_SIZE + memory.MemProofSize
+	for _, target := range targets {
+		state, us := newVM()
+		wit, err := us.StepUntilProof(target)
+		require.NoError(t, err)
+		require.Equal(t, target+1, state.Step)
+		exp := expected[target]
+		require.Equalf(t, exp.wit.State, wit.State, "step %d", target)
+		require.Equalf(t, exp.wit.StateHash, wit.StateHash, "step %d", target)
+		require.Equalf(t, exp.wit.PreimageKey, wit.PreimageKey, "step %d", target)
+		require.Equalf(t, exp.wit.PreimageValue, wit.PreimageValue, "step %d", target)
+		require.Equalf(t, exp.wit.PreimageOffset, wit.PreimageOffset, "step %d", target)
+		require.Len(t, wit.ProofData, len(exp.wit.ProofData))
+		// The thread and instruction proofs always match, the memory proofs only if the step uses them
+		require.Equalf(t, exp.wit.ProofData[:proofsOffset], wit.ProofData[:proofsOffset], "step %d", target)
+		memAddr, memAddr2 := us.memoryTracker.MemProofAddrs()
+		require.Equal(t, exp.memAddr, memAddr)
+		require.Equal(t, exp.memAddr2, memAddr2)
+		memProof := wit.ProofData[proofsOffset : proofsOffset+memory.MemProofSize]
+		memProof2 := wit.ProofData[proofsOffset+memory.MemProofSize:]
+		if memAddr != ^Word(0) {
+			require.Equalf(t, exp.wit.ProofData[proofsOffset:proofsOffset+memory.MemProofSize], memProof, "step %d", target)
+		} else {
+			require.Equal(t, make([]byte, memory.MemProofSize), memProof)
+		}
+		if memAddr2 != ^Word(0) {
+			require.Equalf(t, exp.wit.ProofData[proofsOffset+memory.MemProofSize:], memProof2, "step %d", target)
+		} else {
+			require.Equal(t, make([]byte, memory.MemProofSize), memProof2)
+		}
+	}
+
+	t.Run("past target", func(t *testing.T) {
+		_, us := newVM()
+		_, err := us.StepUntilProof(10)
+		require.NoError(t, err)
+		_, err = us.StepUntilProof(10)
+		require.ErrorIs(t, err, ErrStepUnreachable)
+	})
+
+	t.Run("exits before target", func(t *testing.T) {
+		state, us := newVM()
+		_, err := us.StepUntilProof(10_000_000)
+		require.ErrorIs(t, err, ErrStepUnreachable)
+		require.True(t, state.Exited)
+	})
+}
+
+func TestInstrumentedState_VerifyDeterminism(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+		var stdOutBuf bytes.Buffer
+		us := NewInstrumentedState(state, nil, &stdOutBuf, os.Stderr, testutil.CreateLogger(), meta)
+		us.SetVerifyDeterminism(true)
+		for i := 0; i < 20; i++ {
+			_, err := us.Step(i%2 == 0)
+			require.NoError(t, err)
+		}
+		require.Equal(t, uint64(20), state.Step)
+	})
+
+	t.Run("nondeterministic oracle", func(t *testing.T) {
+		state := CreateEmptyState()
+		state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash([]byte("hello"))).PreimageKey()
+		// Skip the length prefix, so the read data differs between runs
+		state.PreimageOffset = 8
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysRead
+		registers[4] = exec.FdPreimageRead
+		registers[5] = 0x1000
+		registers[6] = 4
+
+		us := NewInstrumentedState(state, &counterOracle{}, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		us.SetVerifyDeterminism(true)
+		_, err := us.Step(true)
+		require.ErrorContains(t, err, "nondeterministic step 0")
+	})
+
+	t.Run("offchain config", func(t *testing.T) {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, 0, 0x00_00_00_0c) // syscall
+		us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+		us.SetVerifyDeterminism(true)
+		us.SetMaxThreads(2)
+		us.SetThreadIdAllocator(func(s *State) Word {
+			return s.NextThreadId + 10
+		})
+
+		// The replay must allocate the same thread id, and enforce the same thread limit
+		for i := 0; i < 2; i++ {
+			thread := state.GetCurrentThread()
+			thread.Cpu.PC = 0
+			thread.Cpu.NextPC = 4
+			thread.Registers[2] = arch.SysClone
+			thread.Registers[4] = exec.ValidCloneFlags
+			thread.Registers[5] = 0x8000
+			thread.Registers[7] = 0
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, state.ThreadCount())
+		require.Equal(t, Word(11), state.GetCurrentThread().ThreadId)
+		require.Equal(t, Word(exec.MipsEAGAIN), state.GetCurrentThread().Registers[7])
+	})
+}
+
+func TestInstrumentedState_OnExit(t *testing.T) {
+	const statusAddr = 0x100
+	newState := func() *State {
+		state := CreateEmptyState()
+		insns := []uint32{
+			0x34_08_00_2a,                     // ori $t0, $zero, 42
+			0xac_08_01_00,                     // sw $t0, 0x100($zero)
+			0x24_04_00_01,                     // addiu $a0, $zero, 1
+			0x24_02_00_00 | arch.SysExitGroup, // addiu $v0, $zero, exit_group
+			0x00_00_00_0c,                     // syscall
+		}
+		for i, insn := range insns {
+			testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+		}
+		return state
+	}
+	run := func(us *InstrumentedState) {
+		for !us.state.Exited {
+			_, err := us.Step(true)
+			require.NoError(t, err)
+		}
+	}
+
+	us := NewInstrumentedState(newState(), nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	var calls int
+	var status Word
+	us.SetOnExit(func(s *State) {
+		calls++
+		require.True(t, s.Exited)
+		require.Equal(t, uint8(1), s.ExitCode)
+		status = exec.LoadSubWord(s.Memory, statusAddr, 4, false, new(exec.NoopMemoryTracker))
+	})
+	run(us)
+	require.Equal(t, 1, calls)
+	require.Equal(t, Word(42), status)
+
+	// The hook must not affect the resulting state
+	plain := NewInstrumentedState(newState(), nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	run(plain)
+	_, hookedHash := us.state.EncodeWitness()
+	_, plainHash := plain.state.EncodeWitness()
+	require.Equal(t, plainHash, hookedHash)
+}
+
+func TestInstrumentedState_HeapWatermarkFn(t *testing.T) {
+	const interval = Word(256 << 20)
+	watermark := (program.HEAP_START/interval + 1) * interval
+	state := CreateEmptyState()
+	state.Heap = watermark - 2*memory.PageSize
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	var watermarks []Word
+	us.SetHeapWatermarkFn(interval, func(heap Word) {
+		watermarks = append(watermarks, heap)
+	})
+
+	mmap := func(size Word) {
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysMmap
+		registers[4] = 0
+		registers[5] = size
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, Word(0), registers[7], "mmap must succeed")
+	}
+
+	mmap(memory.PageSize)
+	require.Empty(t, watermarks)
+	mmap(memory.PageSize)
+	require.Equal(t, []Word{watermark}, watermarks)
+	mmap(memory.PageSize)
+	require.Len(t, watermarks, 1, "must not fire again until the next watermark")
+	// Crossing two watermarks in a single mmap fires once
+	mmap(2 * interval)
+	require.Equal(t, []Word{watermark, watermark + 2*interval + memory.PageSize}, watermarks)
+}
+
+func TestInstrumentedState_PreimageOffsetOutOfBounds(t *testing.T) {
+	data := []byte("hello world")
+	oracle := testutil.StaticOracle(t, data)
+
+	cases := []struct {
+		name        string
+		offset      Word
+		expectedErr bool
+	}{
+		{name: "last byte", offset: Word(8 + len(data) - 1)},
+		{name: "end of preimage", offset: Word(8 + len(data)), expectedErr: true},
+		{name: "far past end", offset: 0x1000, expectedErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+			state.PreimageOffset = c.offset
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRead
+			registers[4] = exec.FdPreimageRead
+			registers[5] = 0x1000
+			registers[6] = 4
+
+			// Load the state as if from a checkpoint
+			var buf bytes.Buffer
+			require.NoError(t, state.Serialize(&buf))
+			loaded := new(State)
+			require.NoError(t, loaded.Deserialize(&buf))
+
+			us := NewInstrumentedState(loaded, oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			_, err := us.Step(true)
+			if c.expectedErr {
+				require.ErrorIs(t, err, exec.ErrPreimageOffsetOutOfBounds)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, c.offset+1, loaded.PreimageOffset)
+			}
+		})
+	}
+}
+
+func TestInstrumentedState_MaxPreimageBytes(t *testing.T) {
+	data := []byte("hello world")
+	oracle := testutil.StaticOracle(t, data)
+
+	cases := []struct {
+		name        string
+		max         int
+		expectedErr bool
+	}{
+		{name: "unlimited", max: 0},
+		{name: "within budget", max: len(data)},
+		{name: "over budget", max: len(data) - 1, expectedErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRead
+			registers[4] = exec.FdPreimageRead
+			registers[5] = 0x1000
+			registers[6] = 4
+
+			us := NewInstrumentedState(state, oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			us.SetMaxPreimageBytes(c.max)
+			_, err := us.Step(true)
+			if c.expectedErr {
+				require.ErrorIs(t, err, exec.ErrPreimageBudgetExceeded)
+			} else {
+				require.NoError(t, err)
+			}
+			// The read completes as onchain, even when the budget is exceeded
+			require.Equal(t, Word(4), state.PreimageOffset)
+		})
+	}
+}
+
+func TestInstrumentedState_MaxDistinctPreimages(t *testing.T) {
+	oracle := testutil.ComputingOracle()
+	var keys [][32]byte
+	for _, v := range []string{"a", "b", "c"} {
+		oracle.Hint([]byte(v))
+		keys = append(keys, preimage.Keccak256Key(crypto.Keccak256Hash([]byte(v))).PreimageKey())
+	}
+
+	cases := []struct {
+		name        string
+		max         int
+		expectedErr bool
+	}{
+		{name: "unlimited", max: 0},
+		{name: "within limit", max: len(keys)},
+		{name: "over limit", max: len(keys) - 1, expectedErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			us := NewInstrumentedState(state, oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			us.SetMaxDistinctPreimages(c.max)
+
+			// Read each key, revisiting the first one, which does not count as a new preimage
+			for i, key := range append(keys, keys[0]) {
+				state.PreimageKey = key
+				state.PreimageOffset = 0
+				testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+				registers := state.GetRegistersRef()
+				registers[2] = arch.SysRead
+				registers[4] = exec.FdPreimageRead
+				registers[5] = 0x1000
+				registers[6] = 4
+
+				_, err := us.Step(true)
+				if c.expectedErr && i == len(keys)-1 {
+					require.ErrorIs(t, err, exec.ErrTooManyPreimages)
+					// The read completes as onchain, even when the limit is exceeded
+					require.Equal(t, Word(4), state.PreimageOffset)
+					return
+				}
+				require.NoError(t, err)
+			}
+			require.False(t, c.expectedErr, "must fail on the first read past the limit")
+			require.Equal(t, len(keys), us.preimageOracle.NumDistinctPreimages())
+		})
+	}
+}
+
+func TestInstrumentedState_AllowedPreimageKeyTypes(t *testing.T) {
+	data := []byte("hello world")
+	oracle := testutil.StaticOracle(t, data)
+
+	cases := []struct {
+		name        string
+		types       []preimage.KeyType
+		expectedErr bool
+	}{
+		{name: "any"},
+		{name: "allowed", types: []preimage.KeyType{preimage.LocalKeyType, preimage.Keccak256KeyType}},
+		{name: "not allowed", types: []preimage.KeyType{preimage.LocalKeyType}, expectedErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRead
+			registers[4] = exec.FdPreimageRead
+			registers[5] = 0x1000
+			registers[6] = 4
+
+			us := NewInstrumentedState(state, oracle, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+			us.SetAllowedPreimageKeyTypes(c.types...)
+			_, err := us.Step(true)
+			if c.expectedErr {
+				require.ErrorIs(t, err, exec.ErrInvalidPreimageKeyType)
+				// The preimage is not fetched
+				require.Zero(t, us.preimageOracle.NumPreimageRequests())
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, Word(4), state.PreimageOffset)
+			}
+		})
+	}
+}
+
+func TestInstrumentedState_PreimageInterceptor(t *testing.T) {
+	claimKey := preimage.LocalIndexKey(2).PreimageKey()
+	run := func(t *testing.T, fn exec.PreimageInterceptor, verify bool) (*State, string) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+		oracle, _, _ := testutil.ClaimTestOracle(t)
+		var stdOutBuf bytes.Buffer
+		us := NewInstrumentedState(state, oracle, &stdOutBuf, io.Discard, testutil.CreateLogger(), meta)
+		us.SetPreimageInterceptor(fn)
+		us.SetVerifyDeterminism(verify)
+		for i := 0; i < 2_000_000 && !state.Exited; i++ {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		require.True(t, state.Exited, "must complete program")
+		return state, stdOutBuf.String()
+	}
+
+	t.Run("identity", func(t *testing.T) {
+		var keys [][32]byte
+		state, stdOut := run(t, func(key [32]byte, data []byte) []byte {
+			keys = append(keys, key)
+			return data
+		}, false)
+		require.Equal(t, uint8(0), state.ExitCode)
+		require.Contains(t, stdOut, "is good!")
+		require.Contains(t, keys, claimKey)
+	})
+
+	t.Run("corrupt claim", func(t *testing.T) {
+		state, stdOut := run(t, func(key [32]byte, data []byte) []byte {
+			if key == claimKey {
+				data = bytes.Clone(data)
+				data[len(data)-1] ^= 1
+			}
+			return data
+		}, false)
+		require.Equal(t, uint8(1), state.ExitCode)
+		require.Contains(t, stdOut, "is bad!")
+	})
+
+	t.Run("replayed with determinism checks", func(t *testing.T) {
+		data := []byte("hello world")
+		state := CreateEmptyState()
+		state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+		state.PreimageOffset = 8
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysRead
+		registers[4] = exec.FdPreimageRead
+		registers[5] = 0x1000
+		registers[6] = 4
+
+		us := NewInstrumentedState(state, testutil.StaticOracle(t, data), os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		us.SetVerifyDeterminism(true)
+		us.SetPreimageInterceptor(func(key [32]byte, data []byte) []byte {
+			return []byte("jello world")
+		})
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		expected := make([]byte, arch.WordSizeBytes)
+		copy(expected, "jell")
+		require.Equal(t, arch.ByteOrderWord.Word(expected), state.Memory.GetWord(0x1000))
+	})
+}
+
+func TestInstrumentedState_ScheduleDigest(t *testing.T) {
+	// Each thread repeatedly yields, so that every other step switches threads
+	run := func(threadIds []Word) common.Hash {
+		mem := memory.NewMemory()
+		for pc := Word(0x1000); pc < 0x1100; pc += 8 {
+			testutil.StoreInstruction(mem, pc, 0x24_02_00_00|uint32(arch.SysSchedYield)) // addiu $v0, $zero, SysSchedYield
+			testutil.StoreInstruction(mem, pc+4, 0x00_00_00_0c)                          // syscall
+		}
+		var threads []*ThreadState
+		for _, id := range threadIds {
+			thread := CreateEmptyThread()
+			thread.ThreadId = id
+			thread.Cpu.PC = 0x1000
+			thread.Cpu.NextPC = 0x1004
+			threads = append(threads, thread)
+		}
+		state, err := NewStateWithThreads(mem, threads, nil, false, 3)
+		require.NoError(t, err)
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+		us.SetScheduleDigest(true)
+		for i := 0; i < 20; i++ {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		return us.ScheduleDigest()
+	}
+
+	digest := run([]Word{0, 1, 2})
+	require.NotEqual(t, common.Hash{}, digest)
+	require.Equal(t, digest, run([]Word{0, 1, 2}))
+	require.NotEqual(t, digest, run([]Word{1, 0, 2}))
+}
+
+func TestInstrumentedState_SchedQuantumPreemption(t *testing.T) {
+	threadA := CreateEmptyThread()
+	threadA.ThreadId = 0
+	threadB := CreateEmptyThread()
+	threadB.ThreadId = 1
+	threadB.Cpu.PC = 0x2000
+	threadB.Cpu.NextPC = 0x2004
+	state, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{threadB, threadA}, nil, false, 2)
+	require.NoError(t, err)
+	testutil.StoreInstruction(state.Memory, threadA.Cpu.PC, 0x24_08_00_01) // addiu $t0, $zero, 1
+	require.NoError(t, state.SetStepsSinceLastContextSwitch(exec.SchedQuantum-1))
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+	// The last step of the quantum still runs the active thread
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, Word(0), state.GetCurrentThread().ThreadId)
+	require.Equal(t, Word(1), threadA.Registers[8])
+	require.Equal(t, uint64(exec.SchedQuantum), state.StepsSinceLastContextSwitch)
+
+	// The next step preempts it
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, Word(1), state.GetCurrentThread().ThreadId)
+	require.Equal(t, []*ThreadState{threadA}, state.RightThreadStack)
+	require.Equal(t, uint64(0), state.StepsSinceLastContextSwitch)
+}
+
+func TestInstrumentedState_PendingWakeup(t *testing.T) {
+	const futexAddr = Word(0x1000)
+	threadA := CreateEmptyThread()
+	threadA.ThreadId = 0
+	threadB := CreateEmptyThread()
+	threadB.ThreadId = 1
+	threadB.FutexAddr = futexAddr
+	threadB.FutexTimeoutStep = exec.FutexNoTimeout
+	threadB.FutexBitset = exec.FutexBitsetMatchAny
+	state, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{threadB, threadA}, nil, false, 2)
+	require.NoError(t, err)
+	require.NoError(t, state.SetWakeup(futexAddr, exec.FutexBitsetMatchAny))
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+	// The active thread is not waiting on the address, so the traversal moves past it
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, futexAddr, state.Wakeup)
+	require.Equal(t, Word(1), state.GetCurrentThread().ThreadId)
+
+	// The waiting thread is found, which ends the traversal
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, exec.FutexEmptyAddr, state.Wakeup)
+	require.Zero(t, state.WakeupBitset)
+}
+
+func TestInstrumentedState_ExitedThreads(t *testing.T) {
+	exiting := CreateEmptyThread()
+	exiting.ThreadId = 1
+	exiting.Cpu.PC = 0x2000
+	exiting.Cpu.NextPC = 0x2004
+	other := CreateEmptyThread()
+	state, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{other, exiting}, nil, false, 2)
+	require.NoError(t, err)
+	testutil.StoreInstruction(state.Memory, exiting.Cpu.PC, 0x00_00_00_0c) // syscall
+	exiting.Registers[2] = arch.SysExit
+	exiting.Registers[4] = 3
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	require.Empty(t, state.ExitedThreads())
+
+	// The exited thread stays on its stack until it is next scheduled
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.False(t, state.Exited)
+	require.Equal(t, []*ThreadState{exiting}, state.ExitedThreads())
+	require.Equal(t, 2, state.ThreadCount())
+
+	// It is then removed, rather than accumulating
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Empty(t, state.ExitedThreads())
+	require.Equal(t, 1, state.ThreadCount())
+}
+
+func TestInstrumentedState_UserIds(t *testing.T) {
+	for _, syscallNum := range []Word{arch.SysGetuid, arch.SysGeteuid, arch.SysGetgid, arch.SysGetegid} {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		state.Memory.SetWord(0x1000, 0xdead)
+		registers := state.GetRegistersRef()
+		registers[2] = syscallNum
+		registers[7] = 0xbad
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, Word(0), registers[2], "syscall %d must return id 0", syscallNum)
+		require.Equal(t, Word(0), registers[7], "syscall %d must not fail", syscallNum)
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+	}
+}
+
+func TestInstrumentedState_Poll(t *testing.T) {
+	for _, syscallNum := range []Word{arch.SysPoll, arch.SysPpoll} {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		state.Memory.SetWord(0x1000, 0xdead)
+		registers := state.GetRegistersRef()
+		registers[2] = syscallNum
+		registers[4] = 0x1000 // fds
+		registers[5] = 1      // nfds
+		registers[6] = 0x2000 // timeout
+		memRoot := state.Memory.MerkleRoot()
+		step := state.Step
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, Word(0), registers[2], "syscall %d must report no ready fds", syscallNum)
+		require.Equal(t, Word(0), registers[7], "syscall %d must not fail", syscallNum)
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+		require.Equal(t, step+1, state.Step)
+		require.Equal(t, state.LeftThreadStack[0], state.GetCurrentThread(), "polling thread must not be preempted")
+	}
+}
+
+func TestInstrumentedState_Faccessat(t *testing.T) {
+	state := CreateEmptyState()
+	testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+	state.Memory.SetWord(0x2000, 0x2f_65_74_63)                           // "/etc"
+	registers := state.GetRegistersRef()
+	registers[2] = arch.SysFaccessat
+	registers[4] = ^Word(99) // AT_FDCWD
+	registers[5] = 0x2000
+	memRoot := state.Memory.MerkleRoot()
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+	_, err := us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, exec.SysErrorSignal, registers[2])
+	require.Equal(t, Word(exec.MipsENOENT), registers[7])
+	require.Equal(t, memRoot, state.Memory.MerkleRoot())
+}
+
+func TestInstrumentedState_ReadlinkatPathSpanningLeaves(t *testing.T) {
+	state := CreateEmptyState()
+	testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+	// /proc/self/exe, starting 4 bytes before the end of a memory leaf
+	pathname := Word(0x2000 + 32 - 4)
+	require.NoError(t, state.Memory.SetMemoryRange(pathname, strings.NewReader(exec.ProcSelfExe)))
+	registers := state.GetRegistersRef()
+	registers[2] = arch.SysReadlinkAt
+	registers[4] = ^Word(99) // AT_FDCWD
+	registers[5] = pathname
+	registers[6] = 0x3000 // buf
+	registers[7] = 128    // bufsiz
+	memRoot := state.Memory.MerkleRoot()
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+	_, err := us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, exec.SysErrorSignal, registers[2])
+	require.Equal(t, Word(exec.MipsEINVAL), registers[7])
+	require.Equal(t, memRoot, state.Memory.MerkleRoot())
+}
+
+func TestInstrumentedState_Prctl(t *testing.T) {
+	cases := []struct {
+		option Word
+		v0     Word
+		v1     Word
+	}{
+		{option: exec.PrSetName, v0: 0, v1: 0},
+		{option: exec.PrSetVma, v0: 0, v1: 0},
+		{option: 16, v0: exec.SysErrorSignal, v1: exec.MipsEINVAL}, // PR_GET_NAME
+	}
+	for _, c := range cases {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysPrctl
+		registers[4] = c.option
+		registers[5] = 0x1000
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, c.v0, registers[2], "option %d", c.option)
+		require.Equal(t, c.v1, registers[7], "option %d", c.option)
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+	}
+}
+
+func TestInstrumentedState_RequestCountSince(t *testing.T) {
+	data := []byte("hello world")
+	state := CreateEmptyState()
+	state.PreimageKey = preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+	insns := []uint32{
+		0x34_08_00_2a, // ori $t0, $zero, 42
+		0xac_08_01_00, // sw $t0, 0x100($zero)
+		0x8c_09_01_00, // lw $t1, 0x100($zero)
+		0x00_00_00_0c, // syscall
+	}
+	for i, insn := range insns {
+		testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+	}
+	us := NewInstrumentedState(state, testutil.StaticOracle(t, data), os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+	// The compute-only segment makes no requests
+	baseline := us.preimageOracle.NumPreimageRequests()
+	for i := 0; i < 3; i++ {
+		_, err := us.Step(true)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 0, us.preimageOracle.RequestCountSince(baseline))
+
+	// Reading the preimage does
+	registers := state.GetRegistersRef()
+	registers[2] = arch.SysRead
+	registers[4] = exec.FdPreimageRead
+	registers[5] = 0x200
+	registers[6] = 4
+	_, err := us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, 1, us.preimageOracle.RequestCountSince(baseline))
+}
+
+func TestInstrumentedState_SyscallCounts(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+	var stdOutBuf bytes.Buffer
+	us := NewInstrumentedState(state, nil, &stdOutBuf, io.Discard, testutil.CreateLogger(), meta)
+	require.Empty(t, us.SyscallCounts())
+
+	for i := 0; i < 2_000_000 && !state.Exited; i++ {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+	require.True(t, state.Exited, "must complete program")
+	require.Equal(t, "hello world!\n", stdOutBuf.String())
+
+	counts := us.SyscallCounts()
+	require.Equal(t, uint64(1), counts[arch.SysExitGroup])
+	require.Equal(t, uint64(1), counts[arch.SysGetAffinity])
+	require.NotZero(t, counts[arch.SysMmap])
+	require.NotZero(t, counts[arch.SysWrite])
+	require.Equal(t, counts, us.GetDebugInfo().SyscallCounts)
+
+	// The returned map is a copy
+	counts[arch.SysWrite] = 0
+	require.NotZero(t, us.SyscallCounts()[arch.SysWrite])
+}
+
+func TestInstrumentedState_Epoll(t *testing.T) {
+	cases := []struct {
+		syscallNum Word
+		v0         Word
+	}{
+		{syscallNum: arch.SysEpollCreate1, v0: exec.FdEpoll},
+		{syscallNum: arch.SysEpollCreate, v0: exec.FdEpoll},
+		{syscallNum: arch.SysEpollCtl, v0: 0},
+		{syscallNum: arch.SysEpollPwait, v0: 0},
+		{syscallNum: arch.SysEpollWait, v0: 0},
+	}
+	for _, c := range cases {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = c.syscallNum
+		registers[4] = exec.FdEpoll
+		registers[5] = 0x1000
+		registers[6] = 1
+		registers[7] = ^Word(0) // infinite timeout
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, c.v0, registers[2], "syscall %d", c.syscallNum)
+		require.Equal(t, Word(0), registers[7], "syscall %d must not fail", c.syscallNum)
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+		require.False(t, state.Exited)
+	}
+}
+
+func TestInstrumentedState_SysShm(t *testing.T) {
+	for _, syscallNum := range []Word{arch.SysShmget, arch.SysShmat, arch.SysShmctl, arch.SysShmdt} {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = syscallNum
+		registers[4] = 0x1234
+		registers[5] = 0x2000
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, exec.SysErrorSignal, registers[2], "syscall %d", syscallNum)
+		require.Equal(t, Word(exec.MipsENOSYS), registers[7], "syscall %d", syscallNum)
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+		require.False(t, state.Exited)
+	}
+}
+
+func TestInstrumentedState_SysClockNanosleep(t *testing.T) {
+	cases := []struct {
+		name       string
+		clkid      Word
+		flags      Word
+		rem        Word
+		v0         Word
+		v1         Word
+		writtenRem bool
+	}{
+		{name: "relative, rem", clkid: exec.ClockGettimeMonotonicFlag, rem: 0x1000, writtenRem: true},
+		{name: "relative, unaligned rem", clkid: exec.ClockGettimeRealtimeFlag, rem: 0x1003, writtenRem: true},
+		{name: "relative, null rem", clkid: exec.ClockGettimeMonotonicFlag, rem: 0},
+		{name: "absolute, rem", clkid: exec.ClockGettimeMonotonicFlag, flags: exec.TimerAbstime, rem: 0x1000},
+		{name: "relative, rem in null page", clkid: exec.ClockGettimeMonotonicFlag, rem: 0xff8, v0: exec.SysErrorSignal, v1: exec.MipsEFAULT},
+		{name: "unsupported clock", clkid: 0xdead, rem: 0x1000, v0: exec.SysErrorSignal, v1: exec.MipsEINVAL},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			effAddr := c.rem & arch.AddressMask
+			state.Memory.SetWord(0x1000, 0x1111)
+			state.Memory.SetWord(0x1000+arch.WordSizeBytes, 0x2222)
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysClockNanosleep
+			registers[4] = c.clkid
+			registers[5] = c.flags
+			registers[6] = 0x2000
+			registers[7] = c.rem
+			us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+			_, err := us.Step(true)
+			require.NoError(t, err)
+			require.Equal(t, c.v0, registers[2])
+			require.Equal(t, c.v1, registers[7])
+			// The sleeping thread is not preempted
+			require.Equal(t, Word(4), state.GetPC())
+			require.Equal(t, uint64(1), state.StepsSinceLastContextSwitch)
+			if c.writtenRem {
+				require.Equal(t, Word(0), state.Memory.GetWord(effAddr))
+				require.Equal(t, Word(0), state.Memory.GetWord(effAddr+arch.WordSizeBytes))
+			} else {
+				require.Equal(t, Word(0x1111), state.Memory.GetWord(0x1000))
+				require.Equal(t, Word(0x2222), state.Memory.GetWord(0x1000+arch.WordSizeBytes))
+			}
+		})
+	}
+}
+
+func TestInstrumentedState_SysRseq(t *testing.T) {
+	cases := []struct {
+		name  string
+		flags Word
+		v0    Word
+		v1    Word
+	}{
+		{name: "register"},
+		{name: "unregister", flags: exec.RseqFlagUnregister},
+		{name: "unknown flags", flags: 0x2, v0: exec.SysErrorSignal, v1: exec.MipsEINVAL},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			state.Memory.SetWord(0x1000, 0x1111)
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysRseq
+			registers[4] = 0x1000
+			registers[5] = 32
+			registers[6] = c.flags
+			registers[7] = 0x53053053
+			root := state.Memory.MerkleRoot()
+			us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+			_, err := us.Step(true)
+			require.NoError(t, err)
+			require.Equal(t, c.v0, registers[2])
+			require.Equal(t, c.v1, registers[7])
+			require.Equal(t, Word(4), state.GetPC())
+			require.Equal(t, root, state.Memory.MerkleRoot())
+		})
+	}
+}
+
+func TestInstrumentedState_SysGetTimeOfDay(t *testing.T) {
+	cases := []struct {
+		name  string
+		step  uint64
+		tv    Word
+		tz    Word
+		secs  Word
+		usecs Word
+	}{
+		{name: "start", step: 1, tv: 0x1000, secs: 0, usecs: 0},
+		{name: "sub-second", step: 12_345_678, tv: 0x1000, secs: 1, usecs: 234_567},
+		{name: "unaligned timeval", step: 3*exec.HZ - 1, tv: 0x1003, secs: 2, usecs: 999_999},
+		{name: "with timezone", step: exec.HZ, tv: 0x1000, tz: 0x2000, secs: 1, usecs: 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			for off := Word(0); off < exec.TimezoneSize; off += arch.WordSizeBytes {
+				state.Memory.SetWord(0x2000+off, 0x1234)
+			}
+			// The step is incremented before the syscall is handled
+			state.Step = c.step - 1
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysGetTimeOfDay
+			registers[4] = c.tv
+			registers[5] = c.tz
+			us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+			_, err := us.Step(true)
+			require.NoError(t, err)
+			require.Equal(t, Word(0), registers[2])
+			require.Equal(t, Word(0), registers[7])
+			effAddr := c.tv & arch.AddressMask
+			require.Equal(t, c.secs, state.Memory.GetWord(effAddr))
+			require.Equal(t, c.usecs, state.Memory.GetWord(effAddr+arch.WordSizeBytes))
+			// The timezone is zeroed when it is given
+			for off := Word(0); off < exec.TimezoneSize; off += arch.WordSizeBytes {
+				if c.tz != 0 {
+					require.Equal(t, Word(0), state.Memory.GetWord(c.tz+off))
+				} else {
+					require.Equal(t, Word(0x1234), state.Memory.GetWord(0x2000+off))
+				}
+			}
+		})
+	}
+
+	t.Run("more than two leaves", func(t *testing.T) {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysGetTimeOfDay
+		// The timeval spans two leaves, and the timezone is in a third
+		registers[4] = 0x1000 + 32 - arch.WordSizeBytes
+		registers[5] = 0x2000
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, exec.SysErrorSignal, registers[2])
+		require.Equal(t, Word(exec.MipsEINVAL), registers[7])
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+	})
+
+	t.Run("null timeval", func(t *testing.T) {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+		registers := state.GetRegistersRef()
+		registers[2] = arch.SysGetTimeOfDay
+		memRoot := state.Memory.MerkleRoot()
+		us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+		_, err := us.Step(true)
+		require.NoError(t, err)
+		require.Equal(t, Word(0), registers[2])
+		require.Equal(t, Word(0), registers[7])
+		require.Equal(t, memRoot, state.Memory.MerkleRoot())
+	})
+}
+
+func TestValidateUserPtr(t *testing.T) {
+	require.ErrorIs(t, validateUserPtr(0, 4), errBadUserPtr)
+	require.ErrorIs(t, validateUserPtr(memory.PageSize-4, 4), errBadUserPtr)
+	require.NoError(t, validateUserPtr(memory.PageSize, 4))
+	require.NoError(t, validateUserPtr(arch.HighMemoryStart, 2*arch.WordSizeBytes))
+	// The buffer may end at the top of the address space, but not wrap around it
+	require.NoError(t, validateUserPtr(^Word(0)-3, 4))
+	require.ErrorIs(t, validateUserPtr(^Word(0)-2, 4), errBadUserPtr)
+	require.NoError(t, validateUserPtr(^Word(0), 0))
+}
+
+func TestInstrumentedState_SyscallBadPointer(t *testing.T) {
+	cases := []struct {
+		name       string
+		syscallNum Word
+		a0         Word
+		a1         Word
+	}{
+		{name: "clock_gettime, null timespec", syscallNum: arch.SysClockGetTime, a0: exec.ClockGettimeMonotonicFlag, a1: 0},
+		{name: "clock_gettime, wrapping timespec", syscallNum: arch.SysClockGetTime, a0: exec.ClockGettimeRealtimeFlag, a1: ^Word(0) - arch.WordSizeBytes},
+		{name: "gettimeofday, wrapping timeval", syscallNum: arch.SysGetTimeOfDay, a0: ^Word(0) - arch.WordSizeBytes},
+		{name: "gettimeofday, timezone in null page", syscallNum: arch.SysGetTimeOfDay, a0: 0x1000, a1: 0xff8},
+		{name: "futex wait, null addr", syscallNum: arch.SysFutex, a0: 0, a1: exec.FutexWaitPrivate},
+		{name: "futex wake, null addr", syscallNum: arch.SysFutex, a0: 0x4, a1: exec.FutexWakePrivate},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+			registers := state.GetRegistersRef()
+			registers[2] = c.syscallNum
+			registers[4] = c.a0
+			registers[5] = c.a1
+			memRoot := state.Memory.MerkleRoot()
+			pc := state.GetPC()
+			us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+
+			_, err := us.Step(true)
+			require.NoError(t, err)
+			require.Equal(t, exec.SysErrorSignal, registers[2])
+			require.Equal(t, Word(exec.MipsEFAULT), registers[7])
+			require.Equal(t, memRoot, state.Memory.MerkleRoot())
+			require.Equal(t, pc+4, state.GetPC())
+			require.Equal(t, exec.FutexEmptyAddr, state.Wakeup)
+			require.Equal(t, exec.FutexEmptyAddr, state.GetCurrentThread().FutexAddr)
+		})
+	}
+}
+
+func TestInstrumentedState_StepsPerThread(t *testing.T) {
+	state := CreateEmptyState()
+	program := []uint32{
+		0x24_02_00_00 | uint32(arch.SysSchedYield), // addiu $v0, $zero, SysSchedYield
+		0x00_00_00_0c, // syscall
+		0x10_00_ff_fd, // b 0
+		0x00_00_00_00, // nop
+	}
+	for i, insn := range program {
+		testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+	}
+	second := CreateEmptyThread()
+	second.ThreadId = 1
+	state.LeftThreadStack = append(state.LeftThreadStack, second)
+	state.NextThreadId = 2
+
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	require.Empty(t, us.StepsPerThread())
+	const steps = 1000
+	for i := 0; i < steps; i++ {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+
+	// Each thread yields after every other instruction of its loop, so the threads alternate evenly
+	counts := us.StepsPerThread()
+	require.Len(t, counts, 2)
+	require.Equal(t, uint64(steps), counts[0]+counts[1])
+	require.InDelta(t, steps/2, counts[0], float64(len(program)))
+	require.InDelta(t, steps/2, counts[1], float64(len(program)))
+
+	// The returned map is a copy
+	counts[0] = 0
+	require.NotZero(t, us.StepsPerThread()[0])
+}
+
+func TestInstrumentedState_ThreadIdAllocator(t *testing.T) {
+	clone := func(t *testing.T, us *InstrumentedState, state *State) *ThreadState {
+		parent := state.GetCurrentThread()
+		parent.Cpu.PC = 0
+		parent.Cpu.NextPC = 4
+		parent.Registers[2] = arch.SysClone
+		parent.Registers[4] = exec.ValidCloneFlags
+		parent.Registers[5] = 0x8000
+		_, err := us.Step(false)
+		require.NoError(t, err)
+		child := state.GetCurrentThread()
+		require.NotSame(t, parent, child)
+		// The parent receives the id of the child
+		require.Equal(t, child.ThreadId, parent.Registers[2])
+		return child
+	}
+
+	t.Run("default", func(t *testing.T) {
+		state := CreateEmptyState()
+		require.NoError(t, state.SetNextThreadId(5))
+		testutil.StoreInstruction(state.Memory, 0, 0x00_00_00_0c) // syscall
+		us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+
+		require.Equal(t, Word(5), clone(t, us, state).ThreadId)
+		require.Equal(t, Word(6), clone(t, us, state).ThreadId)
+		require.Equal(t, Word(7), state.NextThreadId)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, 0, 0x00_00_00_0c) // syscall
+		us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+		ids := []Word{100, 7}
+		us.SetThreadIdAllocator(func(s *State) Word {
+			require.Same(t, state, s)
+			id := ids[0]
+			ids = ids[1:]
+			return id
+		})
+
+		require.Equal(t, Word(100), clone(t, us, state).ThreadId)
+		require.Equal(t, Word(101), state.NextThreadId)
+		// Ids below NextThreadId leave it unchanged
+		require.Equal(t, Word(7), clone(t, us, state).ThreadId)
+		require.Equal(t, Word(101), state.NextThreadId)
+		require.Empty(t, ids)
+
+		// The resulting state remains valid
+		data, err := state.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, new(State).UnmarshalBinary(data))
+	})
+
+	t.Run("id in use", func(t *testing.T) {
+		state := CreateEmptyState()
+		testutil.StoreInstruction(state.Memory, 0, 0x00_00_00_0c) // syscall
+		us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+		us.SetThreadIdAllocator(func(s *State) Word {
+			return s.GetCurrentThread().ThreadId
+		})
+		parent := state.GetCurrentThread()
+		parent.Registers[2] = arch.SysClone
+		parent.Registers[4] = exec.ValidCloneFlags
+		parent.Registers[5] = 0x8000
+
+		_, err := us.Step(false)
+		require.ErrorIs(t, err, ErrThreadIdInUse)
+		require.Equal(t, 1, state.ThreadCount())
+		require.Equal(t, Word(1), state.NextThreadId)
+	})
+}
+
+func TestInstrumentedState_MaxThreads(t *testing.T) {
+	state := CreateEmptyState()
+	testutil.StoreInstruction(state.Memory, 0, 0x00_00_00_0c) // syscall
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	require.Zero(t, us.maxThreads)
+	us.SetMaxThreads(4)
+
+	clone := func() *ThreadState {
+		thread := state.GetCurrentThread()
+		thread.Cpu.PC = 0
+		thread.Cpu.NextPC = 4
+		thread.Registers[2] = arch.SysClone
+		thread.Registers[4] = exec.ValidCloneFlags
+		thread.Registers[5] = 0x8000
+		thread.Registers[7] = 0
+		_, err := us.Step(false)
+		require.NoError(t, err)
+		return thread
+	}
+	for i := 1; i < 4; i++ {
+		clone()
+		require.Equal(t, i+1, state.ThreadCount())
+	}
+
+	// The next clone fails without creating a thread
+	nextThreadId := state.NextThreadId
+	step := state.Step
+	thread := clone()
+	require.Same(t, thread, state.GetCurrentThread())
+	require.Equal(t, 4, state.ThreadCount())
+	require.Equal(t, nextThreadId, state.NextThreadId)
+	require.Equal(t, step+1, state.Step)
+	require.Equal(t, Word(4), thread.Cpu.PC)
+	require.Equal(t, Word(8), thread.Cpu.NextPC)
+	require.Equal(t, exec.SysErrorSignal, thread.Registers[2])
+	require.Equal(t, Word(exec.MipsEAGAIN), thread.Registers[7])
+
+	// Lifting the limit allows further clones
+	us.SetMaxThreads(0)
+	clone()
+	require.Equal(t, 5, state.ThreadCount())
+}
+
+func TestInstrumentedState_LastStepAllocatedPages(t *testing.T) {
+	state := CreateEmptyState()
+	program := []uint32{
+		0xac_a0_00_00, // sw $zero, 0($a1)
+		0xac_a0_00_04, // sw $zero, 4($a1)
+	}
+	for i, insn := range program {
+		testutil.StoreInstruction(state.Memory, state.GetPC()+Word(4*i), insn)
+	}
+	state.GetRegistersRef()[5] = 0x10_0000
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	require.Zero(t, us.LastStepAllocatedPages())
+	pageCount := state.Memory.PageCount()
+
+	// The first store touches a fresh page
+	_, err := us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, 1, us.LastStepAllocatedPages())
+	require.Equal(t, pageCount+1, state.Memory.PageCount())
+
+	// The second store is to the same page
+	_, err = us.Step(true)
+	require.NoError(t, err)
+	require.Zero(t, us.LastStepAllocatedPages())
+}
+
+func TestInstrumentedState_MaxPreimageOffset(t *testing.T) {
+	data := []byte("hello world")
+	key := preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+	state := CreateEmptyState()
+	state.PreimageKey = key
+	registers := state.GetRegistersRef()
+	registers[4] = exec.FdPreimageRead
+	registers[5] = 0x1000
+	registers[6] = 4
+	// Stream the length-prefixed preimage in 4 byte chunks, reloading the syscall number clobbered by each read
+	reads := (8 + len(data) + 3) / 4
+	for i := 0; i < reads; i++ {
+		pc := state.GetPC() + arch.Word(8*i)
+		testutil.StoreInstruction(state.Memory, pc, 0x24_02_00_00|uint32(arch.SysRead)) // addiu $v0, $zero, SysRead
+		testutil.StoreInstruction(state.Memory, pc+4, 0x00_00_00_0c)                    // syscall
+	}
+	us := NewInstrumentedState(state, testutil.StaticOracle(t, data), io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	require.Zero(t, us.preimageOracle.MaxOffsetForKey(key))
+
+	for i := 0; i < reads; i++ {
+		for j := 0; j < 2; j++ {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		require.Equal(t, state.PreimageOffset, us.preimageOracle.MaxOffsetForKey(key))
+	}
+	require.Equal(t, arch.Word(8+len(data)), us.preimageOracle.MaxOffsetForKey(key), "must reach the end of the preimage")
+	require.Zero(t, us.preimageOracle.MaxOffsetForKey([32]byte{0x01}))
+}
+
+func TestInstrumentedState_MultithreadedDeterminism(t *testing.T) {
+	type result struct {
+		stateHash common.Hash
+		memRoot   common.Hash
+		stdOut    string
+	}
+	requireDeterministic := func(t *testing.T, run func() result) result {
+		first := run()
+		for i := 0; i < 2; i++ {
+			require.Equal(t, first, run(), "run %d diverged from the first run", i+1)
+		}
+		return first
+	}
+
+	t.Run("clone producer and consumer", func(t *testing.T) {
+		const stackTop = 0x8000
+		run := func() result {
+			state := CreateEmptyState()
+			program := []uint32{
+				0x00_00_00_0c, // syscall: clone, v0 = 0 in the child
+				0x00_02_40_c0, // sll $t0, $v0, 3: a distinct slot per thread
+				0x24_09_00_32, // addiu $t1, $zero, 50
+				0xad_09_10_00, // loop: sw $t1, 0x1000($t0)
+				0xac_08_20_00, // sw $t0, 0x2000($zero): last writer wins
+				0x24_02_00_00 | uint32(arch.SysSchedYield), // addiu $v0, $zero, SysSchedYield
+				0x00_00_00_0c, // syscall
+				0x25_29_ff_ff, // addiu $t1, $t1, -1
+				0x15_20_ff_fa, // bne $t1, $zero, loop
+				0x00_00_00_00, // nop
+				0x24_02_00_00 | uint32(arch.SysExitGroup), // addiu $v0, $zero, SysExitGroup
+				0x24_04_00_00, // addiu $a0, $zero, 0
+				0x00_00_00_0c, // syscall
+			}
+			for i, insn := range program {
+				testutil.StoreInstruction(state.Memory, state.GetPC()+arch.Word(4*i), insn)
+			}
+			registers := state.GetRegistersRef()
+			registers[2] = arch.SysClone
+			registers[4] = exec.ValidCloneFlags
+			registers[5] = stackTop
+
+			us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+			for i := 0; i < 10_000 && !state.Exited; i++ {
+				_, err := us.Step(false)
+				require.NoError(t, err)
+			}
+			require.True(t, state.Exited, "must complete program")
+			require.NotZero(t, state.Memory.GetWord(0x1000), "child must have written")
+			require.NotZero(t, state.Memory.GetWord(0x1008), "parent must have written")
+
+			_, stateHash := state.EncodeWitness()
+			return result{stateHash: stateHash, memRoot: state.Memory.MerkleRoot()}
+		}
+		requireDeterministic(t, run)
+	})
+
+	t.Run("mt-general", func(t *testing.T) {
+		if os.Getenv("SKIP_SLOW_TESTS") == "true" {
+			t.Skip("Skipping slow test because SKIP_SLOW_TESTS is enabled")
+		}
+		run := func() result {
+			state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("mt-general"), CreateInitialState, false)
+			var stdOut bytes.Buffer
+			us := NewInstrumentedState(state, testutil.StaticOracle(t, []byte{}), &stdOut, io.Discard, testutil.CreateLogger(), meta)
+			for i := 0; i < 5_000_000 && !state.Exited; i++ {
+				_, err := us.Step(false)
+				require.NoError(t, err)
+			}
+			require.True(t, state.Exited, "must complete program")
+			require.Equal(t, uint8(0), state.ExitCode, "exit with 0")
+
+			_, stateHash := state.EncodeWitness()
+			return result{stateHash: stateHash, memRoot: state.Memory.MerkleRoot(), stdOut: stdOut.String()}
+		}
+		out := requireDeterministic(t, run)
+		require.Contains(t, out.stdOut, "channels result: 1234")
+	})
+}
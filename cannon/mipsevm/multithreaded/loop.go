@@ -0,0 +1,67 @@
+package multithreaded
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrLikelyInfiniteLoop is returned by Step when loop detection is enabled and the active thread appears to be stuck.
+var ErrLikelyInfiniteLoop = errors.New("likely infinite loop")
+
+// loopDetector counts how often each thread revisits an identical context. A thread that repeatedly reaches the
+// same pc with the same registers, while memory is unchanged, cannot make progress on its own.
+// This is a heuristic: another thread may still be about to change the memory the stuck thread depends on.
+type loopDetector struct {
+	window    int
+	threshold int
+	visits    map[ThreadState]loopVisit
+}
+
+type loopVisit struct {
+	count   int
+	memRoot common.Hash
+}
+
+// SetLoopDetection enables detecting tight infinite loops. Step fails with ErrLikelyInfiniteLoop once the active
+// thread revisits the same pc with unchanged registers and memory threshold times. Up to window distinct thread
+// contexts are tracked, after which tracking restarts. A zero window or threshold disables detection, the default.
+func (m *InstrumentedState) SetLoopDetection(window int, threshold int) {
+	if window == 0 || threshold == 0 {
+		m.loopDetector = nil
+		return
+	}
+	m.loopDetector = &loopDetector{
+		window:    window,
+		threshold: threshold,
+		visits:    make(map[ThreadState]loopVisit),
+	}
+}
+
+func (m *InstrumentedState) checkLoop() error {
+	if m.state.Exited {
+		return nil
+	}
+	d := m.loopDetector
+	thread := *m.state.GetCurrentThread()
+	visit, ok := d.visits[thread]
+	if !ok {
+		if len(d.visits) >= d.window {
+			clear(d.visits)
+		}
+		// The memory root is only computed on revisits, which are rare outside of loops
+		d.visits[thread] = loopVisit{}
+		return nil
+	}
+	if memRoot := m.state.Memory.MerkleRoot(); memRoot != visit.memRoot {
+		// Memory changed since the last visit, or was not yet known: count revisits from here
+		visit = loopVisit{memRoot: memRoot}
+	}
+	visit.count++
+	d.visits[thread] = visit
+	if visit.count >= d.threshold {
+		return fmt.Errorf("%w: thread %d reached pc 0x%x %d times without changes", ErrLikelyInfiniteLoop, thread.ThreadId, thread.Cpu.PC, visit.count)
+	}
+	return nil
+}
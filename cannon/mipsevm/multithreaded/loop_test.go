@@ -0,0 +1,68 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestInstrumentedState_LoopDetection(t *testing.T) {
+	newLoop := func(insns ...uint32) *InstrumentedState {
+		state := CreateEmptyState()
+		for i, insn := range insns {
+			testutil.StoreInstruction(state.Memory, state.GetPC()+Word(i*4), insn)
+		}
+		return NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	}
+	run := func(us *InstrumentedState, steps int) error {
+		for i := 0; i < steps; i++ {
+			if _, err := us.Step(false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	t.Run("b .", func(t *testing.T) {
+		us := newLoop(
+			0x10_00_ff_ff, // beq $zero, $zero, -1
+			0x00_00_00_00, // nop
+		)
+		us.SetLoopDetection(64, 10)
+		err := run(us, 100)
+		require.ErrorIs(t, err, ErrLikelyInfiniteLoop)
+		// The delay slot is seen once, then revisited 10 times
+		require.Equal(t, uint64(2*10+1), us.state.Step)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		us := newLoop(
+			0x10_00_ff_ff, // beq $zero, $zero, -1
+			0x00_00_00_00, // nop
+		)
+		require.NoError(t, run(us, 1000))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		us := newLoop(
+			0x10_00_ff_ff, // beq $zero, $zero, -1
+			0x00_00_00_00, // nop
+		)
+		us.SetLoopDetection(64, 10)
+		us.SetLoopDetection(0, 0)
+		require.NoError(t, run(us, 1000))
+	})
+
+	t.Run("register progress", func(t *testing.T) {
+		us := newLoop(
+			0x25_08_00_01, // addiu $t0, $t0, 1
+			0x10_00_ff_fe, // beq $zero, $zero, -2
+			0x00_00_00_00, // nop
+		)
+		us.SetLoopDetection(64, 10)
+		require.NoError(t, run(us, 1000))
+	})
+}
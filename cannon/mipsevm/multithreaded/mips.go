@@ -2,7 +2,10 @@ package multithreaded
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -11,25 +14,69 @@ import (
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/register"
 )
 
 type Word = arch.Word
 
+// errBadUserPtr is returned by validateUserPtr for guest buffers that Linux would fault on.
+var errBadUserPtr = errors.New("bad user pointer")
+
+// validateUserPtr checks that the size bytes at addr are a plausible guest buffer, so that a syscall given a wild
+// pointer fails with EFAULT instead of accessing a surprising page. The null page is never mapped, and a buffer
+// must not wrap around the address space.
+func validateUserPtr(addr, size Word) error {
+	if addr < memory.PageSize || (size != 0 && addr > ^Word(0)-(size-1)) {
+		return errBadUserPtr
+	}
+	return nil
+}
+
+// futexBitset returns the bitset of a futex bitset operation, and the error for it, or 0 if the bitset is valid.
+// The bitset is the 6th syscall argument, which MIPS32 passes on the stack. That stack word is read with the second
+// memory proof.
+func (m *InstrumentedState) futexBitset(thread *ThreadState) (uint32, Word) {
+	var bitset Word
+	if arch.IsMips32 {
+		sp := thread.Registers[register.RegSP]
+		if validateUserPtr(sp, register.SyscallParam6StackOffset+4) != nil || sp&arch.ExtMask != 0 {
+			return 0, exec.MipsEFAULT
+		}
+		addr := sp + register.SyscallParam6StackOffset
+		m.memoryTracker.TrackDisjointMemAccess2(addr)
+		bitset = m.state.Memory.GetWord(addr)
+	} else {
+		bitset = thread.Registers[register.RegSyscallParam6]
+	}
+	if uint32(bitset) == 0 {
+		return 0, exec.MipsEINVAL
+	}
+	return uint32(bitset), 0
+}
+
 func (m *InstrumentedState) handleSyscall() error {
 	thread := m.state.GetCurrentThread()
 
 	syscallNum, a0, a1, a2, a3 := exec.GetSyscallArgs(m.state.GetRegistersRef())
 	v0 := Word(0)
 	v1 := Word(0)
+	m.syscallCounts[uint64(syscallNum)]++
 
 	//fmt.Printf("syscall: %d\n", syscallNum)
 	switch syscallNum {
 	case arch.SysMmap:
-		var newHeap Word
-		v0, v1, newHeap = exec.HandleSysMmap(a0, a1, m.state.Heap)
-		m.state.Heap = newHeap
+		if a0&memory.PageAddrMask != 0 {
+			// Fixed mappings must be page-aligned; the heap is left untouched
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+		} else {
+			var newHeap Word
+			v0, v1, newHeap = exec.HandleSysMmap(a0, a1, m.state.Heap)
+			m.checkHeapWatermark(m.state.Heap, newHeap)
+			m.state.Heap = newHeap
+		}
 	case arch.SysBrk:
 		v0 = program.PROGRAM_BREAK
 	case arch.SysClone: // clone
@@ -39,16 +86,26 @@ func (m *InstrumentedState) handleSyscall() error {
 			m.state.ExitCode = mipsevm.VMStatusPanic
 			return nil
 		}
+		if m.maxThreads > 0 && m.state.ThreadCount() >= m.maxThreads {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEAGAIN
+			break
+		}
 
-		v0 = m.state.NextThreadId
+		threadId, err := m.allocateThreadId()
+		if err != nil {
+			return err
+		}
+		v0 = threadId
 		v1 = 0
 		newThread := &ThreadState{
-			ThreadId:         m.state.NextThreadId,
+			ThreadId:         v0,
 			ExitCode:         0,
 			Exited:           false,
 			FutexAddr:        exec.FutexEmptyAddr,
 			FutexVal:         0,
 			FutexTimeoutStep: 0,
+			FutexBitset:      0,
 			Cpu: mipsevm.CpuScalars{
 				PC:     thread.Cpu.NextPC,
 				NextPC: thread.Cpu.NextPC + 4,
@@ -62,7 +119,9 @@ func (m *InstrumentedState) handleSyscall() error {
 		// the child will perceive a 0 value as returned value instead, and no error
 		newThread.Registers[register.RegSyscallRet1] = 0
 		newThread.Registers[register.RegSyscallErrno] = 0
-		m.state.NextThreadId++
+		if v0 >= m.state.NextThreadId {
+			m.state.NextThreadId = v0 + 1
+		}
 
 		// Preempt this thread for the new one. But not before updating PCs
 		stackCaller := thread.Cpu.PC
@@ -78,6 +137,14 @@ func (m *InstrumentedState) handleSyscall() error {
 		m.state.ExitCode = uint8(a0)
 		return nil
 	case arch.SysRead:
+		if a0 == exec.FdPreimageRead {
+			if err := m.preimageOracle.CheckKeyType(m.state.PreimageKey); err != nil {
+				return err
+			}
+			if err := m.preimageOracle.CheckOffset(m.state.PreimageKey, m.state.PreimageOffset); err != nil {
+				return err
+			}
+		}
 		var newPreimageOffset Word
 		var memUpdated bool
 		var memAddr Word
@@ -85,6 +152,7 @@ func (m *InstrumentedState) handleSyscall() error {
 		m.state.PreimageOffset = newPreimageOffset
 		if memUpdated {
 			m.handleMemoryUpdate(memAddr)
+			m.preimageOracle.RecordReadOffset(m.state.PreimageKey, newPreimageOffset)
 		}
 	case arch.SysWrite:
 		var newLastHint hexutil.Bytes
@@ -108,11 +176,28 @@ func (m *InstrumentedState) handleSyscall() error {
 		}
 		return nil
 	case arch.SysFutex:
-		// args: a0 = addr, a1 = op, a2 = val, a3 = timeout
+		// args: a0 = addr, a1 = op, a2 = val, a3 = timeout, val3 = bitset
 		effAddr := a0 & arch.AddressMask
 		switch a1 {
-		case exec.FutexWaitPrivate:
-			m.memoryTracker.TrackMemAccess(effAddr)
+		case exec.FutexWaitPrivate, exec.FutexWaitBitsetPrivate:
+			validAddr := validateUserPtr(a0, 4) == nil
+			if validAddr {
+				m.memoryTracker.TrackMemAccess(effAddr)
+			}
+			bitset := uint32(exec.FutexBitsetMatchAny)
+			if a1 == exec.FutexWaitBitsetPrivate {
+				var errno Word
+				if bitset, errno = m.futexBitset(thread); errno != 0 {
+					v0 = exec.SysErrorSignal
+					v1 = errno
+					break
+				}
+			}
+			if !validAddr {
+				v0 = exec.SysErrorSignal
+				v1 = exec.MipsEFAULT
+				break
+			}
 			mem := m.state.Memory.GetWord(effAddr)
 			if mem != a2 {
 				v0 = exec.SysErrorSignal
@@ -120,6 +205,7 @@ func (m *InstrumentedState) handleSyscall() error {
 			} else {
 				thread.FutexAddr = effAddr
 				thread.FutexVal = a2
+				thread.FutexBitset = bitset
 				if a3 == 0 {
 					thread.FutexTimeoutStep = exec.FutexNoTimeout
 				} else {
@@ -128,10 +214,25 @@ func (m *InstrumentedState) handleSyscall() error {
 				// Leave cpu scalars as-is. This instruction will be completed by `onWaitComplete`
 				return nil
 			}
-		case exec.FutexWakePrivate:
+		case exec.FutexWakePrivate, exec.FutexWakeBitsetPrivate:
+			bitset := uint32(exec.FutexBitsetMatchAny)
+			if a1 == exec.FutexWakeBitsetPrivate {
+				var errno Word
+				if bitset, errno = m.futexBitset(thread); errno != 0 {
+					v0 = exec.SysErrorSignal
+					v1 = errno
+					break
+				}
+			}
+			if validateUserPtr(a0, 4) != nil {
+				v0 = exec.SysErrorSignal
+				v1 = exec.MipsEFAULT
+				break
+			}
 			// Trigger thread traversal starting from the left stack until we find one waiting on the wakeup
-			// address
+			// address with an intersecting bitset
 			m.state.Wakeup = effAddr
+			m.state.WakeupBitset = bitset
 			// Don't indicate to the program that we've woken up a waiting thread, as there are no guarantees.
 			// The woken up thread should indicate this in userspace.
 			v0 = 0
@@ -153,9 +254,82 @@ func (m *InstrumentedState) handleSyscall() error {
 	case arch.SysOpen:
 		v0 = exec.SysErrorSignal
 		v1 = exec.MipsEBADF
+	case arch.SysReadlinkAt:
+		// args: a0 = dirfd, a1 = pathname, a2 = buf, a3 = bufsiz
+		// There is no filesystem, so only /proc/self/exe resolves, to a fixed path. A step proves one memory leaf
+		// for the path and one word for the result: a path spanning two leaves cannot be read, and fails with
+		// EINVAL whatever it holds, and the result is truncated at the end of the word holding buf.
+		pathLen := Word(len(exec.ProcSelfExe))
+		n := min(a3, Word(len(exec.ExePath)), arch.WordSizeBytes-a2&arch.ExtMask)
+		switch {
+		case a3 == 0:
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+		case validateUserPtr(a1, pathLen) != nil:
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEFAULT
+		case a1&31+pathLen > 32:
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+		default:
+			// The buffer is proven whether or not the path matches, so that the proven addresses only depend on
+			// the registers
+			m.memoryTracker.TrackMemAccess(a1 & arch.AddressMask)
+			bufValid := validateUserPtr(a2, n) == nil
+			if bufValid {
+				m.memoryTracker.TrackDisjointMemAccess2(a2 & arch.AddressMask)
+			}
+			var path [len(exec.ProcSelfExe)]byte
+			_, _ = io.ReadFull(m.state.Memory.ReadMemoryRange(a1, pathLen), path[:])
+			if string(path[:]) != exec.ProcSelfExe {
+				v0 = exec.SysErrorSignal
+				v1 = exec.MipsENOENT
+			} else if !bufValid {
+				v0 = exec.SysErrorSignal
+				v1 = exec.MipsEFAULT
+			} else {
+				effAddr := a2 & arch.AddressMask
+				mem := m.state.Memory.GetWord(effAddr)
+				for i := Word(0); i < n; i++ {
+					mem = exec.UpdateSubWord(a2+i, mem, 1, Word(exec.ExePath[i]))
+				}
+				m.state.Memory.SetWord(effAddr, mem)
+				m.handleMemoryUpdate(effAddr)
+				v0, v1 = n, 0
+			}
+		}
+	case arch.SysFaccessat:
+		// args: a0 = dirfd, a1 = pathname, a2 = mode, a3 = flags
+		// There is no filesystem, so no path exists
+		v0 = exec.SysErrorSignal
+		v1 = exec.MipsENOENT
+	case arch.SysPrctl:
+		// args: a0 = option
+		// Options that only attach names are accepted without effect. Others would need real behavior.
+		switch a0 {
+		case exec.PrSetName, exec.PrSetVma:
+			v0, v1 = 0, 0
+		default:
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+		}
+	case arch.SysSocket, arch.SysConnect, arch.SysAccept, arch.SysBind, arch.SysListen, arch.SysSendto, arch.SysRecvfrom:
+		// There is no network available to the VM
+		v0 = exec.SysErrorSignal
+		v1 = exec.MipsEAFNOSUPPORT
+	case arch.SysShmget, arch.SysShmat, arch.SysShmctl, arch.SysShmdt:
+		// There is a single address space, so SysV shared memory is not modeled
+		v0 = exec.SysErrorSignal
+		v1 = exec.MipsENOSYS
 	case arch.SysClockGetTime:
 		switch a0 {
 		case exec.ClockGettimeRealtimeFlag, exec.ClockGettimeMonotonicFlag:
+			// a1 = timespec, two words
+			if validateUserPtr(a1, 2*arch.WordSizeBytes) != nil {
+				v0 = exec.SysErrorSignal
+				v1 = exec.MipsEFAULT
+				break
+			}
 			v0, v1 = 0, 0
 			var secs, nsecs Word
 			if a0 == exec.ClockGettimeMonotonicFlag {
@@ -176,12 +350,106 @@ func (m *InstrumentedState) handleSyscall() error {
 			v0 = exec.SysErrorSignal
 			v1 = exec.MipsEINVAL
 		}
+	case arch.SysClockNanosleep:
+		// args: a0 = clockid, a1 = flags, a2 = request, a3 = rem
+		// The requested sleep elapses immediately. A relative sleep reports no remaining time in rem, if given.
+		switch {
+		case a0 != exec.ClockGettimeRealtimeFlag && a0 != exec.ClockGettimeMonotonicFlag:
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+		case a3 == 0 || a1&exec.TimerAbstime != 0:
+			v0, v1 = 0, 0
+		case validateUserPtr(a3, 2*arch.WordSizeBytes) != nil:
+			// rem = timespec, two words
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEFAULT
+		default:
+			v0, v1 = 0, 0
+			effAddr := a3 & arch.AddressMask
+			m.memoryTracker.TrackMemAccess(effAddr)
+			m.state.Memory.SetWord(effAddr, 0)
+			m.handleMemoryUpdate(effAddr)
+			m.memoryTracker.TrackMemAccess2(effAddr + arch.WordSizeBytes)
+			m.state.Memory.SetWord(effAddr+arch.WordSizeBytes, 0)
+			m.handleMemoryUpdate(effAddr + arch.WordSizeBytes)
+		}
+	case arch.SysGetTimeOfDay:
+		// args: a0 = tv, a1 = tz
+		// The time of day is derived from the step count, like the monotonic clock. The obsolete timezone is zeroed.
+		// A step can only prove two memory leaves, so the timeval and timezone fail with EINVAL if they span more.
+		// tv = timeval, two words
+		if a0 != 0 && validateUserPtr(a0, 2*arch.WordSizeBytes) != nil {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEFAULT
+			break
+		}
+		// tz = timezone, two 32-bit ints
+		if a1 != 0 && validateUserPtr(a1, exec.TimezoneSize) != nil {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEFAULT
+			break
+		}
+		var writes []wordWrite
+		if a0 != 0 {
+			effAddr := a0 & arch.AddressMask
+			secs := Word(m.state.Step / exec.HZ)
+			usecs := Word((m.state.Step % exec.HZ) / (exec.HZ / 1_000_000))
+			writes = append(writes, wordWrite{effAddr, secs}, wordWrite{effAddr + arch.WordSizeBytes, usecs})
+		}
+		if a1 != 0 {
+			effAddr := a1 & arch.AddressMask
+			for off := Word(0); off < exec.TimezoneSize; off += arch.WordSizeBytes {
+				writes = append(writes, wordWrite{effAddr + off, 0})
+			}
+		}
+		if !m.writeWords(writes) {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+			break
+		}
+		v0, v1 = 0, 0
+	case arch.SysMunmap:
+		// args: a0 = addr, a1 = len
+		// A block whose size is a power of two, and that is aligned to its size, is a subtree of the memory merkle
+		// tree that a single memory proof can zero. Such a block is zeroed and its pages freed, and the heap shrinks
+		// if the block is at its top. Other ranges cannot be zeroed in a single step, and fail with EINVAL.
+		// The unmapped range extends to the end of the last page.
+		size := (a1 + memory.PageAddrMask) &^ memory.PageAddrMask
+		if a0&memory.PageAddrMask != 0 || a1 == 0 || a0 > ^Word(0)-(a1-1) || size == 0 || size&(size-1) != 0 || a0&(size-1) != 0 {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+			break
+		}
+		v0, v1 = 0, 0
+		m.memoryTracker.TrackMemZero(a0, size)
+		m.state.Memory.Zero(a0, size)
+		if m.textSegments != nil {
+			m.checkTextWrite(a0, size)
+		}
+		if (m.state.LLAddress&arch.AddressMask)-a0 < size {
+			// Reserved address was zeroed, clear the reservation
+			m.clearLLMemoryReservation()
+		}
+		if a0 < m.state.Heap && m.state.Heap-a0 == size {
+			m.state.Heap = a0
+		}
+	case arch.SysRseq:
+		// args: a0 = rseq area, a1 = rseq_len, a2 = flags, a3 = signature
+		// Registering and unregistering succeed, but the rseq area is ignored. It is never updated, so its cpu id
+		// stays uninitialized and critical sections are never aborted.
+		if a2&^exec.RseqFlagUnregister != 0 {
+			v0 = exec.SysErrorSignal
+			v1 = exec.MipsEINVAL
+			break
+		}
+		v0, v1 = 0, 0
 	case arch.SysGetpid:
 		v0 = 0
 		v1 = 0
-	case arch.SysMunmap:
 	case arch.SysGetAffinity:
 	case arch.SysMadvise:
+		// Advice is only a hint, so every advice value, including unknown ones, succeeds without effect; none is
+		// rejected with EINVAL. In particular MADV_DONTNEED and MADV_FREE do not zero the range as Linux would.
 	case arch.SysRtSigprocmask:
 	case arch.SysSigaltstack:
 	case arch.SysRtSigaction:
@@ -192,24 +460,45 @@ func (m *InstrumentedState) handleSyscall() error {
 	case arch.SysFstat:
 	case arch.SysOpenAt:
 	case arch.SysReadlink:
-	case arch.SysReadlinkAt:
 	case arch.SysIoctl:
-	case arch.SysEpollCreate1:
 	case arch.SysPipe2:
-	case arch.SysEpollCtl:
-	case arch.SysEpollPwait:
 	case arch.SysGetRandom:
 	case arch.SysUname:
 	case arch.SysGetuid:
 	case arch.SysGetgid:
+	case arch.SysGeteuid:
+	case arch.SysGetegid:
 	case arch.SysMinCore:
 	case arch.SysTgkill:
+		// args: a0 = tgid, a1 = tid, a2 = sig
+		// An aborting guest is treated as a crash, which maps to the panic VM status. Other signals are ignored.
+		if a2 == exec.SigAbrt {
+			m.state.Exited = true
+			m.state.ExitCode = mipsevm.VMStatusPanic
+			return nil
+		}
 	case arch.SysSetITimer:
 	case arch.SysTimerCreate:
 	case arch.SysTimerSetTime:
 	case arch.SysTimerDelete:
 	case arch.SysGetRLimit:
 	case arch.SysLseek:
+	case arch.SysPoll, arch.SysPpoll:
+		// No fd can become ready, so the poll times out immediately with no events. The timeout is not waited for.
+	case arch.SysEpollCreate1, arch.SysEpollCreate:
+		// There is no I/O to wait for, so the Go netpoller is kept inert with a fake epoll instance
+		v0 = exec.FdEpoll
+		v1 = 0
+	case arch.SysEpollCtl:
+		// Registrations are accepted, but no fd ever becomes ready
+		v0 = 0
+		v1 = 0
+	case arch.SysEpollPwait, arch.SysEpollWait:
+		// No events are pending, so the wait times out immediately. The timeout is not waited for.
+		v0 = 0
+		v1 = 0
+	case arch.SysSetRobustList:
+		// Robust futex cleanup on thread exit is not modeled, so the list is ignored
 	default:
 		// These syscalls have the same values on 64-bit. So we use if-stmts here to avoid "duplicate case" compiler error for the cannon64 build
 		if arch.IsMips32 && syscallNum == arch.SysFstat64 || syscallNum == arch.SysStat64 || syscallNum == arch.SysLlseek {
@@ -248,13 +537,15 @@ func (m *InstrumentedState) doMipsStep() error {
 	m.state.Step += 1
 	thread := m.state.GetCurrentThread()
 
-	// During wakeup traversal, search for the first thread blocked on the wakeup address.
+	// During wakeup traversal, search for the first thread blocked on the wakeup address, with a bitset that
+	// intersects the wakeup bitset.
 	// Don't allow regular execution until we have found such a thread or else we have visited all threads.
 	if m.state.Wakeup != exec.FutexEmptyAddr {
 		// We are currently performing a wakeup traversal
-		if m.state.Wakeup == thread.FutexAddr {
+		if m.state.Wakeup == thread.FutexAddr && m.state.WakeupBitset&thread.FutexBitset != 0 {
 			// We found a target thread, resume normal execution and process this thread
 			m.state.Wakeup = exec.FutexEmptyAddr
+			m.state.WakeupBitset = 0
 		} else {
 			// This is not the thread we're looking for, move on
 			traversingRight := m.state.TraverseRight
@@ -263,6 +554,7 @@ func (m *InstrumentedState) doMipsStep() error {
 				// We started the wakeup traversal walking left and we've now walked all the way right
 				// We have therefore visited all threads and can resume normal thread execution
 				m.state.Wakeup = exec.FutexEmptyAddr
+				m.state.WakeupBitset = 0
 			}
 		}
 		return nil
@@ -346,12 +638,55 @@ func (m *InstrumentedState) doMipsStep() error {
 }
 
 func (m *InstrumentedState) handleMemoryUpdate(effMemAddr Word) {
+	if m.textSegments != nil {
+		m.checkTextWrite(effMemAddr, arch.WordSizeBytes)
+	}
 	if effMemAddr == (arch.AddressMask & m.state.LLAddress) {
 		// Reserved address was modified, clear the reservation
 		m.clearLLMemoryReservation()
 	}
 }
 
+// wordWrite is a write of a word to memory.
+type wordWrite struct {
+	addr Word
+	val  Word
+}
+
+// writeWords applies the writes, in order, when they lie in at most two memory leaves. The leaf of the first write
+// is proven with the first memory proof, and the other leaf with the second. It returns false, without writing, if
+// the writes span more than two leaves.
+func (m *InstrumentedState) writeWords(writes []wordWrite) bool {
+	if len(writes) == 0 {
+		return true
+	}
+	leaves := []Word{writes[0].addr &^ 31}
+	for _, w := range writes {
+		if leaf := w.addr &^ 31; !slices.Contains(leaves, leaf) {
+			if len(leaves) == 2 {
+				return false
+			}
+			leaves = append(leaves, leaf)
+		}
+	}
+	for i, leaf := range leaves {
+		if i == 0 {
+			m.memoryTracker.TrackMemAccess(writes[0].addr)
+		} else {
+			// The second proof is taken after the writes to the first leaf
+			m.memoryTracker.TrackDisjointMemAccess2(leaf)
+		}
+		for _, w := range writes {
+			if w.addr&^31 != leaf {
+				continue
+			}
+			m.state.Memory.SetWord(w.addr, w.val)
+			m.handleMemoryUpdate(w.addr)
+		}
+	}
+	return true
+}
+
 func (m *InstrumentedState) clearLLMemoryReservation() {
 	m.state.LLReservationStatus = LLStatusNone
 	m.state.LLAddress = 0
@@ -410,6 +745,7 @@ func (m *InstrumentedState) onWaitComplete(thread *ThreadState, isTimedOut bool)
 	thread.FutexAddr = exec.FutexEmptyAddr
 	thread.FutexVal = 0
 	thread.FutexTimeoutStep = 0
+	thread.FutexBitset = 0
 
 	// Complete the FUTEX_WAIT syscall
 	v0 := Word(0)
@@ -0,0 +1,42 @@
+package multithreaded
+
+import (
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+)
+
+// StepToNextSyscall steps m until the next step executes a syscall, and returns the syscall number and argument
+// registers without executing it. If the next step already executes a syscall, no step is taken. A syscall at the PC
+// of a thread that the next step only schedules, such as a thread waiting on a futex, is not stopped at.
+// An error is returned if no syscall is reached within maxSteps steps, or if the VM exits first.
+func (m *InstrumentedState) StepToNextSyscall(maxSteps uint64) (syscallNum Word, args [4]Word, err error) {
+	for i := uint64(0); ; i++ {
+		if m.state.Exited {
+			return 0, args, fmt.Errorf("VM exited after %d steps without reaching a syscall", i)
+		}
+		thread := m.state.GetCurrentThread()
+		if m.executesNextInstruction(thread) {
+			if _, opcode, fun := exec.GetInstructionDetails(thread.Cpu.PC, m.state.Memory); opcode == 0 && fun == 0xC {
+				syscallNum, args[0], args[1], args[2], args[3] = exec.GetSyscallArgs(&thread.Registers)
+				return syscallNum, args, nil
+			}
+		}
+		if i == maxSteps {
+			return 0, args, fmt.Errorf("no syscall reached after %d steps", i)
+		}
+		if _, err := m.Step(false); err != nil {
+			return 0, args, err
+		}
+	}
+}
+
+// executesNextInstruction returns true if the next step executes the instruction at the PC of the active thread.
+// It mirrors the scheduling checks of doMipsStep: the step does not execute an instruction during a wakeup traversal,
+// or when the thread has exited, is waiting on a futex, or has used up its scheduling quantum.
+func (m *InstrumentedState) executesNextInstruction(thread *ThreadState) bool {
+	return m.state.Wakeup == exec.FutexEmptyAddr &&
+		!thread.Exited &&
+		thread.FutexAddr == exec.FutexEmptyAddr &&
+		m.state.StepsSinceLastContextSwitch < exec.SchedQuantum
+}
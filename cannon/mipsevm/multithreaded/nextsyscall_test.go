@@ -0,0 +1,92 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestStepToNextSyscall(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+
+	_, _, err := us.StepToNextSyscall(100)
+	require.ErrorContains(t, err, "no syscall reached after 100 steps")
+
+	// The Go runtime starts by querying the CPU affinity of the process
+	num, args, err := us.StepToNextSyscall(1_000_000)
+	require.NoError(t, err)
+	require.Equal(t, Word(arch.SysGetAffinity), num)
+	require.Equal(t, Word(0), args[0])    // pid
+	require.Equal(t, Word(8192), args[1]) // cpusetsize
+
+	// The syscall has not been executed yet
+	step := state.Step
+	again, _, err := us.StepToNextSyscall(0)
+	require.NoError(t, err)
+	require.Equal(t, num, again)
+	require.Equal(t, step, state.Step)
+
+	_, err = us.Step(false)
+	require.NoError(t, err)
+	next, _, err := us.StepToNextSyscall(1_000_000)
+	require.NoError(t, err)
+	require.Greater(t, state.Step, step)
+	require.NotZero(t, next)
+}
+
+func TestStepToNextSyscall_SkipsUnscheduledThread(t *testing.T) {
+	cases := []struct {
+		name          string
+		modify        func(t *testing.T, state *State, active *ThreadState)
+		expectedNum   Word
+		expectedSteps uint64
+	}{
+		{name: "runnable", modify: func(t *testing.T, state *State, active *ThreadState) {}, expectedNum: arch.SysGetTID},
+		{name: "exited", modify: func(t *testing.T, state *State, active *ThreadState) {
+			active.Exited = true
+		}, expectedNum: arch.SysSchedYield, expectedSteps: 1},
+		{name: "futex waiting", modify: func(t *testing.T, state *State, active *ThreadState) {
+			active.FutexAddr = 0x4000
+			active.FutexVal = 0
+			active.FutexTimeoutStep = exec.FutexNoTimeout
+			active.FutexBitset = exec.FutexBitsetMatchAny
+		}, expectedNum: arch.SysSchedYield, expectedSteps: 1},
+		{name: "pending wakeup", modify: func(t *testing.T, state *State, active *ThreadState) {
+			// No thread waits on the address, so the traversal passes both threads in both directions
+			require.NoError(t, state.SetWakeup(0x4000, exec.FutexBitsetMatchAny))
+		}, expectedNum: arch.SysGetTID, expectedSteps: 4},
+		{name: "quantum used up", modify: func(t *testing.T, state *State, active *ThreadState) {
+			require.NoError(t, state.SetStepsSinceLastContextSwitch(exec.SchedQuantum))
+		}, expectedNum: arch.SysSchedYield, expectedSteps: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			active := CreateEmptyThread()
+			active.ThreadId = 0
+			active.Registers[2] = arch.SysGetTID
+			other := CreateEmptyThread()
+			other.ThreadId = 1
+			other.Cpu.PC = 0x2000
+			other.Cpu.NextPC = 0x2004
+			other.Registers[2] = arch.SysSchedYield
+			state, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{other, active}, nil, false, 2)
+			require.NoError(t, err)
+			testutil.StoreInstruction(state.Memory, active.Cpu.PC, 0x00_00_00_0c) // syscall
+			testutil.StoreInstruction(state.Memory, other.Cpu.PC, 0x00_00_00_0c)  // syscall
+			c.modify(t, state, active)
+			us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+
+			num, _, err := us.StepToNextSyscall(10)
+			require.NoError(t, err)
+			require.Equal(t, c.expectedNum, num)
+			require.Equal(t, c.expectedSteps, state.Step)
+		})
+	}
+}
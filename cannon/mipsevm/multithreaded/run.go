@@ -0,0 +1,67 @@
+package multithreaded
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum-optimism/optimism/op-service/ioutil"
+	"github.com/ethereum-optimism/optimism/op-service/jsonutil"
+)
+
+// RunOptions configures RunSteps.
+type RunOptions struct {
+	// MaxStepsPerSecond caps the rate of execution by sleeping between steps, so that concurrent runs can share a
+	// core. Zero means unlimited.
+	MaxStepsPerSecond uint64
+	// OutputDir, if set, is the directory to which the full state is written as JSON before and after the run,
+	// as prestate.json and poststate.json. The post-state is also written if the run fails.
+	OutputDir string
+}
+
+// RunSteps steps m up to maxSteps times, or until it exits, and returns the number of steps taken.
+// Pacing is applied here rather than in Step, so that unthrottled stepping is unaffected.
+func RunSteps(m *InstrumentedState, maxSteps uint64, opts RunOptions) (uint64, error) {
+	if opts.OutputDir != "" {
+		if err := os.MkdirAll(opts.OutputDir, 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create output dir: %w", err)
+		}
+		if err := m.writeStateJSON(filepath.Join(opts.OutputDir, "prestate.json")); err != nil {
+			return 0, err
+		}
+	}
+	n, err := runSteps(m, maxSteps, opts)
+	if opts.OutputDir != "" {
+		if writeErr := m.writeStateJSON(filepath.Join(opts.OutputDir, "poststate.json")); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+	return n, err
+}
+
+func runSteps(m *InstrumentedState, maxSteps uint64, opts RunOptions) (uint64, error) {
+	start := time.Now()
+	var i uint64
+	for i < maxSteps && !m.state.Exited {
+		if _, err := m.Step(false); err != nil {
+			return i, err
+		}
+		i++
+		if opts.MaxStepsPerSecond != 0 {
+			// Sleep until the time at which step i is due, so that the average rate since start is capped
+			due := time.Duration(float64(i) / float64(opts.MaxStepsPerSecond) * float64(time.Second))
+			if wait := due - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+	}
+	return i, nil
+}
+
+func (m *InstrumentedState) writeStateJSON(path string) error {
+	if err := jsonutil.WriteJSON(m.state, ioutil.ToStdOutOrFileOrNoop(path, 0o644)); err != nil {
+		return fmt.Errorf("failed to write state to %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package multithreaded
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+	"github.com/ethereum-optimism/optimism/op-service/jsonutil"
+)
+
+func TestRunSteps(t *testing.T) {
+	newVM := func() (*InstrumentedState, *State) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("hello"), CreateInitialState, false)
+		return NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta), state
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		us, state := newVM()
+		steps, err := RunSteps(us, 1000, RunOptions{})
+		require.NoError(t, err)
+		require.Equal(t, uint64(1000), steps)
+		require.Equal(t, uint64(1000), state.Step)
+	})
+
+	t.Run("max steps per second", func(t *testing.T) {
+		us, state := newVM()
+		start := time.Now()
+		steps, err := RunSteps(us, 100, RunOptions{MaxStepsPerSecond: 500})
+		require.NoError(t, err)
+		require.Equal(t, uint64(100), steps)
+		require.Equal(t, uint64(100), state.Step)
+		require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+
+	t.Run("output dir", func(t *testing.T) {
+		us, state := newVM()
+		_, preHash := state.EncodeWitness()
+		dir := filepath.Join(t.TempDir(), "run")
+		steps, err := RunSteps(us, 1000, RunOptions{OutputDir: dir})
+		require.NoError(t, err)
+		require.Equal(t, uint64(1000), steps)
+
+		pre, err := jsonutil.LoadJSON[State](filepath.Join(dir, "prestate.json"))
+		require.NoError(t, err)
+		require.Zero(t, pre.Step)
+		_, hash := pre.EncodeWitness()
+		require.Equal(t, preHash, hash)
+
+		post, err := jsonutil.LoadJSON[State](filepath.Join(dir, "poststate.json"))
+		require.NoError(t, err)
+		require.Equal(t, uint64(1000), post.Step)
+		_, hash = post.EncodeWitness()
+		_, postHash := state.EncodeWitness()
+		require.Equal(t, postHash, hash)
+	})
+}
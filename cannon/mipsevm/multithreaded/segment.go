@@ -0,0 +1,59 @@
+package multithreaded
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+)
+
+// Segment is a contiguous range of steps, identified by the state hashes at its boundaries.
+type Segment struct {
+	StartStep uint64
+	EndStep   uint64
+	StartHash common.Hash
+	EndHash   common.Hash
+}
+
+// SegmentByPreimage runs the VM for up to maxSteps steps, or until it exits, and splits the run into segments.
+// Each segment ends with the step that completes the read of a preimage. Any steps remaining after the last
+// completed preimage read form a final segment.
+func SegmentByPreimage(m *InstrumentedState, maxSteps uint64) ([]Segment, error) {
+	var segments []Segment
+	_, startHash := m.state.EncodeWitness()
+	startStep := m.state.Step
+	for i := uint64(0); i < maxSteps && !m.state.Exited; i++ {
+		if _, err := m.Step(false); err != nil {
+			return nil, err
+		}
+		if !m.completedPreimageRead() {
+			continue
+		}
+		_, endHash := m.state.EncodeWitness()
+		segments = append(segments, Segment{
+			StartStep: startStep,
+			EndStep:   m.state.Step,
+			StartHash: startHash,
+			EndHash:   endHash,
+		})
+		startStep, startHash = m.state.Step, endHash
+	}
+	if m.state.Step > startStep {
+		_, endHash := m.state.EncodeWitness()
+		segments = append(segments, Segment{
+			StartStep: startStep,
+			EndStep:   m.state.Step,
+			StartHash: startHash,
+			EndHash:   endHash,
+		})
+	}
+	return segments, nil
+}
+
+// completedPreimageRead returns true if the last step read the final bytes of the current preimage
+func (m *InstrumentedState) completedPreimageRead() bool {
+	_, preimage, offset := m.preimageOracle.LastPreimage()
+	if offset == ^arch.Word(0) {
+		return false
+	}
+	return m.state.PreimageOffset >= arch.Word(len(preimage))
+}
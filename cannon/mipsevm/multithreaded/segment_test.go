@@ -0,0 +1,46 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestSegmentByPreimage(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+	oracle, _, _ := testutil.ClaimTestOracle(t)
+	us := NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	_, initialHash := state.EncodeWitness()
+
+	segments, err := SegmentByPreimage(us, 2_000_000)
+	require.NoError(t, err)
+	require.True(t, state.Exited, "must complete program")
+
+	// The claim program reads 3 local and 4 keccak preimages, followed by the steps to exit
+	require.Len(t, segments, 8)
+	require.Equal(t, uint64(0), segments[0].StartStep)
+	require.Equal(t, initialHash, segments[0].StartHash)
+	for i := 1; i < len(segments); i++ {
+		require.Equal(t, segments[i-1].EndStep, segments[i].StartStep)
+		require.Equal(t, segments[i-1].EndHash, segments[i].StartHash)
+		require.Less(t, segments[i].StartStep, segments[i].EndStep)
+	}
+	last := segments[len(segments)-1]
+	_, finalHash := state.EncodeWitness()
+	require.Equal(t, state.Step, last.EndStep)
+	require.Equal(t, finalHash, last.EndHash)
+}
+
+func TestSegmentByPreimage_MaxSteps(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+	oracle, _, _ := testutil.ClaimTestOracle(t)
+	us := NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+
+	segments, err := SegmentByPreimage(us, 10)
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.Equal(t, uint64(10), segments[0].EndStep)
+}
@@ -1,9 +1,13 @@
 package multithreaded
 
 import (
+	"bytes"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -31,15 +35,21 @@ const (
 	STEP_WITNESS_OFFSET                       = EXITED_WITNESS_OFFSET + 1
 	STEPS_SINCE_CONTEXT_SWITCH_WITNESS_OFFSET = STEP_WITNESS_OFFSET + 8
 	WAKEUP_WITNESS_OFFSET                     = STEPS_SINCE_CONTEXT_SWITCH_WITNESS_OFFSET + 8
-	TRAVERSE_RIGHT_WITNESS_OFFSET             = WAKEUP_WITNESS_OFFSET + arch.WordSizeBytes
+	WAKEUP_BITSET_WITNESS_OFFSET              = WAKEUP_WITNESS_OFFSET + arch.WordSizeBytes
+	TRAVERSE_RIGHT_WITNESS_OFFSET             = WAKEUP_BITSET_WITNESS_OFFSET + 4
 	LEFT_THREADS_ROOT_WITNESS_OFFSET          = TRAVERSE_RIGHT_WITNESS_OFFSET + 1
 	RIGHT_THREADS_ROOT_WITNESS_OFFSET         = LEFT_THREADS_ROOT_WITNESS_OFFSET + 32
 	THREAD_ID_WITNESS_OFFSET                  = RIGHT_THREADS_ROOT_WITNESS_OFFSET + 32
 
-	// 172 and 196 bytes for 32 and 64-bit respectively
+	// 176 and 200 bytes for 32 and 64-bit respectively
 	STATE_WITNESS_SIZE = THREAD_ID_WITNESS_OFFSET + arch.WordSizeBytes
 )
 
+// StateWitnessSize returns STATE_WITNESS_SIZE, for code that handles witnesses of both the 32 and 64-bit builds.
+func StateWitnessSize() int {
+	return STATE_WITNESS_SIZE
+}
+
 type LLReservationStatus uint8
 
 const (
@@ -65,6 +75,7 @@ type State struct {
 	Step                        uint64
 	StepsSinceLastContextSwitch uint64
 	Wakeup                      Word
+	WakeupBitset                uint32 // The bitset of the pending wakeup, matched against the bitsets of waiters
 
 	TraverseRight    bool
 	LeftThreadStack  []*ThreadState
@@ -76,6 +87,9 @@ type State struct {
 }
 
 var _ mipsevm.FPVMState = (*State)(nil)
+var _ mipsevm.Checkpointable = (*State)(nil)
+
+var ErrEmptyThreadStack = errors.New("invalid empty thread stack")
 
 func CreateEmptyState() *State {
 	initThread := CreateEmptyThread()
@@ -90,6 +104,7 @@ func CreateEmptyState() *State {
 		Exited:              false,
 		Step:                0,
 		Wakeup:              exec.FutexEmptyAddr,
+		WakeupBitset:        0,
 		TraverseRight:       false,
 		LeftThreadStack:     []*ThreadState{initThread},
 		RightThreadStack:    []*ThreadState{},
@@ -97,6 +112,75 @@ func CreateEmptyState() *State {
 	}
 }
 
+// NewStateWithThreads creates an empty state with the given thread stacks and scheduling fields.
+// It returns an error if a thread id is duplicated or if nextThreadId is not greater than every existing thread id.
+func NewStateWithThreads(mem *memory.Memory, left, right []*ThreadState, traverseRight bool, nextThreadId Word) (*State, error) {
+	if err := validateThreadIds(left, right, nextThreadId); err != nil {
+		return nil, err
+	}
+
+	state := CreateEmptyState()
+	state.Memory = mem
+	state.TraverseRight = traverseRight
+	state.LeftThreadStack = left
+	state.RightThreadStack = right
+	state.NextThreadId = nextThreadId
+	return state, nil
+}
+
+// validateThreadIds checks that thread ids are unique, and that SysClone cannot mint an id that is already in use.
+func validateThreadIds(left, right []*ThreadState, nextThreadId Word) error {
+	seen := make(map[Word]bool, len(left)+len(right))
+	for _, stack := range [][]*ThreadState{left, right} {
+		for _, thread := range stack {
+			if seen[thread.ThreadId] {
+				return fmt.Errorf("duplicate thread id %d", thread.ThreadId)
+			}
+			seen[thread.ThreadId] = true
+			if thread.ThreadId >= nextThreadId {
+				return fmt.Errorf("invalid next thread id %d, must be greater than thread id %d", nextThreadId, thread.ThreadId)
+			}
+		}
+	}
+	return nil
+}
+
+// SetNextThreadId sets the id that SysClone assigns to the next thread, e.g. to match the ids of a captured state.
+// It returns an error, leaving the state unchanged, if id is not greater than every existing thread id.
+func (s *State) SetNextThreadId(id Word) error {
+	if err := validateThreadIds(s.LeftThreadStack, s.RightThreadStack, id); err != nil {
+		return err
+	}
+	s.NextThreadId = id
+	return nil
+}
+
+// SetStepsSinceLastContextSwitch sets the number of steps the active thread has run since it was scheduled.
+// It is intended for constructing scheduler test scenarios, e.g. a thread about to be preempted.
+// It returns an error, leaving the state unchanged, if steps exceeds exec.SchedQuantum, which execution never reaches.
+func (s *State) SetStepsSinceLastContextSwitch(steps uint64) error {
+	if steps > exec.SchedQuantum {
+		return fmt.Errorf("invalid steps since last context switch %d, must not exceed %d", steps, exec.SchedQuantum)
+	}
+	s.StepsSinceLastContextSwitch = steps
+	return nil
+}
+
+// SetWakeup sets the futex address and bitset of a pending wakeup traversal, or exec.FutexEmptyAddr and a zero
+// bitset for none. It is intended for constructing scheduler test scenarios. It returns an error, leaving the state
+// unchanged, if addr is not word-aligned, or if the bitset is zero for a pending wakeup or non-zero without one.
+func (s *State) SetWakeup(addr Word, bitset uint32) error {
+	if addr != exec.FutexEmptyAddr && addr&arch.ExtMask != 0 {
+		return fmt.Errorf("invalid unaligned wakeup address 0x%x", addr)
+	}
+	if (addr == exec.FutexEmptyAddr) != (bitset == 0) {
+		return fmt.Errorf("invalid wakeup bitset 0x%x for wakeup address 0x%x", bitset, addr)
+	}
+	s.Wakeup = addr
+	s.WakeupBitset = bitset
+	return nil
+}
+
 func CreateInitialState(pc, heapStart Word) *State {
 	state := CreateEmptyState()
 	currentThread := state.GetCurrentThread()
@@ -123,6 +207,27 @@ func (s *State) GetCurrentThread() *ThreadState {
 	return activeStack[activeStackSize-1]
 }
 
+// ThreadContext is the register context of a thread: its cpu scalars and general purpose registers.
+type ThreadContext struct {
+	Cpu       mipsevm.CpuScalars
+	Registers [32]Word
+}
+
+// ActiveContext returns a copy of the register context of the active thread.
+// It panics if the active thread stack is empty, like GetCurrentThread.
+func (s *State) ActiveContext() ThreadContext {
+	thread := s.GetCurrentThread()
+	return ThreadContext{Cpu: thread.Cpu, Registers: thread.Registers}
+}
+
+// RestoreActiveContext overwrites the register context of the active thread with ctx, e.g. to undo a scratch
+// experiment started after ActiveContext. Other thread fields, the scheduler and memory are left unchanged.
+func (s *State) RestoreActiveContext(ctx ThreadContext) {
+	thread := s.GetCurrentThread()
+	thread.Cpu = ctx.Cpu
+	thread.Registers = ctx.Registers
+}
+
 func (s *State) getActiveThreadStack() []*ThreadState {
 	var activeStack []*ThreadState
 	if s.TraverseRight {
@@ -134,6 +239,11 @@ func (s *State) getActiveThreadStack() []*ThreadState {
 	return activeStack
 }
 
+// ThreadStackRoots returns the roots of the left and right thread stacks, as committed to by the state witness.
+func (s *State) ThreadStackRoots() (left, right common.Hash) {
+	return s.getLeftThreadStackRoot(), s.getRightThreadStackRoot()
+}
+
 func (s *State) getRightThreadStackRoot() common.Hash {
 	return s.calculateThreadStackRoot(s.RightThreadStack)
 }
@@ -200,6 +310,8 @@ func (s *State) GetPreimageOffset() Word {
 	return s.PreimageOffset
 }
 
+// EncodeWitness returns the state witness and its hash.
+// The witness is deterministic: equal states always encode to identical bytes, see memory.Memory.MerkleRoot.
 func (s *State) EncodeWitness() ([]byte, common.Hash) {
 	out := make([]byte, 0, STATE_WITNESS_SIZE)
 	memRoot := s.Memory.MerkleRoot()
@@ -216,6 +328,7 @@ func (s *State) EncodeWitness() ([]byte, common.Hash) {
 	out = binary.BigEndian.AppendUint64(out, s.Step)
 	out = binary.BigEndian.AppendUint64(out, s.StepsSinceLastContextSwitch)
 	out = arch.ByteOrderWord.AppendWord(out, s.Wakeup)
+	out = binary.BigEndian.AppendUint32(out, s.WakeupBitset)
 
 	leftStackRoot := s.getLeftThreadStackRoot()
 	rightStackRoot := s.getRightThreadStackRoot()
@@ -227,11 +340,59 @@ func (s *State) EncodeWitness() ([]byte, common.Hash) {
 	return out, stateHashFromWitness(out)
 }
 
+// WitnessFields returns the fields of the state witness in the order they are encoded, e.g. to report which fields
+// of two witnesses differ.
+func (s *State) WitnessFields() []mipsevm.WitnessField {
+	return []mipsevm.WitnessField{
+		{Name: "MemRoot", Offset: MEMROOT_WITNESS_OFFSET, Size: 32},
+		{Name: "PreimageKey", Offset: PREIMAGE_KEY_WITNESS_OFFSET, Size: 32},
+		{Name: "PreimageOffset", Offset: PREIMAGE_OFFSET_WITNESS_OFFSET, Size: arch.WordSizeBytes},
+		{Name: "Heap", Offset: HEAP_WITNESS_OFFSET, Size: arch.WordSizeBytes},
+		{Name: "LLReservationStatus", Offset: LL_RESERVATION_ACTIVE_OFFSET, Size: 1},
+		{Name: "LLAddress", Offset: LL_ADDRESS_OFFSET, Size: arch.WordSizeBytes},
+		{Name: "LLOwnerThread", Offset: LL_OWNER_THREAD_OFFSET, Size: arch.WordSizeBytes},
+		{Name: "ExitCode", Offset: EXITCODE_WITNESS_OFFSET, Size: 1},
+		{Name: "Exited", Offset: EXITED_WITNESS_OFFSET, Size: 1},
+		{Name: "Step", Offset: STEP_WITNESS_OFFSET, Size: 8},
+		{Name: "StepsSinceLastContextSwitch", Offset: STEPS_SINCE_CONTEXT_SWITCH_WITNESS_OFFSET, Size: 8},
+		{Name: "Wakeup", Offset: WAKEUP_WITNESS_OFFSET, Size: arch.WordSizeBytes},
+		{Name: "WakeupBitset", Offset: WAKEUP_BITSET_WITNESS_OFFSET, Size: 4},
+		{Name: "TraverseRight", Offset: TRAVERSE_RIGHT_WITNESS_OFFSET, Size: 1},
+		{Name: "LeftThreadStack", Offset: LEFT_THREADS_ROOT_WITNESS_OFFSET, Size: 32},
+		{Name: "RightThreadStack", Offset: RIGHT_THREADS_ROOT_WITNESS_OFFSET, Size: 32},
+		{Name: "NextThreadId", Offset: THREAD_ID_WITNESS_OFFSET, Size: arch.WordSizeBytes},
+	}
+}
+
+// SchedulerWitness returns the scheduler fields of the state witness: the step, steps since the last context switch,
+// wakeup address and bitset, traversal direction and next thread id, encoded as in EncodeWitness. Unlike the full witness, it
+// omits the memory and thread stack roots, so scheduler state can be diffed on its own.
+func (s *State) SchedulerWitness() []byte {
+	out := make([]byte, 0, 8+8+arch.WordSizeBytes+4+1+arch.WordSizeBytes)
+	out = binary.BigEndian.AppendUint64(out, s.Step)
+	out = binary.BigEndian.AppendUint64(out, s.StepsSinceLastContextSwitch)
+	out = arch.ByteOrderWord.AppendWord(out, s.Wakeup)
+	out = binary.BigEndian.AppendUint32(out, s.WakeupBitset)
+	out = mipsevm.AppendBoolToWitness(out, s.TraverseRight)
+	out = arch.ByteOrderWord.AppendWord(out, s.NextThreadId)
+	return out
+}
+
 func (s *State) EncodeThreadProof() []byte {
+	out, err := s.EncodeThreadProofSafe()
+	if err != nil {
+		panic("Invalid empty thread stack")
+	}
+	return out
+}
+
+// EncodeThreadProofSafe is like EncodeThreadProof, but returns ErrEmptyThreadStack instead of panicking
+// when the active thread stack is empty.
+func (s *State) EncodeThreadProofSafe() ([]byte, error) {
 	activeStack := s.getActiveThreadStack()
 	threadCount := len(activeStack)
 	if threadCount == 0 {
-		panic("Invalid empty thread stack")
+		return nil, ErrEmptyThreadStack
 	}
 
 	activeThread := activeStack[threadCount-1]
@@ -242,13 +403,104 @@ func (s *State) EncodeThreadProof() []byte {
 	out := make([]byte, 0, THREAD_WITNESS_SIZE)
 	out = append(out, threadBytes[:]...)
 	out = append(out, otherThreadsWitness[:]...)
-	return out
+	return out, nil
+}
+
+// ReadProof returns the step calldata of an instruction at the current PC that reads the word at addr.
+// The first STATE_WITNESS_SIZE bytes are the encoded state witness, passed to the contract as the state data.
+// The remainder is the proof data, laid out in the order the contract consumes it: the thread witness of the
+// active thread, the memory proof of the instruction at the PC, and the memory proof of addr.
+// Both memory proofs verify against the memory root in the state witness.
+func (s *State) ReadProof(addr Word) ([]byte, error) {
+	if addr&arch.ExtMask != 0 {
+		return nil, fmt.Errorf("unaligned memory read proof address: 0x%x", addr)
+	}
+	threadProof, err := s.EncodeThreadProofSafe()
+	if err != nil {
+		return nil, err
+	}
+	witness, _ := s.EncodeWitness()
+	insnProof := s.Memory.MerkleProof(s.GetPC())
+	memProof := s.Memory.MerkleProof(addr)
+
+	out := make([]byte, 0, STATE_WITNESS_SIZE+THREAD_WITNESS_SIZE+2*memory.MemProofSize)
+	out = append(out, witness...)
+	out = append(out, threadProof...)
+	out = append(out, insnProof[:]...)
+	out = append(out, memProof[:]...)
+	return out, nil
+}
+
+// StateHash returns the hash of the state witness
+func (s *State) StateHash() common.Hash {
+	_, hash := s.EncodeWitness()
+	return hash
+}
+
+// HeapUsage returns the size of the heap reserved by mmap, from arch.HeapStart up to Heap, and how much of it is
+// backed by allocated memory pages. Reserved regions that were never accessed are not backed by pages.
+func (s *State) HeapUsage() (reserved uint64, backed uint64) {
+	if s.Heap <= arch.HeapStart {
+		return 0, 0
+	}
+	reserved = uint64(s.Heap - arch.HeapStart)
+	start := Word(arch.HeapStart) >> memory.PageAddrSize
+	end := (s.Heap + memory.PageAddrMask) >> memory.PageAddrSize
+	_ = s.Memory.ForEachPage(func(pageIndex Word, _ *memory.Page) error {
+		if pageIndex >= start && pageIndex < end {
+			backed += memory.PageSize
+		}
+		return nil
+	})
+	return reserved, backed
+}
+
+// VerifyMemoryRoot recomputes the memory merkle root and returns an error if it does not match expected,
+// e.g. the memory root committed to by a witness of the state.
+func (s *State) VerifyMemoryRoot(expected common.Hash) error {
+	if root := common.Hash(s.Memory.MerkleRoot()); root != expected {
+		return fmt.Errorf("memory root %s does not match expected root %s", root, expected)
+	}
+	return nil
 }
 
 func (s *State) ThreadCount() int {
 	return len(s.LeftThreadStack) + len(s.RightThreadStack)
 }
 
+// ThreadStackBytes returns the total size of all threads on both stacks when serialized,
+// for budgeting thread proof and witness sizes alongside the memory size.
+func (s *State) ThreadStackBytes() int {
+	return s.ThreadCount() * SERIALIZED_THREAD_SIZE
+}
+
+// ExitedThreads returns the threads on either stack that have exited but not yet been removed.
+// An exited thread is removed the next time it is scheduled, so at most one is expected between steps.
+func (s *State) ExitedThreads() []*ThreadState {
+	var exited []*ThreadState
+	for _, stack := range [][]*ThreadState{s.LeftThreadStack, s.RightThreadStack} {
+		for _, thread := range stack {
+			if thread.Exited {
+				exited = append(exited, thread)
+			}
+		}
+	}
+	return exited
+}
+
+// clone returns a deep copy of the state
+func (s *State) clone() (*State, error) {
+	var buf bytes.Buffer
+	if err := s.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	out := new(State)
+	if err := out.Deserialize(&buf); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Serialize writes the state in a simple binary format which can be read again using Deserialize
 // The format is a simple concatenation of fields, with prefixed item count for repeating items and using big endian
 // encoding for numbers.
@@ -263,6 +515,7 @@ func (s *State) ThreadCount() int {
 // Step                        uint64
 // StepsSinceLastContextSwitch uint64
 // Wakeup                      Word
+// WakeupBitset                uint32
 // TraverseRight               uint8 - 0 for false, 1 for true
 // NextThreadId                Word
 // len(LeftThreadStack)        Word
@@ -310,6 +563,9 @@ func (s *State) Serialize(out io.Writer) error {
 	if err := bout.WriteUInt(s.Wakeup); err != nil {
 		return err
 	}
+	if err := bout.WriteUInt(s.WakeupBitset); err != nil {
+		return err
+	}
 	if err := bout.WriteBool(s.TraverseRight); err != nil {
 		return err
 	}
@@ -379,6 +635,9 @@ func (s *State) Deserialize(in io.Reader) error {
 	if err := bin.ReadUInt(&s.Wakeup); err != nil {
 		return err
 	}
+	if err := bin.ReadUInt(&s.WakeupBitset); err != nil {
+		return err
+	}
 	if err := bin.ReadBool(&s.TraverseRight); err != nil {
 		return err
 	}
@@ -414,9 +673,78 @@ func (s *State) Deserialize(in io.Reader) error {
 	if err := bin.ReadBytes((*[]byte)(&s.LastHint)); err != nil {
 		return err
 	}
+	if err := validateThreadIds(s.LeftThreadStack, s.RightThreadStack, s.NextThreadId); err != nil {
+		return fmt.Errorf("invalid thread ids: %w", err)
+	}
 	return nil
 }
 
+// MarshalBinary encodes the state in the format written by Serialize
+func (s *State) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := s.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a state in the format read by Deserialize
+func (s *State) UnmarshalBinary(data []byte) error {
+	return s.Deserialize(bytes.NewReader(data))
+}
+
+// stateMarshaling is the JSON encoding of State. It has the fields of State, so the keys are the field names.
+type stateMarshaling State
+
+// UnmarshalJSON decodes a state encoded as JSON. The decoded state produces the same witness as the encoded state.
+// The thread stacks are validated as in Deserialize, so an invalid state is rejected rather than failing later.
+// Encoding does not validate, so that an invalid state can still be dumped for debugging.
+func (s *State) UnmarshalJSON(data []byte) error {
+	var sm stateMarshaling
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return err
+	}
+	return s.setDecoded((*State)(&sm))
+}
+
+// setDecoded validates a decoded state, and sets s to it.
+func (s *State) setDecoded(decoded *State) error {
+	if decoded.Memory == nil {
+		return errors.New("invalid state: missing memory")
+	}
+	if err := decoded.validateThreads(); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	// An empty hint is encoded the same as no hint, and is decoded as no hint like in Deserialize
+	if len(decoded.LastHint) == 0 {
+		decoded.LastHint = nil
+	}
+	*s = *decoded
+	return nil
+}
+
+// validateThreads checks that the active thread stack is not empty, that no thread is missing, and that thread ids
+// are unique and below NextThreadId.
+func (s *State) validateThreads() error {
+	if len(s.getActiveThreadStack()) == 0 {
+		return ErrEmptyThreadStack
+	}
+	for _, stack := range [][]*ThreadState{s.LeftThreadStack, s.RightThreadStack} {
+		if slices.Contains(stack, nil) {
+			return errors.New("missing thread")
+		}
+	}
+	return validateThreadIds(s.LeftThreadStack, s.RightThreadStack, s.NextThreadId)
+}
+
+// CanonicalJSON encodes the state as JSON without the LastHint metadata, which is not part of the witness.
+// States with equal witnesses and memory contents encode identically, which keeps JSON diffs focused on the VM state.
+func (s *State) CanonicalJSON() ([]byte, error) {
+	canonical := *s
+	canonical.LastHint = nil
+	return json.Marshal(&canonical)
+}
+
 type StateWitness []byte
 
 func (sw StateWitness) StateHash() (common.Hash, error) {
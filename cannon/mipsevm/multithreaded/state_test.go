@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"debug/elf"
 	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +21,7 @@ import (
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
 )
 
 func setWitnessField(witness StateWitness, fieldOffset int, fieldData []byte) {
@@ -53,6 +58,7 @@ func TestState_EncodeWitness(t *testing.T) {
 	preimageOffset := Word(24)
 	step := uint64(33)
 	stepsSinceContextSwitch := uint64(123)
+	wakeupBitset := uint32(0x0f0f_0f0f)
 	for _, c := range cases {
 		state := CreateEmptyState()
 		state.Exited = c.exited
@@ -65,6 +71,7 @@ func TestState_EncodeWitness(t *testing.T) {
 		state.LLOwnerThread = llThreadOwner
 		state.Step = step
 		state.StepsSinceLastContextSwitch = stepsSinceContextSwitch
+		state.WakeupBitset = wakeupBitset
 
 		memRoot := state.Memory.MerkleRoot()
 		leftStackRoot := state.calculateThreadStackRoot(state.LeftThreadStack)
@@ -86,6 +93,7 @@ func TestState_EncodeWitness(t *testing.T) {
 		setWitnessField(expectedWitness, STEP_WITNESS_OFFSET, []byte{0, 0, 0, 0, 0, 0, 0, byte(step)})
 		setWitnessField(expectedWitness, STEPS_SINCE_CONTEXT_SWITCH_WITNESS_OFFSET, []byte{0, 0, 0, 0, 0, 0, 0, byte(stepsSinceContextSwitch)})
 		setWitnessWord(expectedWitness, WAKEUP_WITNESS_OFFSET, ^arch.Word(0))
+		setWitnessField(expectedWitness, WAKEUP_BITSET_WITNESS_OFFSET, []byte{0x0f, 0x0f, 0x0f, 0x0f})
 		setWitnessField(expectedWitness, TRAVERSE_RIGHT_WITNESS_OFFSET, []byte{0})
 		setWitnessField(expectedWitness, LEFT_THREADS_ROOT_WITNESS_OFFSET, leftStackRoot[:])
 		setWitnessField(expectedWitness, RIGHT_THREADS_ROOT_WITNESS_OFFSET, rightStackRoot[:])
@@ -102,6 +110,46 @@ func TestState_EncodeWitness(t *testing.T) {
 	}
 }
 
+func TestState_EncodeWitnessDeterministic(t *testing.T) {
+	// Populate pages spread over the address space, in a random order
+	rng := rand.New(rand.NewSource(0x726))
+	addrs := make([]Word, 64)
+	for i := range addrs {
+		addrs[i] = Word(rng.Uint64()) & arch.AddressMask
+	}
+	newState := func(order []int) *State {
+		state := CreateEmptyState()
+		for _, i := range order {
+			state.Memory.SetWord(addrs[i], Word(i)+1)
+		}
+		state.Step = 1234
+		return state
+	}
+
+	state := newState(rng.Perm(len(addrs)))
+	expected, _ := state.EncodeWitness()
+	for i := 0; i < 100; i++ {
+		witness, _ := state.EncodeWitness()
+		require.Equal(t, expected, witness)
+	}
+
+	// The witness does not depend on the order in which pages were allocated
+	for i := 0; i < 10; i++ {
+		witness, _ := newState(rng.Perm(len(addrs))).EncodeWitness()
+		require.Equal(t, expected, witness)
+	}
+
+	// Nor on how the pages were restored
+	for i := 0; i < 10; i++ {
+		stateJSON, err := json.Marshal(state)
+		require.NoError(t, err)
+		state = new(State)
+		require.NoError(t, json.Unmarshal(stateJSON, state))
+		witness, _ := state.EncodeWitness()
+		require.Equal(t, expected, witness)
+	}
+}
+
 func TestState_JSONCodec(t *testing.T) {
 	elfProgram, err := elf.Open("../../testdata/example/bin/hello.elf")
 	require.NoError(t, err, "open ELF file")
@@ -140,6 +188,141 @@ func TestState_JSONCodec(t *testing.T) {
 	require.Equal(t, state.LastHint, newState.LastHint)
 }
 
+func TestState_CanonicalJSON(t *testing.T) {
+	newState := func(lastHint []byte) *State {
+		state := CreateInitialState(0x1000, arch.HeapStart)
+		state.Memory.SetWord(0x1000, 0x1234)
+		state.Step = 42
+		state.LastHint = lastHint
+		return state
+	}
+	a := newState([]byte{1, 2, 3})
+	b := newState(nil)
+	witnessA, _ := a.EncodeWitness()
+	witnessB, _ := b.EncodeWitness()
+	require.Equal(t, witnessA, witnessB)
+
+	canonicalA, err := a.CanonicalJSON()
+	require.NoError(t, err)
+	canonicalB, err := b.CanonicalJSON()
+	require.NoError(t, err)
+	require.Equal(t, canonicalA, canonicalB)
+	// The regular encoding includes the hint, and the state itself is not modified
+	plainA, err := json.Marshal(a)
+	require.NoError(t, err)
+	require.NotEqual(t, canonicalA, plainA)
+	require.Equal(t, hexutil.Bytes{1, 2, 3}, a.LastHint)
+
+	// The canonical encoding still decodes to the same VM state
+	var decoded *State
+	require.NoError(t, json.Unmarshal(canonicalA, &decoded))
+	decodedWitness, _ := decoded.EncodeWitness()
+	require.Equal(t, witnessA, decodedWitness)
+
+	b.Step++
+	canonicalB, err = b.CanonicalJSON()
+	require.NoError(t, err)
+	require.NotEqual(t, canonicalA, canonicalB)
+}
+
+func TestState_JSONRoundTrip(t *testing.T) {
+	state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("mt-general"), CreateInitialState, false)
+	us := NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+
+	roundTrip := func(state *State) *State {
+		stateJSON, err := json.Marshal(state)
+		require.NoError(t, err)
+		var decoded *State
+		require.NoError(t, json.Unmarshal(stateJSON, &decoded))
+		return decoded
+	}
+	requireRoundTrip := func(state *State) {
+		decoded := roundTrip(state)
+		expectedWitness, expectedHash := state.EncodeWitness()
+		witness, hash := decoded.EncodeWitness()
+		require.Equal(t, expectedWitness, witness)
+		require.Equal(t, expectedHash, hash)
+		require.Equal(t, state.NextThreadId, decoded.NextThreadId)
+		require.Equal(t, state.Wakeup, decoded.Wakeup)
+		require.Equal(t, state.LeftThreadStack, decoded.LeftThreadStack)
+		require.Equal(t, state.RightThreadStack, decoded.RightThreadStack)
+		require.Equal(t, state.LastHint, decoded.LastHint)
+	}
+
+	requireRoundTrip(state)
+	// Run until threads are on both stacks
+	for i := 0; i < 1_000_000 && (len(state.LeftThreadStack) == 0 || len(state.RightThreadStack) == 0); i++ {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+		require.False(t, state.Exited)
+	}
+	require.NotEmpty(t, state.LeftThreadStack, "must have threads on both stacks")
+	require.NotEmpty(t, state.RightThreadStack, "must have threads on both stacks")
+	for i := 0; i < 5 && !state.Exited; i++ {
+		state.LastHint = []byte{byte(i), 0xaa}
+		requireRoundTrip(state)
+		for j := 0; j < 1000; j++ {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+	}
+
+	// A run resumed from the decoded state continues identically. The memory proofs of steps that do not access memory
+	// are carried over by the InstrumentedState rather than the state, so only the state witnesses are compared.
+	resumed := roundTrip(state)
+	resumedUs := NewInstrumentedState(resumed, nil, io.Discard, io.Discard, testutil.CreateLogger(), meta)
+	for i := 0; i < 10_000 && !state.Exited; i++ {
+		expected, err := us.Step(true)
+		require.NoError(t, err)
+		actual, err := resumedUs.Step(true)
+		require.NoError(t, err)
+		require.Equalf(t, expected.State, actual.State, "witness %d", i)
+		require.Equalf(t, expected.StateHash, actual.StateHash, "witness %d", i)
+	}
+	expectedWitness, _ := state.EncodeWitness()
+	witness, _ := resumed.EncodeWitness()
+	require.Equal(t, expectedWitness, witness)
+}
+
+func TestState_JSONInvalid(t *testing.T) {
+	cases := []struct {
+		name        string
+		modify      func(state *State)
+		expectedErr string
+	}{
+		{name: "empty active stack", modify: func(state *State) { state.LeftThreadStack = nil }, expectedErr: ErrEmptyThreadStack.Error()},
+		{name: "missing thread", modify: func(state *State) { state.RightThreadStack = []*ThreadState{nil} }, expectedErr: "missing thread"},
+		{name: "duplicate thread id", modify: func(state *State) {
+			state.RightThreadStack = []*ThreadState{CreateEmptyThread()}
+			state.NextThreadId = 1
+		}, expectedErr: "duplicate thread id 0"},
+		{name: "next thread id", modify: func(state *State) { state.NextThreadId = 0 }, expectedErr: "invalid next thread id 0"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			c.modify(state)
+			// An invalid state can still be encoded, e.g. to dump it for debugging
+			invalidJSON, err := json.Marshal(state)
+			require.NoError(t, err)
+			err = json.Unmarshal(invalidJSON, new(State))
+			require.ErrorContains(t, err, "invalid state")
+			require.ErrorContains(t, err, c.expectedErr)
+		})
+	}
+
+	t.Run("missing memory", func(t *testing.T) {
+		stateJSON, err := json.Marshal(CreateEmptyState())
+		require.NoError(t, err)
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(stateJSON, &fields))
+		delete(fields, "Memory")
+		invalidJSON, err := json.Marshal(fields)
+		require.NoError(t, err)
+		require.ErrorContains(t, json.Unmarshal(invalidJSON, new(State)), "invalid state: missing memory")
+	})
+}
+
 func TestState_Binary(t *testing.T) {
 	elfProgram, err := elf.Open("../../testdata/example/bin/hello.elf")
 	require.NoError(t, err, "open ELF file")
@@ -197,6 +380,7 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 		Step:                        0xdeadbeef,
 		StepsSinceLastContextSwitch: 334,
 		Wakeup:                      42,
+		WakeupBitset:                43,
 		TraverseRight:               true,
 		LeftThreadStack: []*ThreadState{
 			{
@@ -206,6 +390,7 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 				FutexAddr:        47,
 				FutexVal:         48,
 				FutexTimeoutStep: 49,
+				FutexBitset:      50,
 				Cpu: mipsevm.CpuScalars{
 					PC:     0xFF,
 					NextPC: 0xFF + 4,
@@ -229,6 +414,7 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 				FutexAddr:        57,
 				FutexVal:         58,
 				FutexTimeoutStep: 59,
+				FutexBitset:      60,
 				Cpu: mipsevm.CpuScalars{
 					PC:     0xEE,
 					NextPC: 0xEE + 4,
@@ -249,6 +435,7 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 				FutexAddr:        67,
 				FutexVal:         68,
 				FutexTimeoutStep: 69,
+				FutexBitset:      70,
 				Cpu: mipsevm.CpuScalars{
 					PC:     0xdd,
 					NextPC: 0xdd + 4,
@@ -266,6 +453,7 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 				FutexAddr:        77,
 				FutexVal:         78,
 				FutexTimeoutStep: 79,
+				FutexBitset:      80,
 				Cpu: mipsevm.CpuScalars{
 					PC:     0xcc,
 					NextPC: 0xcc + 4,
@@ -384,22 +572,449 @@ func TestState_EncodeThreadProof_EmptyThreadStackPanic(t *testing.T) {
 			}
 
 			assert.PanicsWithValue(t, "Invalid empty thread stack", func() { state.EncodeThreadProof() })
+
+			proof, err := state.EncodeThreadProofSafe()
+			require.ErrorIs(t, err, ErrEmptyThreadStack)
+			require.Nil(t, proof)
+		})
+	}
+}
+
+func TestState_ReadProof(t *testing.T) {
+	state := CreateEmptyState()
+	addr := Word(0x80008)
+	state.GetRegistersRef()[4] = addr
+	state.Memory.SetWord(0x10000, 0xaabbccdd)
+	state.Memory.SetWord(addr, 42)
+	state.Memory.SetWord(0x13370000, 123)
+	// lw $t0, 0($a0)
+	testutil.StoreInstruction(state.Memory, state.GetPC(), 0x8c_88_00_00)
+
+	proof, err := state.ReadProof(addr)
+	require.NoError(t, err)
+	require.Len(t, proof, STATE_WITNESS_SIZE+THREAD_WITNESS_SIZE+2*memory.MemProofSize)
+	witness, _ := state.EncodeWitness()
+	require.Equal(t, witness, proof[:STATE_WITNESS_SIZE])
+	require.Equal(t, state.EncodeThreadProof(), proof[STATE_WITNESS_SIZE:STATE_WITNESS_SIZE+THREAD_WITNESS_SIZE])
+
+	memRoot := witness[MEMROOT_WITNESS_OFFSET : MEMROOT_WITNESS_OFFSET+32]
+	verify := func(memProof []byte, addr Word, expected Word) {
+		leafOffset := addr & 31
+		require.Equal(t, expected, arch.ByteOrderWord.Word(memProof[leafOffset:leafOffset+arch.WordSizeBytes]))
+		node := *(*[32]byte)(memProof[:32])
+		path := addr >> 5
+		for i := 32; i < len(memProof); i += 32 {
+			sib := *(*[32]byte)(memProof[i : i+32])
+			if path&1 != 0 {
+				node = memory.HashPair(sib, node)
+			} else {
+				node = memory.HashPair(node, sib)
+			}
+			path >>= 1
+		}
+		require.Equal(t, memRoot, node[:], "proof must verify against memory root")
+	}
+	insnProofOffset := STATE_WITNESS_SIZE + THREAD_WITNESS_SIZE
+	pcWord := state.GetPC() & ^Word(arch.ExtMask)
+	verify(proof[insnProofOffset:insnProofOffset+memory.MemProofSize], pcWord, state.Memory.GetWord(pcWord))
+	verify(proof[insnProofOffset+memory.MemProofSize:], addr, 42)
+
+	// The proof data must match what the VM produces when stepping the read
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	wit, err := us.Step(true)
+	require.NoError(t, err)
+	require.Equal(t, wit.State, proof[:STATE_WITNESS_SIZE])
+	require.Equal(t, wit.ProofData[:len(proof)-STATE_WITNESS_SIZE], proof[STATE_WITNESS_SIZE:])
+
+	_, err = state.ReadProof(addr + 1)
+	require.ErrorContains(t, err, "unaligned")
+}
+
+func TestState_NewStateWithThreads(t *testing.T) {
+	newThread := func(id Word) *ThreadState {
+		thread := CreateEmptyThread()
+		thread.ThreadId = id
+		thread.Cpu.PC = 0x1000 + 4*id
+		thread.Cpu.NextPC = thread.Cpu.PC + 4
+		thread.Registers[2] = id
+		return thread
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		mem := memory.NewMemory()
+		mem.SetWord(0x100, 0xdead)
+		left := []*ThreadState{newThread(0), newThread(2)}
+		right := []*ThreadState{newThread(1)}
+		state, err := NewStateWithThreads(mem, left, right, true, 3)
+		require.NoError(t, err)
+
+		require.Same(t, mem, state.Memory)
+		require.True(t, state.TraverseRight)
+		require.Equal(t, Word(3), state.NextThreadId)
+		require.Equal(t, 3, state.ThreadCount())
+		require.Equal(t, Word(1), state.GetCurrentThread().ThreadId)
+
+		expectedLeftRoot := computeThreadRoot(computeThreadRoot(EmptyThreadsRoot, left[0]), left[1])
+		expectedRightRoot := computeThreadRoot(EmptyThreadsRoot, right[0])
+		witness, _ := state.EncodeWitness()
+		require.Equal(t, expectedLeftRoot[:], []byte(witness[LEFT_THREADS_ROOT_WITNESS_OFFSET:LEFT_THREADS_ROOT_WITNESS_OFFSET+32]))
+		require.Equal(t, expectedRightRoot[:], []byte(witness[RIGHT_THREADS_ROOT_WITNESS_OFFSET:RIGHT_THREADS_ROOT_WITNESS_OFFSET+32]))
+		require.Equal(t, []byte{1}, []byte(witness[TRAVERSE_RIGHT_WITNESS_OFFSET:TRAVERSE_RIGHT_WITNESS_OFFSET+1]))
+		require.Equal(t, Word(3), arch.ByteOrderWord.Word(witness[THREAD_ID_WITNESS_OFFSET:]))
+		memRoot := mem.MerkleRoot()
+		require.Equal(t, memRoot[:], []byte(witness[MEMROOT_WITNESS_OFFSET:MEMROOT_WITNESS_OFFSET+32]))
+	})
+
+	t.Run("duplicate thread id", func(t *testing.T) {
+		_, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{newThread(0), newThread(1)}, []*ThreadState{newThread(1)}, false, 2)
+		require.EqualError(t, err, "duplicate thread id 1")
+	})
+
+	t.Run("next thread id too small", func(t *testing.T) {
+		_, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{newThread(0), newThread(2)}, nil, false, 2)
+		require.EqualError(t, err, "invalid next thread id 2, must be greater than thread id 2")
+	})
+}
+
+func TestState_DeserializeInvalidThreadIds(t *testing.T) {
+	cases := []struct {
+		name         string
+		ids          []Word
+		nextThreadId Word
+		expectedErr  string
+	}{
+		{name: "next id collides", ids: []Word{0, 1}, nextThreadId: 1, expectedErr: "invalid next thread id 1, must be greater than thread id 1"},
+		{name: "next id below existing", ids: []Word{5}, nextThreadId: 2, expectedErr: "invalid next thread id 2, must be greater than thread id 5"},
+		{name: "duplicate id", ids: []Word{3, 3}, nextThreadId: 4, expectedErr: "duplicate thread id 3"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state := CreateEmptyState()
+			state.LeftThreadStack = nil
+			for _, id := range c.ids {
+				thread := CreateEmptyThread()
+				thread.ThreadId = id
+				state.LeftThreadStack = append(state.LeftThreadStack, thread)
+			}
+			state.NextThreadId = c.nextThreadId
+			data, err := state.MarshalBinary()
+			require.NoError(t, err)
+
+			err = new(State).UnmarshalBinary(data)
+			require.ErrorContains(t, err, "invalid thread ids")
+			require.ErrorContains(t, err, c.expectedErr)
 		})
 	}
 }
 
+func TestState_SchedulerSetters(t *testing.T) {
+	state := CreateEmptyState()
+	require.NoError(t, state.SetStepsSinceLastContextSwitch(exec.SchedQuantum))
+	require.Equal(t, uint64(exec.SchedQuantum), state.StepsSinceLastContextSwitch)
+	err := state.SetStepsSinceLastContextSwitch(exec.SchedQuantum + 1)
+	require.EqualError(t, err, fmt.Sprintf("invalid steps since last context switch %d, must not exceed %d", exec.SchedQuantum+1, exec.SchedQuantum))
+	require.Equal(t, uint64(exec.SchedQuantum), state.StepsSinceLastContextSwitch, "state must be unchanged")
+
+	require.NoError(t, state.SetWakeup(0x1000, 0x3))
+	require.Equal(t, Word(0x1000), state.Wakeup)
+	require.Equal(t, uint32(0x3), state.WakeupBitset)
+	require.NoError(t, state.SetWakeup(exec.FutexEmptyAddr, 0))
+	require.Equal(t, exec.FutexEmptyAddr, state.Wakeup)
+	require.Zero(t, state.WakeupBitset)
+	require.EqualError(t, state.SetWakeup(0x1001, 0x3), "invalid unaligned wakeup address 0x1001")
+	require.EqualError(t, state.SetWakeup(0x1000, 0), "invalid wakeup bitset 0x0 for wakeup address 0x1000")
+	require.Equal(t, exec.FutexEmptyAddr, state.Wakeup, "state must be unchanged")
+
+	require.NoError(t, state.SetNextThreadId(10))
+	require.Equal(t, Word(10), state.NextThreadId)
+	require.EqualError(t, state.SetNextThreadId(0), "invalid next thread id 0, must be greater than thread id 0")
+	require.Equal(t, Word(10), state.NextThreadId, "state must be unchanged")
+}
+
+func TestState_Checkpointable(t *testing.T) {
+	restore := func(t *testing.T, saved mipsevm.Checkpointable, restored mipsevm.Checkpointable) {
+		data, err := saved.MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, restored.UnmarshalBinary(data))
+		require.Equal(t, saved.StateHash(), restored.StateHash())
+		require.Equal(t, saved.GetStep(), restored.GetStep())
+	}
+
+	state := CreateInitialState(0x1000, 0x2000)
+	state.Step = 42
+	state.Memory.SetWord(0x1000, 0xdead)
+	state.GetRegistersRef()[3] = 7
+	_, expectedHash := state.EncodeWitness()
+	require.Equal(t, expectedHash, state.StateHash())
+
+	restored := new(State)
+	restore(t, state, restored)
+	require.Equal(t, Word(0xdead), restored.Memory.GetWord(0x1000))
+	require.Error(t, new(State).UnmarshalBinary([]byte{1, 2, 3}))
+}
+
+func TestState_FPVMStateConformance(t *testing.T) {
+	const pc = Word(0x1000)
+	initial := CreateInitialState(pc, arch.HeapStart)
+	var state mipsevm.FPVMState = initial
+
+	require.Equal(t, pc, state.GetPC())
+	require.Equal(t, pc, state.GetCpu().PC)
+	require.Equal(t, pc+4, state.GetCpu().NextPC)
+	require.Equal(t, Word(arch.HeapStart), state.GetHeap())
+	require.Equal(t, uint64(0), state.GetStep())
+	require.False(t, state.GetExited())
+	require.Equal(t, uint8(0), state.GetExitCode())
+	require.Equal(t, uint8(mipsevm.VMStatusUnfinished), initial.VMStatus())
+	require.Equal(t, common.Hash{}, state.GetPreimageKey())
+	require.Equal(t, Word(0), state.GetPreimageOffset())
+	require.Empty(t, state.GetLastHint())
+
+	require.NotNil(t, state.GetMemory())
+	require.Zero(t, state.GetMemory().PageCount())
+	require.Equal(t, memory.NewMemory().MerkleRoot(), state.GetMemory().MerkleRoot())
+
+	registers := state.GetRegistersRef()
+	require.NotNil(t, registers)
+	require.Equal(t, [32]Word{}, *registers)
+	// The registers are a reference to the active thread's registers
+	require.Same(t, &initial.GetCurrentThread().Registers, registers)
+
+	witness, hash := state.EncodeWitness()
+	require.Len(t, witness, STATE_WITNESS_SIZE)
+	require.Equal(t, uint8(mipsevm.VMStatusUnfinished), hash[0])
+	witnessHash, err := GetStateHashFn()(witness)
+	require.NoError(t, err)
+	require.Equal(t, hash, witnessHash)
+	require.Equal(t, hash, initial.StateHash())
+
+	var buf bytes.Buffer
+	require.NoError(t, state.Serialize(&buf))
+	deserialized := new(State)
+	require.NoError(t, deserialized.Deserialize(&buf))
+	deserializedWitness, _ := deserialized.EncodeWitness()
+	require.Equal(t, witness, deserializedWitness)
+
+	vm := state.CreateVM(testutil.CreateLogger(), nil, os.Stdout, os.Stderr, nil)
+	require.NotNil(t, vm)
+	require.Same(t, initial, vm.GetState())
+}
+
+func TestState_ThreadStackRoots(t *testing.T) {
+	left := []*ThreadState{CreateEmptyThread()}
+	right := []*ThreadState{CreateEmptyThread(), CreateEmptyThread()}
+	left[0].ThreadId = 0
+	right[0].ThreadId = 1
+	right[1].ThreadId = 2
+	right[1].Registers[4] = 0xbeef
+	state, err := NewStateWithThreads(memory.NewMemory(), left, right, false, 3)
+	require.NoError(t, err)
+
+	leftRoot, rightRoot := state.ThreadStackRoots()
+	require.NotEqual(t, leftRoot, rightRoot)
+	witness, _ := state.EncodeWitness()
+	require.Equal(t, common.Hash(witness[LEFT_THREADS_ROOT_WITNESS_OFFSET:LEFT_THREADS_ROOT_WITNESS_OFFSET+32]), leftRoot)
+	require.Equal(t, common.Hash(witness[RIGHT_THREADS_ROOT_WITNESS_OFFSET:RIGHT_THREADS_ROOT_WITNESS_OFFSET+32]), rightRoot)
+
+	state.LeftThreadStack = []*ThreadState{}
+	leftRoot, _ = state.ThreadStackRoots()
+	require.Equal(t, EmptyThreadsRoot, leftRoot)
+}
+
+func TestState_SchedulerWitness(t *testing.T) {
+	state := CreateEmptyState()
+	state.Step = 0x0102030405060708
+	state.StepsSinceLastContextSwitch = 0x1112131415161718
+	state.Wakeup = 0xabcd
+	state.TraverseRight = true
+	state.NextThreadId = 0x42
+
+	sched := state.SchedulerWitness()
+	witness, _ := state.EncodeWitness()
+	require.Len(t, sched, TRAVERSE_RIGHT_WITNESS_OFFSET+1-STEP_WITNESS_OFFSET+arch.WordSizeBytes)
+	require.Equal(t, witness[STEP_WITNESS_OFFSET:TRAVERSE_RIGHT_WITNESS_OFFSET+1], sched[:len(sched)-arch.WordSizeBytes])
+	require.Equal(t, witness[THREAD_ID_WITNESS_OFFSET:], sched[len(sched)-arch.WordSizeBytes:])
+
+	// Changes outside the scheduler do not affect it
+	state.Memory.SetWord(0x1000, 0xdead)
+	state.LeftThreadStack[0].Registers[4] = 1
+	require.Equal(t, sched, state.SchedulerWitness())
+
+	state.TraverseRight = false
+	require.NotEqual(t, sched, state.SchedulerWitness())
+}
+
+func TestState_ActiveContext(t *testing.T) {
+	for _, traverseRight := range []bool{false, true} {
+		t.Run(fmt.Sprintf("traverseRight=%v", traverseRight), func(t *testing.T) {
+			state := CreateEmptyState()
+			other := CreateEmptyThread()
+			other.ThreadId = 1
+			state.LeftThreadStack = append(state.LeftThreadStack, other)
+			state.RightThreadStack = append(state.RightThreadStack, CreateEmptyThread())
+			state.TraverseRight = traverseRight
+			state.GetCurrentThread().Cpu.PC = 0x100
+			state.GetCurrentThread().Cpu.NextPC = 0x104
+			state.GetCurrentThread().Registers[2] = 0x1234
+
+			ctx := state.ActiveContext()
+			witness, hash := state.EncodeWitness()
+
+			thread := state.GetCurrentThread()
+			thread.Cpu.PC = 0x200
+			thread.Cpu.NextPC = 0x204
+			thread.Cpu.HI = 7
+			thread.Registers[2] = 0
+			thread.Registers[31] = 0xdead
+			_, mutatedHash := state.EncodeWitness()
+			require.NotEqual(t, hash, mutatedHash)
+			// The saved context is a copy, unaffected by the mutation
+			require.Equal(t, Word(0x100), ctx.Cpu.PC)
+			require.Equal(t, Word(0x1234), ctx.Registers[2])
+
+			state.RestoreActiveContext(ctx)
+			restoredWitness, restoredHash := state.EncodeWitness()
+			require.Equal(t, witness, restoredWitness)
+			require.Equal(t, hash, restoredHash)
+		})
+	}
+}
+
+func TestState_ThreadStackBytes(t *testing.T) {
+	state := CreateEmptyState()
+	require.Equal(t, SERIALIZED_THREAD_SIZE, state.ThreadStackBytes())
+
+	state.LeftThreadStack = append(state.LeftThreadStack, CreateEmptyThread())
+	state.RightThreadStack = append(state.RightThreadStack, CreateEmptyThread(), CreateEmptyThread())
+	require.Equal(t, 4*SERIALIZED_THREAD_SIZE, state.ThreadStackBytes())
+
+	// Matches the size of the threads when actually serialized
+	var buf bytes.Buffer
+	for _, thread := range append(state.LeftThreadStack, state.RightThreadStack...) {
+		require.NoError(t, thread.Serialize(&buf))
+	}
+	require.Equal(t, buf.Len(), state.ThreadStackBytes())
+
+	state.LeftThreadStack = nil
+	state.RightThreadStack = nil
+	require.Zero(t, state.ThreadStackBytes())
+}
+
+func TestState_HeapUsage(t *testing.T) {
+	state := CreateEmptyState()
+	state.Heap = arch.HeapStart
+	reserved, backed := state.HeapUsage()
+	require.Zero(t, reserved)
+	require.Zero(t, backed)
+
+	// mmap 1 MiB, then touch two of its pages
+	const size = 1 << 20
+	testutil.StoreInstruction(state.Memory, state.GetPC(), 0x00_00_00_0c) // syscall
+	registers := state.GetRegistersRef()
+	registers[2] = arch.SysMmap
+	registers[4] = 0
+	registers[5] = size
+	us := NewInstrumentedState(state, nil, os.Stdout, os.Stderr, testutil.CreateLogger(), nil)
+	_, err := us.Step(false)
+	require.NoError(t, err)
+	addr := registers[2]
+	require.Equal(t, Word(arch.HeapStart), addr)
+	state.Memory.SetWord(addr, 1)
+	state.Memory.SetWord(addr+size-memory.PageSize, 1)
+	// Pages outside of the heap are not counted
+	state.Memory.SetWord(addr+size, 1)
+
+	reserved, backed = state.HeapUsage()
+	require.Equal(t, uint64(size), reserved)
+	require.Equal(t, uint64(2*memory.PageSize), backed)
+	require.Less(t, backed, reserved)
+}
+
+func TestState_VerifyMemoryRoot(t *testing.T) {
+	state := CreateEmptyState()
+	state.Memory.SetWord(0x1000, 0x12345678)
+	witness, _ := state.EncodeWitness()
+	committed := common.Hash(witness[MEMROOT_WITNESS_OFFSET : MEMROOT_WITNESS_OFFSET+32])
+
+	// Round trip the state, as when loading it from an untrusted source
+	data, err := state.MarshalBinary()
+	require.NoError(t, err)
+	loaded := new(State)
+	require.NoError(t, loaded.UnmarshalBinary(data))
+	require.NoError(t, loaded.VerifyMemoryRoot(committed))
+
+	loaded.Memory.SetWord(0x1000, 0x87654321)
+	require.ErrorContains(t, loaded.VerifyMemoryRoot(committed), "does not match expected root")
+}
+
+func TestState_StateHashFromWitness_InvalidLength(t *testing.T) {
+	witness := make([]byte, STATE_WITNESS_SIZE-1)
+	expectedMsg := fmt.Sprintf("Invalid witness length. Got %d, expected %d", STATE_WITNESS_SIZE-1, STATE_WITNESS_SIZE)
+	require.PanicsWithValue(t, expectedMsg, func() { stateHashFromWitness(witness) })
+
+	_, err := StateWitness(witness).StateHash()
+	require.EqualError(t, err, expectedMsg)
+}
+
 func TestStateWitnessSize(t *testing.T) {
-	expectedWitnessSize := 172
+	expectedWitnessSize := 176
 	if !arch.IsMips32 {
-		expectedWitnessSize = 196
+		expectedWitnessSize = 200
 	}
 	require.Equal(t, expectedWitnessSize, STATE_WITNESS_SIZE)
+	require.Equal(t, STATE_WITNESS_SIZE, StateWitnessSize())
 }
 
 func TestThreadStateWitnessSize(t *testing.T) {
-	expectedWitnessSize := 166
+	expectedWitnessSize := 170
 	if !arch.IsMips32 {
-		expectedWitnessSize = 322
+		expectedWitnessSize = 326
 	}
 	require.Equal(t, expectedWitnessSize, SERIALIZED_THREAD_SIZE)
+	require.Equal(t, SERIALIZED_THREAD_SIZE, SerializedThreadSize())
+	require.Equal(t, THREAD_WITNESS_SIZE, ThreadWitnessSize())
+	require.Equal(t, expectedWitnessSize+32, ThreadWitnessSize())
+}
+
+func TestState_WitnessFields(t *testing.T) {
+	state := CreateEmptyState()
+	fields := state.WitnessFields()
+	offset := 0
+	for _, f := range fields {
+		require.Equalf(t, offset, f.Offset, "field %s must follow the previous field", f.Name)
+		offset += f.Size
+	}
+	require.Equal(t, STATE_WITNESS_SIZE, offset, "fields must cover the witness")
+
+	// Each field is named after the state field it encodes
+	modify := map[string]func(s *State){
+		"MemRoot":                     func(s *State) { s.Memory.SetWord(0x1000, 1) },
+		"PreimageKey":                 func(s *State) { s.PreimageKey[0] = 1 },
+		"PreimageOffset":              func(s *State) { s.PreimageOffset = 1 },
+		"Heap":                        func(s *State) { s.Heap = 1 },
+		"LLReservationStatus":         func(s *State) { s.LLReservationStatus = LLStatusActive32bit },
+		"LLAddress":                   func(s *State) { s.LLAddress = 1 },
+		"LLOwnerThread":               func(s *State) { s.LLOwnerThread = 1 },
+		"ExitCode":                    func(s *State) { s.ExitCode = 1 },
+		"Exited":                      func(s *State) { s.Exited = true },
+		"Step":                        func(s *State) { s.Step = 1 },
+		"StepsSinceLastContextSwitch": func(s *State) { s.StepsSinceLastContextSwitch = 1 },
+		"Wakeup":                      func(s *State) { s.Wakeup = 1 },
+		"WakeupBitset":                func(s *State) { s.WakeupBitset = 1 },
+		"TraverseRight":               func(s *State) { s.TraverseRight = true },
+		"LeftThreadStack":             func(s *State) { s.LeftThreadStack[0].Registers[1] = 1 },
+		"RightThreadStack":            func(s *State) { s.RightThreadStack = []*ThreadState{CreateEmptyThread()} },
+		"NextThreadId":                func(s *State) { s.NextThreadId = 2 },
+	}
+	require.Len(t, modify, len(fields))
+	pre, _ := state.EncodeWitness()
+	for _, f := range fields {
+		s := CreateEmptyState()
+		modify[f.Name](s)
+		post, _ := s.EncodeWitness()
+		for _, other := range fields {
+			changed := !bytes.Equal(pre[other.Offset:other.Offset+other.Size], post[other.Offset:other.Offset+other.Size])
+			require.Equalf(t, other.Name == f.Name, changed, "field %s after modifying %s", other.Name, f.Name)
+		}
+	}
 }
@@ -0,0 +1,27 @@
+package multithreaded
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// StepsBetween steps m, which must be at the state with hash preHash, until it reaches the state with hash postHash,
+// and returns the number of steps taken. State hashes do not order steps, so the search is linear: an error is
+// returned if postHash is not reached within maxSteps steps or before the VM exits.
+func StepsBetween(m *InstrumentedState, preHash, postHash common.Hash, maxSteps uint64) (uint64, error) {
+	if _, hash := m.state.EncodeWitness(); hash != preHash {
+		return 0, fmt.Errorf("state hash %s does not match pre-state hash %s", hash, preHash)
+	}
+	for i := uint64(0); ; i++ {
+		if _, hash := m.state.EncodeWitness(); hash == postHash {
+			return i, nil
+		}
+		if i == maxSteps || m.state.Exited {
+			return 0, fmt.Errorf("post-state %s not reached after %d steps", postHash, i)
+		}
+		if _, err := m.Step(false); err != nil {
+			return 0, err
+		}
+	}
+}
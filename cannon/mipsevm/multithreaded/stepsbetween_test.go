@@ -0,0 +1,49 @@
+package multithreaded
+
+import (
+	"io"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestStepsBetween(t *testing.T) {
+	newVM := func() (*InstrumentedState, *State) {
+		state, meta := testutil.LoadELFProgram(t, testutil.ProgramPath("claim"), CreateInitialState, false)
+		oracle, _, _ := testutil.ClaimTestOracle(t)
+		return NewInstrumentedState(state, oracle, io.Discard, io.Discard, testutil.CreateLogger(), meta), state
+	}
+	runTo := func(step uint64) common.Hash {
+		us, state := newVM()
+		for state.Step < step {
+			_, err := us.Step(false)
+			require.NoError(t, err)
+		}
+		return state.StateHash()
+	}
+	preHash := runTo(1000)
+	postHash := runTo(1234)
+
+	us, state := newVM()
+	_, err := StepsBetween(us, preHash, postHash, 1000)
+	require.ErrorContains(t, err, "does not match pre-state hash")
+	for state.Step < 1000 {
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	}
+
+	steps, err := StepsBetween(us, preHash, postHash, 1000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(234), steps)
+	require.Equal(t, uint64(1234), state.Step)
+
+	steps, err = StepsBetween(us, postHash, postHash, 0)
+	require.NoError(t, err)
+	require.Zero(t, steps)
+
+	_, err = StepsBetween(us, postHash, preHash, 100)
+	require.ErrorContains(t, err, "not reached after 100 steps")
+}
@@ -32,6 +32,7 @@ type ExpectedMTState struct {
 	// Threading-related expectations
 	StepsSinceLastContextSwitch uint64
 	Wakeup                      arch.Word
+	WakeupBitset                uint32
 	TraverseRight               bool
 	NextThreadId                arch.Word
 	ThreadCount                 int
@@ -50,6 +51,7 @@ type ExpectedThreadState struct {
 	FutexAddr        arch.Word
 	FutexVal         arch.Word
 	FutexTimeoutStep uint64
+	FutexBitset      uint32
 	PC               arch.Word
 	NextPC           arch.Word
 	HI               arch.Word
@@ -82,6 +84,7 @@ func NewExpectedMTState(fromState *multithreaded.State) *ExpectedMTState {
 		// Thread-related global fields
 		StepsSinceLastContextSwitch: fromState.StepsSinceLastContextSwitch,
 		Wakeup:                      fromState.Wakeup,
+		WakeupBitset:                fromState.WakeupBitset,
 		TraverseRight:               fromState.TraverseRight,
 		NextThreadId:                fromState.NextThreadId,
 		ThreadCount:                 fromState.ThreadCount(),
@@ -104,6 +107,7 @@ func newExpectedThreadState(fromThread *multithreaded.ThreadState) *ExpectedThre
 		FutexAddr:        fromThread.FutexAddr,
 		FutexVal:         fromThread.FutexVal,
 		FutexTimeoutStep: fromThread.FutexTimeoutStep,
+		FutexBitset:      fromThread.FutexBitset,
 		PC:               fromThread.Cpu.PC,
 		NextPC:           fromThread.Cpu.NextPC,
 		HI:               fromThread.Cpu.HI,
@@ -194,6 +198,7 @@ func (e *ExpectedMTState) Validate(t require.TestingT, actualState *multithreade
 	// Thread-related global fields
 	require.Equalf(t, e.StepsSinceLastContextSwitch, actualState.StepsSinceLastContextSwitch, "Expect StepsSinceLastContextSwitch = %v", e.StepsSinceLastContextSwitch)
 	require.Equalf(t, e.Wakeup, actualState.Wakeup, "Expect Wakeup = %v", e.Wakeup)
+	require.Equalf(t, e.WakeupBitset, actualState.WakeupBitset, "Expect WakeupBitset = %v", e.WakeupBitset)
 	require.Equalf(t, e.TraverseRight, actualState.TraverseRight, "Expect TraverseRight = %v", e.TraverseRight)
 	require.Equalf(t, e.NextThreadId, actualState.NextThreadId, "Expect NextThreadId = %v", e.NextThreadId)
 	require.Equalf(t, e.ThreadCount, actualState.ThreadCount(), "Expect thread count = %v", e.ThreadCount)
@@ -232,4 +237,5 @@ func (e *ExpectedMTState) validateThread(t require.TestingT, et *ExpectedThreadS
 	require.Equalf(t, et.FutexAddr, actual.FutexAddr, "Expect futexAddr = %v (%v)", et.FutexAddr, threadInfo)
 	require.Equalf(t, et.FutexVal, actual.FutexVal, "Expect futexVal = %v (%v)", et.FutexVal, threadInfo)
 	require.Equalf(t, et.FutexTimeoutStep, actual.FutexTimeoutStep, "Expect futexTimeoutStep = %v (%v)", et.FutexTimeoutStep, threadInfo)
+	require.Equalf(t, et.FutexBitset, actual.FutexBitset, "Expect futexBitset = %v (%v)", et.FutexBitset, threadInfo)
 }
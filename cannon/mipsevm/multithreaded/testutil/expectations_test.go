@@ -39,6 +39,7 @@ func TestValidate_shouldCatchMutations(t *testing.T) {
 		{name: "MemoryRoot", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.MemoryRoot = emptyHash }},
 		{name: "StepsSinceLastContextSwitch", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.StepsSinceLastContextSwitch += 1 }},
 		{name: "Wakeup", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.Wakeup += 1 }},
+		{name: "WakeupBitset", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.WakeupBitset += 1 }},
 		{name: "TraverseRight", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.TraverseRight = !e.TraverseRight }},
 		{name: "NextThreadId", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.NextThreadId += 1 }},
 		{name: "ThreadCount", mut: func(e *ExpectedMTState, st *multithreaded.State) { e.ThreadCount += 1 }},
@@ -69,6 +70,9 @@ func TestValidate_shouldCatchMutations(t *testing.T) {
 		{name: "Active thread FutexTimeoutStep", mut: func(e *ExpectedMTState, st *multithreaded.State) {
 			e.threadExpectations[st.GetCurrentThread().ThreadId].FutexTimeoutStep += 1
 		}},
+		{name: "Active thread FutexBitset", mut: func(e *ExpectedMTState, st *multithreaded.State) {
+			e.threadExpectations[st.GetCurrentThread().ThreadId].FutexBitset += 1
+		}},
 		{name: "Active thread PC", mut: func(e *ExpectedMTState, st *multithreaded.State) {
 			e.threadExpectations[st.GetCurrentThread().ThreadId].PC += 1
 		}},
@@ -105,6 +109,9 @@ func TestValidate_shouldCatchMutations(t *testing.T) {
 		{name: "Inactive thread FutexTimeoutStep", mut: func(e *ExpectedMTState, st *multithreaded.State) {
 			e.threadExpectations[FindNextThread(st).ThreadId].FutexTimeoutStep += 1
 		}},
+		{name: "Inactive thread FutexBitset", mut: func(e *ExpectedMTState, st *multithreaded.State) {
+			e.threadExpectations[FindNextThread(st).ThreadId].FutexBitset += 1
+		}},
 		{name: "Inactive thread PC", mut: func(e *ExpectedMTState, st *multithreaded.State) {
 			e.threadExpectations[FindNextThread(st).ThreadId].PC += 1
 		}},
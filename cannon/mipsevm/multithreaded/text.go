@@ -0,0 +1,39 @@
+package multithreaded
+
+import (
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
+)
+
+// WriteToTextError is returned by Step when write detection is enabled with SetTextSegments and the step stored to
+// a read-only program segment. Addr is the word-aligned address of the store.
+type WriteToTextError struct {
+	Addr Word
+}
+
+func (e *WriteToTextError) Error() string {
+	return fmt.Sprintf("write to read-only text segment at %#x", e.Addr)
+}
+
+// SetTextSegments enables detecting writes to the given read-only program segments, see program.TextSegments.
+// Step fails with a *WriteToTextError after a step that stores to a word overlapping one of them; the store itself
+// is still applied. The on-chain VM does not enforce segment permissions, so this is an off-chain debugging aid.
+// Nil segments disable detection, the default.
+func (m *InstrumentedState) SetTextSegments(segments []program.TextSegment) {
+	m.textSegments = segments
+}
+
+// checkTextWrite records a store to the size bytes at effMemAddr if it overlaps a text segment.
+// Only the first such store of a step is reported.
+func (m *InstrumentedState) checkTextWrite(effMemAddr Word, size Word) {
+	if m.textWrite != nil {
+		return
+	}
+	for _, seg := range m.textSegments {
+		if effMemAddr < seg.End && effMemAddr+(size-1) >= seg.Start {
+			m.textWrite = &WriteToTextError{Addr: effMemAddr}
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package multithreaded
+
+import (
+	"debug/elf"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
+)
+
+func TestInstrumentedState_WriteToText(t *testing.T) {
+	f, err := elf.Open(testutil.ProgramPath("hello"))
+	require.NoError(t, err)
+	defer f.Close()
+	segments := program.TextSegments(f)
+	require.NotEmpty(t, segments)
+
+	newVM := func(target Word) *InstrumentedState {
+		state, err := program.LoadELF(f, CreateInitialState)
+		require.NoError(t, err)
+		testutil.StoreInstruction(state.Memory, state.GetPC(), 0xad_00_00_00) // sw $zero, 0($t0)
+		state.GetRegistersRef()[8] = target
+		return NewInstrumentedState(state, nil, io.Discard, io.Discard, testutil.CreateLogger(), nil)
+	}
+	text := segments[0].Start
+	data := Word(0x1000)
+	for _, seg := range segments {
+		require.False(t, seg.Contains(data))
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		us := newVM(text)
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("write to text", func(t *testing.T) {
+		us := newVM(text)
+		us.SetTextSegments(segments)
+		_, err := us.Step(false)
+		var textErr *WriteToTextError
+		require.ErrorAs(t, err, &textErr)
+		require.Equal(t, text&arch.AddressMask, textErr.Addr)
+
+		// Detection does not prevent the store, nor carry over to later steps
+		stored, err := io.ReadAll(us.state.Memory.ReadMemoryRange(text, 4))
+		require.NoError(t, err)
+		require.Equal(t, make([]byte, 4), stored)
+		us.state.GetCurrentThread().Cpu.PC = us.state.GetPC() - 4
+		us.state.GetCurrentThread().Cpu.NextPC = us.state.GetPC() + 4
+		us.state.GetRegistersRef()[8] = data
+		_, err = us.Step(false)
+		require.NoError(t, err)
+	})
+
+	t.Run("write outside text", func(t *testing.T) {
+		us := newVM(data)
+		us.SetTextSegments(segments)
+		_, err := us.Step(false)
+		require.NoError(t, err)
+	})
+}
@@ -19,11 +19,12 @@ const (
 	THREAD_FUTEX_ADDR_WITNESS_OFFSET         = THREAD_EXITED_WITNESS_OFFSET + 1
 	THREAD_FUTEX_VAL_WITNESS_OFFSET          = THREAD_FUTEX_ADDR_WITNESS_OFFSET + arch.WordSizeBytes
 	THREAD_FUTEX_TIMEOUT_STEP_WITNESS_OFFSET = THREAD_FUTEX_VAL_WITNESS_OFFSET + arch.WordSizeBytes
-	THREAD_FUTEX_CPU_WITNESS_OFFSET          = THREAD_FUTEX_TIMEOUT_STEP_WITNESS_OFFSET + 8
+	THREAD_FUTEX_BITSET_WITNESS_OFFSET       = THREAD_FUTEX_TIMEOUT_STEP_WITNESS_OFFSET + 8
+	THREAD_FUTEX_CPU_WITNESS_OFFSET          = THREAD_FUTEX_BITSET_WITNESS_OFFSET + 4
 	THREAD_REGISTERS_WITNESS_OFFSET          = THREAD_FUTEX_CPU_WITNESS_OFFSET + (4 * arch.WordSizeBytes)
 
 	// SERIALIZED_THREAD_SIZE is the size of a serialized ThreadState object
-	// 166 and 322 bytes for 32 and 64-bit respectively
+	// 170 and 326 bytes for 32 and 64-bit respectively
 	SERIALIZED_THREAD_SIZE = THREAD_REGISTERS_WITNESS_OFFSET + (32 * arch.WordSizeBytes)
 
 	// THREAD_WITNESS_SIZE is the size of a thread witness encoded in bytes.
@@ -33,6 +34,16 @@ const (
 	THREAD_WITNESS_SIZE = SERIALIZED_THREAD_SIZE + 32
 )
 
+// SerializedThreadSize returns SERIALIZED_THREAD_SIZE, for code that handles threads of both the 32 and 64-bit builds.
+func SerializedThreadSize() int {
+	return SERIALIZED_THREAD_SIZE
+}
+
+// ThreadWitnessSize returns THREAD_WITNESS_SIZE, for code that handles witnesses of both the 32 and 64-bit builds.
+func ThreadWitnessSize() int {
+	return THREAD_WITNESS_SIZE
+}
+
 // The empty thread root - keccak256(bytes32(0) ++ bytes32(0))
 var EmptyThreadsRoot common.Hash = common.HexToHash("0xad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5")
 
@@ -43,6 +54,7 @@ type ThreadState struct {
 	FutexAddr        Word               `json:"futexAddr"`
 	FutexVal         Word               `json:"futexVal"`
 	FutexTimeoutStep uint64             `json:"futexTimeoutStep"`
+	FutexBitset      uint32             `json:"futexBitset"`
 	Cpu              mipsevm.CpuScalars `json:"cpu"`
 	Registers        [32]Word           `json:"registers"`
 }
@@ -62,6 +74,7 @@ func CreateEmptyThread() *ThreadState {
 		FutexAddr:        exec.FutexEmptyAddr,
 		FutexVal:         0,
 		FutexTimeoutStep: 0,
+		FutexBitset:      0,
 		Registers:        [32]Word{},
 	}
 }
@@ -75,6 +88,7 @@ func (t *ThreadState) serializeThread() []byte {
 	out = arch.ByteOrderWord.AppendWord(out, t.FutexAddr)
 	out = arch.ByteOrderWord.AppendWord(out, t.FutexVal)
 	out = binary.BigEndian.AppendUint64(out, t.FutexTimeoutStep)
+	out = binary.BigEndian.AppendUint32(out, t.FutexBitset)
 
 	out = arch.ByteOrderWord.AppendWord(out, t.Cpu.PC)
 	out = arch.ByteOrderWord.AppendWord(out, t.Cpu.NextPC)
@@ -116,6 +130,9 @@ func (t *ThreadState) Deserialize(in io.Reader) error {
 	if err := binary.Read(in, binary.BigEndian, &t.FutexTimeoutStep); err != nil {
 		return err
 	}
+	if err := binary.Read(in, binary.BigEndian, &t.FutexBitset); err != nil {
+		return err
+	}
 	if err := binary.Read(in, binary.BigEndian, &t.Cpu.PC); err != nil {
 		return err
 	}
@@ -0,0 +1,79 @@
+package multithreaded
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+)
+
+// vectorThread returns a thread with every field set to a distinct, non-zero value.
+func vectorThread(id Word) *ThreadState {
+	thread := CreateEmptyThread()
+	thread.ThreadId = id
+	thread.ExitCode = 3
+	thread.Exited = true
+	thread.FutexAddr = 0x1000
+	thread.FutexVal = 0x2a
+	thread.FutexTimeoutStep = 0x1122334455
+	thread.FutexBitset = 0x0ff0
+	thread.Cpu.PC = 0x4000 + 8*id
+	thread.Cpu.NextPC = thread.Cpu.PC + 4
+	thread.Cpu.LO = 0xbeef
+	thread.Cpu.HI = 0xdead
+	for i := range thread.Registers {
+		thread.Registers[i] = Word(i)*0x10 + id
+	}
+	return thread
+}
+
+// The thread stack root scheme must match the on-chain implementation exactly.
+// These vectors must only change together with the contracts.
+func TestComputeThreadRoot_Vectors(t *testing.T) {
+	singleRoot := common.HexToHash("0x66ff64c596a95b2cc1bae35713164ca1d61f75186e91d0356c2160044b62e6da")
+	stackRoot := common.HexToHash("0xef9162e8bfc71b150da25d47f99878b82f14b83bc0f3a72d2ea9c2c57e102ad1")
+	if !arch.IsMips32 {
+		singleRoot = common.HexToHash("0xd2515701f530fa750eb0cb4ff82ef7a62df04e9944e9a9af0d04fc1a752a097b")
+		stackRoot = common.HexToHash("0x0f5764d80b4e9aa864b0eb6c2b47600809d8e839577a8b602427aacad0efe2bc")
+	}
+
+	t.Run("single thread", func(t *testing.T) {
+		root := computeThreadRoot(EmptyThreadsRoot, vectorThread(0))
+		require.Equal(t, singleRoot, root)
+	})
+
+	t.Run("thread stack", func(t *testing.T) {
+		state := CreateEmptyState()
+		stack := []*ThreadState{vectorThread(0), vectorThread(1), vectorThread(2)}
+		root := state.calculateThreadStackRoot(stack)
+		require.Equal(t, stackRoot, root)
+		require.Equal(t, computeThreadRoot(computeThreadRoot(computeThreadRoot(EmptyThreadsRoot, stack[0]), stack[1]), stack[2]), root)
+	})
+}
+
+func TestSerializeThread_Layout(t *testing.T) {
+	thread := vectorThread(7)
+	out := thread.serializeThread()
+	require.Len(t, out, SERIALIZED_THREAD_SIZE)
+
+	// Each field must be found at its witness offset, so that adding a field forces the constants to be updated
+	word := func(offset int) Word { return arch.ByteOrderWord.Word(out[offset:]) }
+	require.Equal(t, thread.ThreadId, word(THREAD_ID_STATE_WITNESS_OFFSET))
+	require.Equal(t, thread.ExitCode, out[THREAD_EXIT_CODE_WITNESS_OFFSET])
+	require.Equal(t, byte(1), out[THREAD_EXITED_WITNESS_OFFSET])
+	require.Equal(t, thread.FutexAddr, word(THREAD_FUTEX_ADDR_WITNESS_OFFSET))
+	require.Equal(t, thread.FutexVal, word(THREAD_FUTEX_VAL_WITNESS_OFFSET))
+	require.Equal(t, thread.FutexTimeoutStep, binary.BigEndian.Uint64(out[THREAD_FUTEX_TIMEOUT_STEP_WITNESS_OFFSET:]))
+	require.Equal(t, thread.FutexBitset, binary.BigEndian.Uint32(out[THREAD_FUTEX_BITSET_WITNESS_OFFSET:]))
+	require.Equal(t, thread.Cpu.PC, word(THREAD_FUTEX_CPU_WITNESS_OFFSET))
+	require.Equal(t, thread.Cpu.HI, word(THREAD_REGISTERS_WITNESS_OFFSET-arch.WordSizeBytes))
+	require.Equal(t, thread.Registers[31], word(SERIALIZED_THREAD_SIZE-arch.WordSizeBytes))
+
+	state, err := NewStateWithThreads(memory.NewMemory(), []*ThreadState{vectorThread(1), thread}, nil, false, 8)
+	require.NoError(t, err)
+	require.Len(t, state.EncodeThreadProof(), THREAD_WITNESS_SIZE)
+}
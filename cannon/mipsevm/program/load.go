@@ -20,8 +20,94 @@ type Word = arch.Word
 
 type CreateInitialFPVMState[T mipsevm.FPVMState] func(pc, heapStart Word) T
 
+// EndiannessError is returned by LoadELF for a program whose data encoding differs from arch.ElfData.
+type EndiannessError struct {
+	Data elf.Data
+}
+
+func (e *EndiannessError) Error() string {
+	return fmt.Sprintf("unsupported ELF data encoding %s, only %s programs can be loaded", e.Data, arch.ElfData)
+}
+
+// SegmentAlignmentError is returned by LoadELF for a segment that violates the ELF alignment constraints.
+type SegmentAlignmentError struct {
+	SegIndex int
+	Vaddr    uint64
+	Off      uint64
+	Align    uint64
+}
+
+func (e *SegmentAlignmentError) Error() string {
+	if e.Align&(e.Align-1) != 0 {
+		return fmt.Sprintf("program segment %d has unsupported alignment %d: must be a power of two", e.SegIndex, e.Align)
+	}
+	return fmt.Sprintf("program segment %d is misaligned: vaddr %x and file offset %x differ modulo alignment %x", e.SegIndex, e.Vaddr, e.Off, e.Align)
+}
+
+// SegmentSizeMismatchError is returned by LoadELF for a segment whose file size cannot be extended to its memory size.
+type SegmentSizeMismatchError struct {
+	SegIndex int
+	Type     elf.ProgType
+	Filesz   uint64
+	Memsz    uint64
+}
+
+func (e *SegmentSizeMismatchError) Error() string {
+	if e.Type == elf.PT_LOAD {
+		return fmt.Sprintf("invalid PT_LOAD program segment %d, file size (%d) > mem size (%d)", e.SegIndex, e.Filesz, e.Memsz)
+	}
+	return fmt.Sprintf("program segment %d has different file size (%d) than mem size (%d): filling for non PT_LOAD segments is not supported", e.SegIndex, e.Filesz, e.Memsz)
+}
+
+// SegmentRangeError is returned by LoadELF for a segment that does not fit in the address space.
+type SegmentRangeError struct {
+	SegIndex int
+	Vaddr    uint64
+	Size     uint64
+}
+
+func (e *SegmentRangeError) Error() string {
+	return fmt.Sprintf("program %d out of memory range: %x - %x (size: %x)", e.SegIndex, e.Vaddr, e.Vaddr+e.Size-1, e.Size)
+}
+
+// HeapOverlapError is returned by LoadELF for a segment that extends into the heap.
+type HeapOverlapError struct {
+	SegIndex int
+	Vaddr    uint64
+	Size     uint64
+}
+
+func (e *HeapOverlapError) Error() string {
+	return fmt.Sprintf("program %d overlaps with heap: %x - %x (size: %x). The heap start offset must be reconfigured", e.SegIndex, e.Vaddr, e.Vaddr+e.Size-1, e.Size)
+}
+
+// SegmentReadError is returned by LoadELF when the contents of a segment cannot be read.
+type SegmentReadError struct {
+	SegIndex int
+	Err      error
+}
+
+func (e *SegmentReadError) Error() string {
+	return fmt.Sprintf("failed to read program segment %d: %v", e.SegIndex, e.Err)
+}
+
+func (e *SegmentReadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadELF loads the program segments of f into a new state created with initState.
+// Segments do not need to be page-aligned: a segment starting mid-page is copied byte-for-byte to its virtual
+// address, and the rest of the page keeps its previous contents (zero, unless another segment wrote to it).
+// Segments must however satisfy the ELF alignment constraints: a non-trivial alignment is a power of two,
+// and the virtual address is congruent to the file offset modulo the alignment.
+// The program must be big-endian, see arch.ElfData.
+// If a segment cannot be loaded, the error is returned without a state. The memory of the state created with
+// initState may then contain the segments loaded before, but not part of the failing segment.
 func LoadELF[T mipsevm.FPVMState](f *elf.File, initState CreateInitialFPVMState[T]) (T, error) {
 	var empty T
+	if f.Data != arch.ElfData {
+		return empty, &EndiannessError{Data: f.Data}
+	}
 	s := initState(Word(f.Entry), HEAP_START)
 
 	for i, prog := range f.Progs {
@@ -29,17 +115,16 @@ func LoadELF[T mipsevm.FPVMState](f *elf.File, initState CreateInitialFPVMState[
 			continue
 		}
 
+		if prog.Align > 1 && (prog.Align&(prog.Align-1) != 0 || prog.Vaddr%prog.Align != prog.Off%prog.Align) {
+			return empty, &SegmentAlignmentError{SegIndex: i, Vaddr: prog.Vaddr, Off: prog.Off, Align: prog.Align}
+		}
+
 		r := io.Reader(io.NewSectionReader(prog, 0, int64(prog.Filesz)))
 		if prog.Filesz != prog.Memsz {
-			if prog.Type == elf.PT_LOAD {
-				if prog.Filesz < prog.Memsz {
-					r = io.MultiReader(r, bytes.NewReader(make([]byte, prog.Memsz-prog.Filesz)))
-				} else {
-					return empty, fmt.Errorf("invalid PT_LOAD program segment %d, file size (%d) > mem size (%d)", i, prog.Filesz, prog.Memsz)
-				}
-			} else {
-				return empty, fmt.Errorf("program segment %d has different file size (%d) than mem size (%d): filling for non PT_LOAD segments is not supported", i, prog.Filesz, prog.Memsz)
+			if prog.Type != elf.PT_LOAD || prog.Filesz > prog.Memsz {
+				return empty, &SegmentSizeMismatchError{SegIndex: i, Type: prog.Type, Filesz: prog.Filesz, Memsz: prog.Memsz}
 			}
+			r = io.MultiReader(r, bytes.NewReader(make([]byte, prog.Memsz-prog.Filesz)))
 		}
 
 		if prog.Memsz == 0 {
@@ -59,15 +144,39 @@ func LoadELF[T mipsevm.FPVMState](f *elf.File, initState CreateInitialFPVMState[
 
 		lastByteToWrite := prog.Vaddr + prog.Memsz - 1
 		if lastByteToWrite > lastMemoryAddr || lastByteToWrite < prog.Vaddr {
-			return empty, fmt.Errorf("program %d out of memory range: %x - %x (size: %x)", i, prog.Vaddr, lastByteToWrite, prog.Memsz)
+			return empty, &SegmentRangeError{SegIndex: i, Vaddr: prog.Vaddr, Size: prog.Memsz}
 		}
 		if lastByteToWrite >= HEAP_START {
-			return empty, fmt.Errorf("program %d overlaps with heap: %x - %x (size: %x). The heap start offset must be reconfigured", i, prog.Vaddr, lastByteToWrite, prog.Memsz)
+			return empty, &HeapOverlapError{SegIndex: i, Vaddr: prog.Vaddr, Size: prog.Memsz}
 		}
 		if err := s.GetMemory().SetMemoryRange(Word(prog.Vaddr), r); err != nil {
-			return empty, fmt.Errorf("failed to read program segment %d: %w", i, err)
+			return empty, &SegmentReadError{SegIndex: i, Err: err}
 		}
 	}
 
 	return s, nil
 }
+
+// TextSegment is the address range [Start, End) of a loaded program segment that is executable and not writable.
+type TextSegment struct {
+	Start Word
+	End   Word
+}
+
+// Contains returns whether addr lies within the segment.
+func (s TextSegment) Contains(addr Word) bool {
+	return addr >= s.Start && addr < s.End
+}
+
+// TextSegments returns the address ranges of the PT_LOAD segments of f that are executable and not writable,
+// as loaded by LoadELF. Empty segments are omitted.
+func TextSegments(f *elf.File) []TextSegment {
+	var out []TextSegment
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Flags&elf.PF_X == 0 || prog.Flags&elf.PF_W != 0 || prog.Memsz == 0 {
+			continue
+		}
+		out = append(out, TextSegment{Start: Word(prog.Vaddr), End: Word(prog.Vaddr + prog.Memsz)})
+	}
+	return out
+}
@@ -1,13 +1,16 @@
 package program
 
 import (
+	"bytes"
 	"debug/elf"
+	"errors"
 	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program/testutil"
 )
 
@@ -81,3 +84,188 @@ func TestLoadELF(t *testing.T) {
 		})
 	}
 }
+
+func TestTextSegments(t *testing.T) {
+	data := make([]byte, 0x100)
+	newProg := func(progType elf.ProgType, flags elf.ProgFlag, vAddr uint64) *elf.Prog {
+		prog, _ := testutil.MockProgWithReader(progType, 0x100, 0x100, vAddr, data)
+		prog.Flags = flags
+		return prog
+	}
+	f := testutil.MockELFFile([]*elf.Prog{
+		newProg(elf.PT_LOAD, elf.PF_R|elf.PF_X, 0x1000),
+		newProg(elf.PT_LOAD, elf.PF_R|elf.PF_W, 0x2000),
+		newProg(elf.PT_LOAD, elf.PF_R|elf.PF_W|elf.PF_X, 0x3000),
+		newProg(elf.PT_MIPS_ABIFLAGS, elf.PF_R|elf.PF_X, 0x4000),
+		newProg(elf.PT_LOAD, elf.PF_X, 0x5000),
+	})
+
+	segments := TextSegments(f)
+	require.Equal(t, []TextSegment{{Start: 0x1000, End: 0x1100}, {Start: 0x5000, End: 0x5100}}, segments)
+	require.True(t, segments[0].Contains(0x1000))
+	require.True(t, segments[0].Contains(0x10ff))
+	require.False(t, segments[0].Contains(0x1100))
+	require.False(t, segments[0].Contains(0xfff))
+}
+
+func TestLoadELF_SegmentAlignment(t *testing.T) {
+	data := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	dataSize := uint64(len(data))
+
+	tests := []struct {
+		name        string
+		vAddr       uint64
+		offset      uint64
+		align       uint64
+		expectedErr string
+	}{
+		{name: "Page-aligned segment", vAddr: 0x4000, offset: 0x1000, align: 0x1000},
+		{name: "Unaligned segment, no alignment constraint", vAddr: 0x4003, offset: 0x10, align: 0},
+		{name: "Unaligned segment, congruent to offset", vAddr: 0x4ffd, offset: 0xffd, align: 0x1000},
+		{name: "Non power-of-two alignment", vAddr: 0x3000, offset: 0x3000, align: 0x3000, expectedErr: "unsupported alignment 12288"},
+		{name: "Segment not congruent to offset", vAddr: 0x4003, offset: 0x1000, align: 0x1000, expectedErr: "vaddr 4003 and file offset 1000 differ modulo alignment 1000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, _ := testutil.MockProgWithReader(elf.PT_LOAD, dataSize, dataSize, tt.vAddr, data)
+			prog.Off = tt.offset
+			prog.Align = tt.align
+			state, err := LoadELF(testutil.MockELFFile([]*elf.Prog{prog}), testutil.MockCreateInitState)
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The segment is written at its exact address, possibly spanning pages, with the surrounding bytes zeroed
+			start := arch.Word(tt.vAddr) &^ 0xfff
+			end := (arch.Word(tt.vAddr+dataSize) + 0xfff) &^ 0xfff
+			expected := make([]byte, end-start)
+			copy(expected[tt.vAddr-uint64(start):], data)
+			actual, err := io.ReadAll(state.GetMemory().ReadMemoryRange(start, end-start))
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestLoadELF_Endianness(t *testing.T) {
+	data := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	prog, reader := testutil.MockProgWithReader(elf.PT_LOAD, uint64(len(data)), uint64(len(data)), 0x4000, data)
+	f := testutil.MockELFFile([]*elf.Prog{prog})
+	f.Data = elf.ELFDATA2LSB
+
+	_, err := LoadELF(f, testutil.MockCreateInitState)
+	var endianErr *EndiannessError
+	require.ErrorAs(t, err, &endianErr)
+	require.Equal(t, elf.ELFDATA2LSB, endianErr.Data)
+	require.ErrorContains(t, err, "only ELFDATA2MSB programs can be loaded")
+	require.Zero(t, reader.BytesRead)
+
+	// Loaded big-endian data is packed into memory words in the same byte order
+	f.Data = elf.ELFDATA2MSB
+	state, err := LoadELF(f, testutil.MockCreateInitState)
+	require.NoError(t, err)
+	var word [arch.WordSizeBytes]byte
+	arch.ByteOrderWord.PutWord(word[:], state.GetMemory().GetWord(0x4000))
+	require.Equal(t, data[:arch.WordSizeBytes], word[:])
+}
+
+type failingReaderAt struct {
+	err error
+}
+
+func (r failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return 0, r.err
+}
+
+// partialReaderAt reads data, and fails with err beyond it.
+type partialReaderAt struct {
+	data []byte
+	err  error
+}
+
+func (r partialReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(r.data)) {
+		return 0, r.err
+	}
+	n := copy(p, r.data[off:])
+	if n < len(p) {
+		return n, r.err
+	}
+	return n, nil
+}
+
+func TestLoadELF_ErrorTypes(t *testing.T) {
+	data := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	dataSize := uint64(len(data))
+
+	load := func(prog *elf.Prog) error {
+		_, err := LoadELF(testutil.MockELFFile([]*elf.Prog{prog}), testutil.MockCreateInitState)
+		return err
+	}
+
+	t.Run("alignment", func(t *testing.T) {
+		prog, _ := testutil.MockProgWithReader(elf.PT_LOAD, dataSize, dataSize, 0x4003, data)
+		prog.Off = 0x1000
+		prog.Align = 0x1000
+		var alignErr *SegmentAlignmentError
+		require.ErrorAs(t, load(prog), &alignErr)
+		require.Equal(t, &SegmentAlignmentError{SegIndex: 0, Vaddr: 0x4003, Off: 0x1000, Align: 0x1000}, alignErr)
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		prog, _ := testutil.MockProgWithReader(elf.PT_DYNAMIC, dataSize, dataSize*2, 0x4000, data)
+		var sizeErr *SegmentSizeMismatchError
+		require.ErrorAs(t, load(prog), &sizeErr)
+		require.Equal(t, &SegmentSizeMismatchError{SegIndex: 0, Type: elf.PT_DYNAMIC, Filesz: dataSize, Memsz: dataSize * 2}, sizeErr)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		vAddr := uint64(^uint32(0))
+		if !arch.IsMips32 {
+			vAddr = (1 << 48) - 1
+		}
+		prog, _ := testutil.MockProgWithReader(elf.PT_LOAD, dataSize, dataSize, vAddr, data)
+		var rangeErr *SegmentRangeError
+		require.ErrorAs(t, load(prog), &rangeErr)
+		require.Equal(t, &SegmentRangeError{SegIndex: 0, Vaddr: vAddr, Size: dataSize}, rangeErr)
+	})
+
+	t.Run("heap overlap", func(t *testing.T) {
+		vAddr := uint64(HEAP_START - 1)
+		prog, _ := testutil.MockProgWithReader(elf.PT_LOAD, dataSize, dataSize, vAddr, data)
+		var heapErr *HeapOverlapError
+		require.ErrorAs(t, load(prog), &heapErr)
+		require.Equal(t, &HeapOverlapError{SegIndex: 0, Vaddr: vAddr, Size: dataSize}, heapErr)
+	})
+
+	t.Run("read failure", func(t *testing.T) {
+		readErr := errors.New("read failed")
+		ok, _ := testutil.MockProgWithReader(elf.PT_LOAD, dataSize, dataSize, 0x4000, data)
+		failing := testutil.MockProg(elf.PT_LOAD, dataSize, dataSize, 0x8000)
+		failing.ReaderAt = failingReaderAt{err: readErr}
+		_, err := LoadELF(testutil.MockELFFile([]*elf.Prog{ok, failing}), testutil.MockCreateInitState)
+		var segErr *SegmentReadError
+		require.ErrorAs(t, err, &segErr)
+		require.Equal(t, 1, segErr.SegIndex)
+		require.ErrorIs(t, err, readErr)
+	})
+
+	t.Run("partial read failure", func(t *testing.T) {
+		readErr := errors.New("read failed")
+		// The segment fails after two of its three pages were read
+		prog := testutil.MockProg(elf.PT_LOAD, 3*memory.PageSize, 3*memory.PageSize, 0x8000)
+		prog.ReaderAt = partialReaderAt{data: bytes.Repeat([]byte{0xAA}, 2*memory.PageSize), err: readErr}
+		var initial *testutil.MockFPVMState
+		state, err := LoadELF(testutil.MockELFFile([]*elf.Prog{prog}), func(pc, heapStart Word) *testutil.MockFPVMState {
+			initial = testutil.MockCreateInitState(pc, heapStart)
+			return initial
+		})
+		require.ErrorIs(t, err, readErr)
+		require.Nil(t, state, "no partially loaded state is returned")
+		// The pages read before the failure are not written either
+		require.Equal(t, 0, initial.GetMemory().PageCount())
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"debug/elf"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
@@ -110,3 +111,62 @@ func pad(buf []byte) []byte {
 	bytesToAlignment := WordSizeBytes - len(buf)%WordSizeBytes
 	return append(buf, make([]byte, bytesToAlignment)...)
 }
+
+// maxStackStrings and maxStackStringLen bound ReadStackStrings, so that corrupt stack data cannot cause unbounded reads
+const (
+	maxStackStrings   = 1024
+	maxStackStringLen = 4096
+)
+
+// ReadStackStrings reads back the argv and envp strings from the initial stack at $sp, as laid out by PatchStack.
+// The stack starts with argc, followed by the null-terminated argv and envp pointer arrays.
+func ReadStackStrings(st mipsevm.FPVMState) (argv []string, envp []string, err error) {
+	mem := st.GetMemory()
+	sp := st.GetRegistersRef()[register.RegSP]
+	argc := mem.GetWord(sp)
+	if argc > maxStackStrings {
+		return nil, nil, fmt.Errorf("invalid argc %d", argc)
+	}
+	ptr := sp + WordSizeBytes
+	for i := Word(0); i < argc; i++ {
+		s, err := readCString(mem, mem.GetWord(ptr))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid argv[%d]: %w", i, err)
+		}
+		argv = append(argv, s)
+		ptr += WordSizeBytes
+	}
+	if term := mem.GetWord(ptr); term != 0 {
+		return nil, nil, fmt.Errorf("argv is not null-terminated, found 0x%x", term)
+	}
+	ptr += WordSizeBytes
+	for i := 0; ; i++ {
+		addr := mem.GetWord(ptr)
+		if addr == 0 {
+			break
+		}
+		if i == maxStackStrings {
+			return nil, nil, errors.New("envp is not null-terminated")
+		}
+		s, err := readCString(mem, addr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid envp[%d]: %w", i, err)
+		}
+		envp = append(envp, s)
+		ptr += WordSizeBytes
+	}
+	return argv, envp, nil
+}
+
+func readCString(mem *memory.Memory, addr Word) (string, error) {
+	var buf [maxStackStringLen]byte
+	n, err := io.ReadFull(mem.ReadMemoryRange(addr, maxStackStringLen), buf[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to read string at 0x%x: %w", addr, err)
+	}
+	end := bytes.IndexByte(buf[:n], 0)
+	if end < 0 {
+		return "", fmt.Errorf("string at 0x%x exceeds %d bytes", addr, maxStackStringLen)
+	}
+	return string(buf[:end]), nil
+}
@@ -0,0 +1,42 @@
+package program
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program/testutil"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/register"
+)
+
+func TestReadStackStrings(t *testing.T) {
+	t.Run("patched stack", func(t *testing.T) {
+		state := testutil.MockCreateInitState(0, 0)
+		require.NoError(t, PatchStack(state))
+		argv, envp, err := ReadStackStrings(state)
+		require.NoError(t, err)
+		require.Equal(t, []string{"op-program"}, argv)
+		require.Equal(t, []string{"GODEBUG=memprofilerate=0"}, envp)
+	})
+
+	t.Run("missing argv terminator", func(t *testing.T) {
+		state := testutil.MockCreateInitState(0, 0)
+		require.NoError(t, PatchStack(state))
+		sp := state.GetRegistersRef()[register.RegSP]
+		state.GetMemory().SetWord(sp+WordSizeBytes*2, 0x1234)
+		_, _, err := ReadStackStrings(state)
+		require.ErrorContains(t, err, "argv is not null-terminated")
+	})
+
+	t.Run("unterminated string", func(t *testing.T) {
+		state := testutil.MockCreateInitState(0, 0)
+		require.NoError(t, PatchStack(state))
+		sp := state.GetRegistersRef()[register.RegSP]
+		argv0 := state.GetMemory().GetWord(sp + WordSizeBytes)
+		for addr := argv0; addr < argv0+maxStackStringLen; addr += WordSizeBytes {
+			state.GetMemory().SetWord(addr, ^Word(0))
+		}
+		_, _, err := ReadStackStrings(state)
+		require.ErrorContains(t, err, "invalid argv[0]")
+	})
+}
@@ -0,0 +1,44 @@
+package program
+
+import (
+	"debug/elf"
+	"fmt"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+)
+
+// InstructionEncoding returns the fields of insn that select the operation: the opcode, and the funct field of
+// SPECIAL, SPECIAL2 and SPECIAL3 instructions or the rt field of REGIMM instructions. Operand fields are zeroed.
+func InstructionEncoding(insn uint32) uint32 {
+	switch insn >> 26 {
+	case 0x00, 0x1C, 0x1F: // SPECIAL, SPECIAL2, SPECIAL3
+		return insn & 0xFC_00_00_3F
+	case 0x01: // REGIMM
+		return insn & 0xFC_1F_00_00
+	default:
+		return insn & 0xFC_00_00_00
+	}
+}
+
+// ScanInstructions tallies the instruction encodings, see InstructionEncoding, of the executable segments of f.
+// The instructions are read from the memory of st, which must have been loaded from f, so that patches applied
+// to the program are taken into account.
+func ScanInstructions(f *elf.File, st mipsevm.FPVMState) (map[uint32]int, error) {
+	counts := make(map[uint32]int)
+	mem := st.GetMemory()
+	for i, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Flags&elf.PF_X == 0 {
+			continue
+		}
+		if prog.Vaddr%4 != 0 || prog.Filesz%4 != 0 {
+			return nil, fmt.Errorf("executable program segment %d is not instruction-aligned: vaddr %x, size %x", i, prog.Vaddr, prog.Filesz)
+		}
+		for addr := Word(prog.Vaddr); addr < Word(prog.Vaddr+prog.Filesz); addr += 4 {
+			word := mem.GetWord(addr & arch.AddressMask)
+			insn := uint32(word >> ((arch.WordSizeBytes - 4 - addr&arch.ExtMask) * 8))
+			counts[InstructionEncoding(insn)]++
+		}
+	}
+	return counts, nil
+}
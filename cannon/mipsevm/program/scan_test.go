@@ -0,0 +1,49 @@
+package program
+
+import (
+	"debug/elf"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program/testutil"
+)
+
+func TestInstructionEncoding(t *testing.T) {
+	require.Equal(t, uint32(0x00_00_00_21), InstructionEncoding(0x01_09_40_21)) // addu $t0, $t0, $t1
+	require.Equal(t, uint32(0x70_00_00_02), InstructionEncoding(0x71_09_40_02)) // mul $t0, $t0, $t1
+	require.Equal(t, uint32(0x04_01_00_00), InstructionEncoding(0x05_01_00_10)) // bgez $t0, 0x10
+	require.Equal(t, uint32(0x24_00_00_00), InstructionEncoding(0x25_08_00_2a)) // addiu $t0, $t0, 42
+}
+
+func TestScanInstructions(t *testing.T) {
+	path := "../../testdata/example/bin/hello.elf"
+	loadOp := uint32(0x23) // lw
+	if !arch.IsMips32 {
+		path = "../../testdata/example/bin/hello.64.elf"
+		loadOp = 0x37 // ld
+	}
+	f, err := elf.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	state, err := LoadELF(f, testutil.MockCreateInitState)
+	require.NoError(t, err)
+
+	counts, err := ScanInstructions(f, state)
+	require.NoError(t, err)
+
+	var total, textSize int
+	for _, n := range counts {
+		total += n
+	}
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_X != 0 {
+			textSize += int(prog.Filesz)
+		}
+	}
+	require.Equal(t, textSize/4, total)
+	require.Positive(t, counts[0x00_00_00_0c], "syscall")
+	require.Positive(t, counts[0x00_00_00_08], "jr")
+	require.Positive(t, counts[loadOp<<26], "load")
+}
@@ -15,7 +15,7 @@ import (
 
 // MockELFFile create a mock ELF file with custom program segments
 func MockELFFile(progs []*elf.Prog) *elf.File {
-	return &elf.File{Progs: progs}
+	return &elf.File{FileHeader: elf.FileHeader{Data: arch.ElfData}, Progs: progs}
 }
 
 // MockProg sets up a elf.Prog structure for testing
@@ -62,14 +62,15 @@ func MockCreateInitState(pc, heapStart arch.Word) *MockFPVMState {
 }
 
 type MockFPVMState struct {
-	memory *memory.Memory
+	memory    *memory.Memory
+	registers *[32]arch.Word
 }
 
 var _ mipsevm.FPVMState = (*MockFPVMState)(nil)
 
 func newMockFPVMState() *MockFPVMState {
 	mem := memory.NewMemory()
-	state := MockFPVMState{mem}
+	state := MockFPVMState{memory: mem, registers: new([32]arch.Word)}
 	return &state
 }
 
@@ -102,7 +103,7 @@ func (m MockFPVMState) GetCpu() mipsevm.CpuScalars {
 }
 
 func (m MockFPVMState) GetRegistersRef() *[32]arch.Word {
-	panic("not implemented")
+	return m.registers
 }
 
 func (m MockFPVMState) GetStep() uint64 {
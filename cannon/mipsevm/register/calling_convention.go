@@ -26,4 +26,8 @@ const (
 	RegSyscallParam2 = RegA1
 	RegSyscallParam3 = RegA2
 	RegSyscallParam4 = RegA3
+	// 6th syscall argument on MIPS64. MIPS32 passes it on the stack instead, at RegSP + SyscallParam6StackOffset.
+	RegSyscallParam6 = 9
+
+	SyscallParam6StackOffset = 20
 )
@@ -33,6 +33,11 @@ func (m *InstrumentedState) handleSyscall() error {
 		m.state.ExitCode = uint8(a0)
 		return nil
 	case arch.SysRead:
+		if a0 == exec.FdPreimageRead {
+			if err := m.preimageOracle.CheckOffset(m.state.PreimageKey, m.state.PreimageOffset); err != nil {
+				return err
+			}
+		}
 		var newPreimageOffset Word
 		v0, v1, newPreimageOffset, _, _ = exec.HandleSysRead(a0, a1, a2, m.state.PreimageKey, m.state.PreimageOffset, m.preimageOracle, m.state.Memory, m.memoryTracker)
 		m.state.PreimageOffset = newPreimageOffset
@@ -186,6 +186,31 @@ func (s *State) EncodeWitness() ([]byte, common.Hash) {
 	return out, stateHashFromWitness(out)
 }
 
+// WitnessFields returns the fields of the state witness in the order they are encoded, e.g. to report which fields
+// of two witnesses differ.
+func (s *State) WitnessFields() []mipsevm.WitnessField {
+	fields := []mipsevm.WitnessField{
+		{Name: "MemRoot", Size: 32},
+		{Name: "PreimageKey", Size: 32},
+		{Name: "PreimageOffset", Size: arch.WordSizeBytes},
+		{Name: "PC", Size: arch.WordSizeBytes},
+		{Name: "NextPC", Size: arch.WordSizeBytes},
+		{Name: "LO", Size: arch.WordSizeBytes},
+		{Name: "HI", Size: arch.WordSizeBytes},
+		{Name: "Heap", Size: arch.WordSizeBytes},
+		{Name: "ExitCode", Size: 1},
+		{Name: "Exited", Size: 1},
+		{Name: "Step", Size: 8},
+	}
+	for i := range s.Registers {
+		fields = append(fields, mipsevm.WitnessField{Name: fmt.Sprintf("Registers[%d]", i), Size: arch.WordSizeBytes})
+	}
+	for i := 1; i < len(fields); i++ {
+		fields[i].Offset = fields[i-1].Offset + fields[i-1].Size
+	}
+	return fields
+}
+
 // Serialize writes the state in a simple binary format which can be read again using Deserialize
 // The format is a simple concatenation of fields, with prefixed item count for repeating items and using big endian
 // encoding for numbers.
@@ -316,7 +341,7 @@ func GetStateHashFn() mipsevm.HashFn {
 
 func stateHashFromWitness(sw []byte) common.Hash {
 	if len(sw) != STATE_WITNESS_SIZE {
-		panic("Invalid witness length")
+		panic(fmt.Sprintf("Invalid witness length. Got %d, expected %d", len(sw), STATE_WITNESS_SIZE))
 	}
 	hash := crypto.Keccak256Hash(sw)
 	offset := 32*2 + 4*6
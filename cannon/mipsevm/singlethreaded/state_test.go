@@ -6,6 +6,7 @@ package singlethreaded
 import (
 	"bytes"
 	"debug/elf"
+	"fmt"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -152,3 +153,33 @@ func TestSerializeStateRoundTrip(t *testing.T) {
 	require.NoError(t, err, "must deserialize state")
 	require.Equal(t, state, state2, "must roundtrip state")
 }
+
+func TestStateHashFromWitness_InvalidLength(t *testing.T) {
+	witness := make([]byte, STATE_WITNESS_SIZE-1)
+	expectedMsg := fmt.Sprintf("Invalid witness length. Got %d, expected %d", STATE_WITNESS_SIZE-1, STATE_WITNESS_SIZE)
+	require.PanicsWithValue(t, expectedMsg, func() { stateHashFromWitness(witness) })
+}
+
+func TestWitnessFields(t *testing.T) {
+	state := CreateEmptyState()
+	fields := state.WitnessFields()
+	offset := 0
+	for _, f := range fields {
+		require.Equalf(t, offset, f.Offset, "field %s must follow the previous field", f.Name)
+		offset += f.Size
+	}
+	require.Equal(t, STATE_WITNESS_SIZE, offset, "fields must cover the witness")
+
+	// Spot-check that fields are named after the state fields they encode
+	pre, _ := state.EncodeWitness()
+	state.Cpu.NextPC = 0x1234
+	state.Registers[31] = 0x5678
+	post, _ := state.EncodeWitness()
+	var changed []string
+	for _, f := range fields {
+		if !bytes.Equal(pre[f.Offset:f.Offset+f.Size], post[f.Offset:f.Offset+f.Size]) {
+			changed = append(changed, f.Name)
+		}
+	}
+	require.Equal(t, []string{"NextPC", "Registers[31]"}, changed)
+}
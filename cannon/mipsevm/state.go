@@ -16,6 +16,9 @@ const (
 	VMStatusUnfinished = 3
 )
 
+// VmStatus maps the exit state of the VM to the status byte of the state hash.
+// Exit code 0 is a valid exit and exit code 1 is an invalid exit. Any other exit code is a panic,
+// which includes guests that abort (SIGABRT via tgkill) and unsupported clone flags, both of which exit with VMStatusPanic.
 func VmStatus(exited bool, exitCode uint8) uint8 {
 	if !exited {
 		return VMStatusUnfinished
@@ -1074,6 +1074,34 @@ func TestEVM_EntryProgram(t *testing.T) {
 	}
 }
 
+func TestEVM_RunParity(t *testing.T) {
+	versions := GetMipsVersionTestCases(t)
+	insns := []uint32{
+		0x34_08_00_03,                     // ori $t0, $zero, 3
+		0xac_08_01_00,                     // sw $t0, 0x100($zero)
+		0x8c_09_01_00,                     // lw $t1, 0x100($zero)
+		0x25_24_00_04,                     // addiu $a0, $t1, 4
+		0x24_02_00_00 | arch.SysExitGroup, // addiu $v0, $zero, exit_group
+		syscallInsn,
+	}
+
+	for _, v := range versions {
+		t.Run(v.Name, func(t *testing.T) {
+			goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithPCAndNextPC(0x1000))
+			state := goVm.GetState()
+			for i, insn := range insns {
+				testutil.StoreInstruction(state.GetMemory(), 0x1000+arch.Word(4*i), insn)
+			}
+
+			testutil.RunParity(t, state, nil, v.StateHashFn, v.Contracts, 100)
+
+			require.True(t, state.GetExited(), "must complete program")
+			require.Equal(t, uint8(7), state.GetExitCode())
+			require.Equal(t, uint64(len(insns)), state.GetStep())
+		})
+	}
+}
+
 func TestEVM_SingleStep_Branch32(t *testing.T) {
 	testutil.Cannon32OnlyTest(t, "These tests are fully covered for 64-bits in TestEVM_SingleStep_Branch64")
 	t.Parallel()
@@ -15,6 +15,7 @@ import (
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded"
 	mttestutil "github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded/testutil"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/register"
@@ -211,8 +212,8 @@ func TestEVM_MT_SysRead_Preimage32(t *testing.T) {
 		{name: "Count greater than 4", addr: 0x00_00_FF_00, count: 15, writeLen: 4, preimageOffset: 8, prestateMem: 0xFF_FF_FF_FF, postateMem: 0x12_34_56_78},
 		{name: "Count greater than 4, unaligned", addr: 0x00_00_FF_01, count: 15, writeLen: 3, preimageOffset: 8, prestateMem: 0xFF_FF_FF_FF, postateMem: 0xFF_12_34_56},
 		{name: "Offset at last byte", addr: 0x00_00_FF_00, count: 4, writeLen: 1, preimageOffset: 15, prestateMem: 0xFF_FF_FF_FF, postateMem: 0x32_FF_FF_FF},
-		{name: "Offset just out of bounds", addr: 0x00_00_FF_00, count: 4, writeLen: 0, preimageOffset: 16, prestateMem: 0xFF_FF_FF_FF, postateMem: 0xFF_FF_FF_FF, shouldPanic: true},
-		{name: "Offset out of bounds", addr: 0x00_00_FF_00, count: 4, writeLen: 0, preimageOffset: 17, prestateMem: 0xFF_FF_FF_FF, postateMem: 0xFF_FF_FF_FF, shouldPanic: true},
+		{name: "Offset just out of bounds", addr: 0x00_00_FF_00, count: 4, writeLen: 0, preimageOffset: 16, prestateMem: 0xFF_FF_FF_FF, postateMem: 0xFF_FF_FF_FF, shouldError: true},
+		{name: "Offset out of bounds", addr: 0x00_00_FF_00, count: 4, writeLen: 0, preimageOffset: 17, prestateMem: 0xFF_FF_FF_FF, postateMem: 0xFF_FF_FF_FF, shouldError: true},
 	}
 
 	testMTSysReadPreimage(t, preimageValue, cases)
@@ -504,46 +505,54 @@ func TestEVM_SysFutex_WaitPrivate(t *testing.T) {
 		{name: "memory mismatch w timeout", addressParam: 0xFF_FF_FF_FF_FF_FF_12_00, effAddr: 0xFF_FF_FF_FF_FF_FF_12_00, targetValue: 0xFF_FF_FF_FF_FF_FF_FF_01, actualValue: 0xFF_FF_FF_FF_FF_FF_FF_02, timeout: 2000000, shouldFail: true},
 		{name: "memory mismatch w timeout, unaligned", addressParam: 0xFF_FF_FF_FF_FF_FF_12_0F, effAddr: 0xFF_FF_FF_FF_FF_FF_12_10, targetValue: 0xFF_FF_FF_FF_FF_FF_FF_01, actualValue: 0xFF_FF_FF_FF_FF_FF_FF_02, timeout: 2000000, shouldFail: true},
 	}
-	for i, c := range cases {
-		t.Run(c.name, func(t *testing.T) {
-			goVm, state, contracts := setup(t, i*1234, nil)
-			step := state.GetStep()
+	ops := map[string]Word{
+		"FUTEX_WAIT_PRIVATE":        exec.FutexWaitPrivate,
+		"FUTEX_WAIT_BITSET_PRIVATE": exec.FutexWaitBitsetPrivate,
+	}
+	for opName, op := range ops {
+		for i, c := range cases {
+			t.Run(fmt.Sprintf("%v %v", opName, c.name), func(t *testing.T) {
+				goVm, state, contracts := setup(t, i*1234, nil)
+				step := state.GetStep()
 
-			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
-			state.Memory.SetWord(Word(c.effAddr), Word(c.actualValue))
-			state.GetRegistersRef()[2] = arch.SysFutex // Set syscall number
-			state.GetRegistersRef()[4] = Word(c.addressParam)
-			state.GetRegistersRef()[5] = exec.FutexWaitPrivate
-			state.GetRegistersRef()[6] = Word(c.targetValue)
-			state.GetRegistersRef()[7] = Word(c.timeout)
+				testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+				state.Memory.SetWord(Word(c.effAddr), Word(c.actualValue))
+				state.GetRegistersRef()[2] = arch.SysFutex // Set syscall number
+				state.GetRegistersRef()[4] = Word(c.addressParam)
+				state.GetRegistersRef()[5] = op
+				state.GetRegistersRef()[6] = Word(c.targetValue)
+				state.GetRegistersRef()[7] = Word(c.timeout)
+				setFutexBitset(state, futexStackPtr, exec.FutexBitsetMatchAny)
 
-			// Setup expectations
-			expected := mttestutil.NewExpectedMTState(state)
-			expected.Step += 1
-			expected.StepsSinceLastContextSwitch += 1
-			if c.shouldFail {
-				expected.ActiveThread().PC = state.GetCpu().NextPC
-				expected.ActiveThread().NextPC = state.GetCpu().NextPC + 4
-				expected.ActiveThread().Registers[2] = exec.SysErrorSignal
-				expected.ActiveThread().Registers[7] = exec.MipsEAGAIN
-			} else {
-				// PC and return registers should not update on success, updates happen when wait completes
-				expected.ActiveThread().FutexAddr = Word(c.effAddr)
-				expected.ActiveThread().FutexVal = Word(c.targetValue)
-				expected.ActiveThread().FutexTimeoutStep = exec.FutexNoTimeout
-				if c.shouldSetTimeout {
-					expected.ActiveThread().FutexTimeoutStep = step + exec.FutexTimeoutSteps + 1
+				// Setup expectations
+				expected := mttestutil.NewExpectedMTState(state)
+				expected.Step += 1
+				expected.StepsSinceLastContextSwitch += 1
+				if c.shouldFail {
+					expected.ActiveThread().PC = state.GetCpu().NextPC
+					expected.ActiveThread().NextPC = state.GetCpu().NextPC + 4
+					expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+					expected.ActiveThread().Registers[7] = exec.MipsEAGAIN
+				} else {
+					// PC and return registers should not update on success, updates happen when wait completes
+					expected.ActiveThread().FutexAddr = Word(c.effAddr)
+					expected.ActiveThread().FutexVal = Word(c.targetValue)
+					expected.ActiveThread().FutexTimeoutStep = exec.FutexNoTimeout
+					expected.ActiveThread().FutexBitset = exec.FutexBitsetMatchAny
+					if c.shouldSetTimeout {
+						expected.ActiveThread().FutexTimeoutStep = step + exec.FutexTimeoutSteps + 1
+					}
 				}
-			}
 
-			// State transition
-			stepWitness, err := goVm.Step(true)
-			require.NoError(t, err)
+				// State transition
+				stepWitness, err := goVm.Step(true)
+				require.NoError(t, err)
 
-			// Validate post-state
-			expected.Validate(t, state)
-			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
-		})
+				// Validate post-state
+				expected.Validate(t, state)
+				testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+			})
+		}
 	}
 }
 
@@ -571,29 +580,121 @@ func TestEVM_SysFutex_WakePrivate(t *testing.T) {
 		{name: "Traverse left, single thread", addressParam: 0xFF_FF_FF_FF_FF_FF_67_88, effAddr: 0xFF_FF_FF_FF_FF_FF_67_88, activeThreadCount: 1, inactiveThreadCount: 0, traverseRight: false, expectTraverseRight: true},
 		{name: "Traverse left, single thread, unaligned", addressParam: 0xFF_FF_FF_FF_FF_FF_67_89, effAddr: 0xFF_FF_FF_FF_FF_FF_67_88, activeThreadCount: 1, inactiveThreadCount: 0, traverseRight: false, expectTraverseRight: true},
 	}
+	ops := map[string]Word{
+		"FUTEX_WAKE_PRIVATE":        exec.FutexWakePrivate,
+		"FUTEX_WAKE_BITSET_PRIVATE": exec.FutexWakeBitsetPrivate,
+	}
+	for opName, op := range ops {
+		for i, c := range cases {
+			t.Run(fmt.Sprintf("%v %v", opName, c.name), func(t *testing.T) {
+				goVm, state, contracts := setup(t, i*1122, nil)
+				mttestutil.SetupThreads(int64(i*2244), state, c.traverseRight, c.activeThreadCount, c.inactiveThreadCount)
+				step := state.Step
+
+				testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+				state.GetRegistersRef()[2] = arch.SysFutex // Set syscall number
+				state.GetRegistersRef()[4] = Word(c.addressParam)
+				state.GetRegistersRef()[5] = op
+				setFutexBitset(state, futexStackPtr, exec.FutexBitsetMatchAny)
+
+				// Set up post-state expectations
+				expected := mttestutil.NewExpectedMTState(state)
+				expected.ExpectStep()
+				expected.ActiveThread().Registers[2] = 0
+				expected.ActiveThread().Registers[7] = 0
+				expected.Wakeup = Word(c.effAddr) & arch.AddressMask // aligned for 32 and 64-bit compatibility
+				expected.WakeupBitset = exec.FutexBitsetMatchAny
+				expected.ExpectPreemption(state)
+				expected.TraverseRight = c.expectTraverseRight
+				if c.traverseRight != c.expectTraverseRight {
+					// If we preempt the current thread and then switch directions, the same
+					// thread will remain active
+					expected.ActiveThreadId = state.GetCurrentThread().ThreadId
+				}
+
+				// State transition
+				stepWitness, err := goVm.Step(true)
+				require.NoError(t, err)
+
+				// Validate post-state
+				expected.Validate(t, state)
+				testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+			})
+		}
+	}
+}
+
+func TestEVM_SysFutex_Bitset(t *testing.T) {
+	// Note: parameters are written as 64-bit values. For 32-bit architectures, these values are downcast to 32-bit
+	cases := []struct {
+		name         string
+		op           Word
+		sp           Word
+		bitset       uint64
+		mips32Only   bool
+		expectBitset uint32
+		expectErrno  Word
+	}{
+		{name: "wait, match any", op: exec.FutexWaitBitsetPrivate, sp: futexStackPtr, bitset: exec.FutexBitsetMatchAny, expectBitset: exec.FutexBitsetMatchAny},
+		{name: "wait, single bit", op: exec.FutexWaitBitsetPrivate, sp: futexStackPtr, bitset: 0x1, expectBitset: 0x1},
+		{name: "wait, upper bits ignored", op: exec.FutexWaitBitsetPrivate, sp: futexStackPtr, bitset: 0xFF_FF_FF_FF_00_00_01_10, expectBitset: 0x110},
+		{name: "wait, zero", op: exec.FutexWaitBitsetPrivate, sp: futexStackPtr, bitset: 0, expectErrno: exec.MipsEINVAL},
+		{name: "wait, upper bits only", op: exec.FutexWaitBitsetPrivate, sp: futexStackPtr, bitset: 0xFF_FF_FF_FF_00_00_00_00, expectErrno: exec.MipsEINVAL},
+		{name: "wake, match any", op: exec.FutexWakeBitsetPrivate, sp: futexStackPtr, bitset: exec.FutexBitsetMatchAny, expectBitset: exec.FutexBitsetMatchAny},
+		{name: "wake, single bit", op: exec.FutexWakeBitsetPrivate, sp: futexStackPtr, bitset: 0x8000_0000, expectBitset: 0x8000_0000},
+		{name: "wake, zero", op: exec.FutexWakeBitsetPrivate, sp: futexStackPtr, bitset: 0, expectErrno: exec.MipsEINVAL},
+		{name: "wait, null stack", op: exec.FutexWaitBitsetPrivate, sp: 0x10, bitset: exec.FutexBitsetMatchAny, mips32Only: true, expectErrno: exec.MipsEFAULT},
+		{name: "wake, unaligned stack", op: exec.FutexWakeBitsetPrivate, sp: futexStackPtr + 2, bitset: exec.FutexBitsetMatchAny, mips32Only: true, expectErrno: exec.MipsEFAULT},
+	}
 	for i, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			goVm, state, contracts := setup(t, i*1122, nil)
-			mttestutil.SetupThreads(int64(i*2244), state, c.traverseRight, c.activeThreadCount, c.inactiveThreadCount)
-			step := state.Step
+			if c.mips32Only && !arch.IsMips32 {
+				t.Skip("the bitset is passed in a register on MIPS64")
+			}
+			goVm, state, contracts := setup(t, i*4321, nil)
+			mttestutil.SetupThreads(int64(i*4322), state, false, 2, 1)
+			step := state.GetStep()
 
+			const futexAddr = Word(0x1238)
+			const futexVal = Word(0x2)
 			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.Memory.SetWord(futexAddr, futexVal)
 			state.GetRegistersRef()[2] = arch.SysFutex // Set syscall number
-			state.GetRegistersRef()[4] = Word(c.addressParam)
-			state.GetRegistersRef()[5] = exec.FutexWakePrivate
+			state.GetRegistersRef()[4] = futexAddr
+			state.GetRegistersRef()[5] = c.op
+			state.GetRegistersRef()[6] = futexVal
+			state.GetRegistersRef()[7] = 0
+			if arch.IsMips32 {
+				state.GetRegistersRef()[register.RegSP] = c.sp
+				if c.sp&arch.ExtMask == 0 {
+					state.Memory.SetWord(c.sp+register.SyscallParam6StackOffset, Word(c.bitset))
+				}
+			} else {
+				state.GetRegistersRef()[register.RegSyscallParam6] = Word(c.bitset)
+			}
 
 			// Set up post-state expectations
 			expected := mttestutil.NewExpectedMTState(state)
-			expected.ExpectStep()
-			expected.ActiveThread().Registers[2] = 0
-			expected.ActiveThread().Registers[7] = 0
-			expected.Wakeup = Word(c.effAddr) & arch.AddressMask // aligned for 32 and 64-bit compatibility
-			expected.ExpectPreemption(state)
-			expected.TraverseRight = c.expectTraverseRight
-			if c.traverseRight != c.expectTraverseRight {
-				// If we preempt the current thread and then switch directions, the same
-				// thread will remain active
-				expected.ActiveThreadId = state.GetCurrentThread().ThreadId
+			switch {
+			case c.expectErrno != 0:
+				expected.ExpectStep()
+				expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+				expected.ActiveThread().Registers[7] = c.expectErrno
+			case c.op == exec.FutexWaitBitsetPrivate:
+				// The wait completes in a later step, which updates the PC and return registers
+				expected.Step += 1
+				expected.StepsSinceLastContextSwitch += 1
+				expected.ActiveThread().FutexAddr = futexAddr
+				expected.ActiveThread().FutexVal = futexVal
+				expected.ActiveThread().FutexTimeoutStep = exec.FutexNoTimeout
+				expected.ActiveThread().FutexBitset = c.expectBitset
+			default:
+				expected.ExpectStep()
+				expected.ActiveThread().Registers[2] = 0
+				expected.ActiveThread().Registers[7] = 0
+				expected.Wakeup = futexAddr
+				expected.WakeupBitset = c.expectBitset
+				expected.ExpectPreemption(state)
 			}
 
 			// State transition
@@ -605,7 +706,79 @@ func TestEVM_SysFutex_WakePrivate(t *testing.T) {
 			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
 		})
 	}
+}
+
+func TestEVM_SysFutex_WakeBitsetMatchingWaiter(t *testing.T) {
+	const futexAddr = Word(0x1238)
+	const futexVal = Word(0x2)
+	goVm, state, contracts := setup(t, 7001, nil)
+	mttestutil.SetupThreads(7002, state, false, 3, 0)
+	step := state.GetStep()
+
+	// Both inactive threads wait on the same address, the next one to be visited with a disjoint bitset
+	disjointWaiter := state.LeftThreadStack[1]
+	matchingWaiter := state.LeftThreadStack[0]
+	for waiter, bitset := range map[*multithreaded.ThreadState]uint32{disjointWaiter: 0x1, matchingWaiter: 0x6} {
+		waiter.FutexAddr = futexAddr
+		waiter.FutexVal = futexVal
+		waiter.FutexTimeoutStep = exec.FutexNoTimeout
+		waiter.FutexBitset = bitset
+	}
 
+	testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+	state.Memory.SetWord(futexAddr, futexVal)
+	state.GetRegistersRef()[2] = arch.SysFutex // Set syscall number
+	state.GetRegistersRef()[4] = futexAddr
+	state.GetRegistersRef()[5] = exec.FutexWakeBitsetPrivate
+	setFutexBitset(state, futexStackPtr, 0x2)
+
+	// The wake starts a traversal with the wake bitset
+	expected := mttestutil.NewExpectedMTState(state)
+	expected.ExpectStep()
+	expected.ActiveThread().Registers[2] = 0
+	expected.ActiveThread().Registers[7] = 0
+	expected.Wakeup = futexAddr
+	expected.WakeupBitset = 0x2
+	expected.ExpectPreemption(state)
+	stepWitness, err := goVm.Step(true)
+	require.NoError(t, err)
+	expected.Validate(t, state)
+	testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+
+	// The waiter with a disjoint bitset is passed over
+	require.Equal(t, disjointWaiter.ThreadId, state.GetCurrentThread().ThreadId)
+	expected = mttestutil.NewExpectedMTState(state)
+	expected.Step += 1
+	expected.ExpectPreemption(state)
+	stepWitness, err = goVm.Step(true)
+	require.NoError(t, err)
+	expected.Validate(t, state)
+	testutil.ValidateEVM(t, stepWitness, step+1, goVm, multithreaded.GetStateHashFn(), contracts)
+
+	// The waiter with an intersecting bitset ends the traversal
+	require.Equal(t, matchingWaiter.ThreadId, state.GetCurrentThread().ThreadId)
+	expected = mttestutil.NewExpectedMTState(state)
+	expected.Step += 1
+	expected.Wakeup = exec.FutexEmptyAddr
+	expected.WakeupBitset = 0
+	stepWitness, err = goVm.Step(true)
+	require.NoError(t, err)
+	expected.Validate(t, state)
+	testutil.ValidateEVM(t, stepWitness, step+2, goVm, multithreaded.GetStateHashFn(), contracts)
+	require.Equal(t, futexAddr, disjointWaiter.FutexAddr)
+}
+
+// futexStackPtr is the stack pointer used by the futex bitset tests, so that MIPS32 finds the bitset on the stack
+const futexStackPtr = Word(0x7f_ff_c0_00)
+
+// setFutexBitset sets the bitset argument of a futex bitset operation, which MIPS32 passes on the stack
+func setFutexBitset(state *multithreaded.State, sp Word, bitset Word) {
+	if arch.IsMips32 {
+		state.GetRegistersRef()[register.RegSP] = sp
+		state.Memory.SetWord(sp+register.SyscallParam6StackOffset, bitset)
+	} else {
+		state.GetRegistersRef()[register.RegSyscallParam6] = bitset
+	}
 }
 
 func TestEVM_SysFutex_UnsupportedOp(t *testing.T) {
@@ -648,8 +821,6 @@ func TestEVM_SysFutex_UnsupportedOp(t *testing.T) {
 		"FUTEX_LOCK_PI2_PRIVATE":        (FUTEX_LOCK_PI2 | FUTEX_PRIVATE_FLAG),
 		"FUTEX_UNLOCK_PI_PRIVATE":       (FUTEX_UNLOCK_PI | FUTEX_PRIVATE_FLAG),
 		"FUTEX_TRYLOCK_PI_PRIVATE":      (FUTEX_TRYLOCK_PI | FUTEX_PRIVATE_FLAG),
-		"FUTEX_WAIT_BITSET_PRIVATE":     (FUTEX_WAIT_BITSET | FUTEX_PRIVATE_FLAG),
-		"FUTEX_WAKE_BITSET_PRIVATE":     (FUTEX_WAKE_BITSET | FUTEX_PRIVATE_FLAG),
 		"FUTEX_WAIT_REQUEUE_PI_PRIVATE": (FUTEX_WAIT_REQUEUE_PI | FUTEX_PRIVATE_FLAG),
 		"FUTEX_CMP_REQUEUE_PI_PRIVATE":  (FUTEX_CMP_REQUEUE_PI | FUTEX_PRIVATE_FLAG),
 	}
@@ -762,6 +933,482 @@ func TestEVM_SysOpen(t *testing.T) {
 	testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
 }
 
+func TestEVM_SysNetwork(t *testing.T) {
+	cases := []struct {
+		name       string
+		syscallNum Word
+	}{
+		{name: "socket", syscallNum: arch.SysSocket},
+		{name: "connect", syscallNum: arch.SysConnect},
+		{name: "accept", syscallNum: arch.SysAccept},
+		{name: "bind", syscallNum: arch.SysBind},
+		{name: "listen", syscallNum: arch.SysListen},
+		{name: "sendto", syscallNum: arch.SysSendto},
+		{name: "recvfrom", syscallNum: arch.SysRecvfrom},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 7720+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = c.syscallNum // Set syscall number
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEAFNOSUPPORT
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysShm(t *testing.T) {
+	cases := []struct {
+		name       string
+		syscallNum Word
+	}{
+		{name: "shmget", syscallNum: arch.SysShmget},
+		{name: "shmat", syscallNum: arch.SysShmat},
+		{name: "shmctl", syscallNum: arch.SysShmctl},
+		{name: "shmdt", syscallNum: arch.SysShmdt},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 7740+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = c.syscallNum // Set syscall number
+			state.GetRegistersRef()[4] = 0x1234       // key or shmid
+			state.GetRegistersRef()[5] = 0x2000       // size or address
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsENOSYS
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysMunmap(t *testing.T) {
+	const addr = Word(0x10_000)
+	cases := []struct {
+		name        string
+		addr        Word
+		length      Word
+		heap        Word
+		llAddress   Word
+		zeroed      bool
+		expectedErr Word
+	}{
+		{name: "single page", addr: addr, length: 0x1000, zeroed: true},
+		{name: "partial page", addr: addr, length: 0x10, zeroed: true},
+		{name: "aligned block of pages", addr: addr, length: 0x4000, zeroed: true},
+		{name: "heap top", addr: addr, length: 0x4000, heap: addr + 0x4000, zeroed: true},
+		{name: "below heap top", addr: addr, length: 0x4000, heap: addr + 0x8000, zeroed: true},
+		{name: "reservation in block", addr: addr, length: 0x4000, llAddress: addr + 0x3008, zeroed: true},
+		{name: "reservation outside block", addr: addr, length: 0x4000, llAddress: addr + 0x4008, zeroed: true},
+		{name: "non-power-of-two length", addr: addr, length: 0x3000, heap: addr + 0x3000, expectedErr: exec.MipsEINVAL},
+		{name: "partial page rounding to a non-power-of-two length", addr: addr, length: 0x2001, expectedErr: exec.MipsEINVAL},
+		{name: "block misaligned to its size", addr: addr + 0x1000, length: 0x2000, expectedErr: exec.MipsEINVAL},
+		{name: "length overflowing when rounded", addr: 0, length: ^Word(0), expectedErr: exec.MipsEINVAL},
+		{name: "misaligned address", addr: 0x10_004, length: 0x1000, expectedErr: exec.MipsEINVAL},
+		{name: "zero length", addr: addr, length: 0, expectedErr: exec.MipsEINVAL},
+		{name: "wrapping range", addr: ^Word(0) - 0xfff, length: 0x2000, expectedErr: exec.MipsEINVAL},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 7760+i, nil, testutil.WithPCAndNextPC(0x1000))
+			if c.heap != 0 {
+				state.Heap = c.heap
+			} else {
+				state.Heap = arch.HeapStart
+			}
+			if c.llAddress != 0 {
+				state.LLReservationStatus = multithreaded.LLStatusActive32bit
+				state.LLAddress = c.llAddress
+				state.LLOwnerThread = state.GetCurrentThread().ThreadId
+			}
+
+			// Dirty the first and last word of the range the syscall may zero
+			size := (c.length + memory.PageAddrMask) &^ memory.PageAddrMask
+			written := []Word{c.addr &^ arch.ExtMask}
+			if size != 0 && c.addr+size > c.addr {
+				written = append(written, (c.addr+size-arch.WordSizeBytes)&^arch.ExtMask)
+			}
+			for _, a := range written {
+				state.Memory.SetWord(a, 0x1234)
+			}
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			pageCount := state.Memory.PageCount()
+			state.GetRegistersRef()[2] = arch.SysMunmap
+			state.GetRegistersRef()[4] = c.addr
+			state.GetRegistersRef()[5] = c.length
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			if c.expectedErr != 0 {
+				expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+				expected.ActiveThread().Registers[7] = c.expectedErr
+			} else {
+				expected.ActiveThread().Registers[2] = 0
+				expected.ActiveThread().Registers[7] = 0
+			}
+			if c.zeroed {
+				for _, a := range written {
+					expected.ExpectMemoryWordWrite(a, 0)
+				}
+				if c.heap == c.addr+size {
+					expected.Heap = c.addr
+				}
+				if c.llAddress-c.addr < size {
+					expected.LLReservationStatus = multithreaded.LLStatusNone
+					expected.LLAddress = 0
+					expected.LLOwnerThread = 0
+				}
+			}
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			if c.zeroed {
+				require.Less(t, state.Memory.PageCount(), pageCount)
+			} else {
+				require.Equal(t, pageCount, state.Memory.PageCount())
+			}
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysGetTimeOfDay(t *testing.T) {
+	const addr = Word(0x10_000)
+	cases := []struct {
+		name        string
+		tv          Word
+		tz          Word
+		llAddress   Word
+		expectedErr Word
+	}{
+		{name: "timeval only", tv: addr},
+		{name: "timezone only", tz: addr},
+		{name: "timezone in the timeval leaf", tv: addr, tz: addr + 2*arch.WordSizeBytes},
+		{name: "timezone in another leaf", tv: addr, tz: 0x20_000},
+		{name: "timeval spanning two leaves", tv: addr + 32 - arch.WordSizeBytes, tz: addr + 32 + arch.WordSizeBytes},
+		{name: "timezone overlapping the timeval", tv: addr, tz: addr + arch.WordSizeBytes},
+		{name: "reservation in timezone", tv: addr, tz: 0x20_000, llAddress: 0x20_000},
+		{name: "more than two leaves", tv: addr + 32 - arch.WordSizeBytes, tz: 0x20_000, expectedErr: exec.MipsEINVAL},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 7800+i, nil, testutil.WithPCAndNextPC(0x1000))
+			state.LLReservationStatus = multithreaded.LLStatusNone
+			state.LLAddress = 0
+			state.LLOwnerThread = 0
+			if c.llAddress != 0 {
+				state.LLReservationStatus = multithreaded.LLStatusActive32bit
+				state.LLAddress = c.llAddress
+				state.LLOwnerThread = state.GetCurrentThread().ThreadId
+			}
+			// Dirty the timezone, which the syscall zeroes
+			for off := Word(0); c.tz != 0 && off < exec.TimezoneSize; off += arch.WordSizeBytes {
+				state.Memory.SetWord(c.tz+off, 0x1234)
+			}
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = arch.SysGetTimeOfDay
+			state.GetRegistersRef()[4] = c.tv
+			state.GetRegistersRef()[5] = c.tz
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			if c.expectedErr != 0 {
+				expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+				expected.ActiveThread().Registers[7] = c.expectedErr
+			} else {
+				expected.ActiveThread().Registers[2] = 0
+				expected.ActiveThread().Registers[7] = 0
+				// The time is derived from the step count after it is incremented
+				if c.tv != 0 {
+					expected.ExpectMemoryWordWrite(c.tv, Word((step+1)/exec.HZ))
+					expected.ExpectMemoryWordWrite(c.tv+arch.WordSizeBytes, Word(((step+1)%exec.HZ)/(exec.HZ/1_000_000)))
+				}
+				for off := Word(0); c.tz != 0 && off < exec.TimezoneSize; off += arch.WordSizeBytes {
+					expected.ExpectMemoryWordWrite(c.tz+off, 0)
+				}
+				if c.llAddress != 0 {
+					expected.LLReservationStatus = multithreaded.LLStatusNone
+					expected.LLAddress = 0
+					expected.LLOwnerThread = 0
+				}
+			}
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysFaccessat(t *testing.T) {
+	goVm, state, contracts := setup(t, 5713, nil)
+
+	testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+	state.GetRegistersRef()[2] = arch.SysFaccessat // Set syscall number
+	state.GetRegistersRef()[4] = ^Word(99)         // AT_FDCWD
+	state.GetRegistersRef()[5] = 0x2000            // pathname
+	state.GetRegistersRef()[6] = 0                 // F_OK
+	state.Memory.SetWord(0x2000, 0x2f_65_74_63)    // "/etc"
+	step := state.Step
+
+	// Set up post-state expectations
+	expected := mttestutil.NewExpectedMTState(state)
+	expected.ExpectStep()
+	expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+	expected.ActiveThread().Registers[7] = exec.MipsENOENT
+
+	// State transition
+	var err error
+	var stepWitness *mipsevm.StepWitness
+	stepWitness, err = goVm.Step(true)
+	require.NoError(t, err)
+
+	// Validate post-state
+	expected.Validate(t, state)
+	testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+}
+
+func TestEVM_SysPrctl(t *testing.T) {
+	cases := []struct {
+		name   string
+		option Word
+		v0     Word
+		v1     Word
+	}{
+		{name: "PR_SET_NAME", option: exec.PrSetName, v0: 0, v1: 0},
+		{name: "PR_SET_VMA", option: exec.PrSetVma, v0: 0, v1: 0},
+		{name: "PR_GET_NAME", option: 16, v0: exec.SysErrorSignal, v1: exec.MipsEINVAL},
+		{name: "PR_SET_PDEATHSIG", option: 1, v0: exec.SysErrorSignal, v1: exec.MipsEINVAL},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 8830+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = arch.SysPrctl // Set syscall number
+			state.GetRegistersRef()[4] = c.option
+			state.GetRegistersRef()[5] = 0x1000
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			expected.ActiveThread().Registers[2] = c.v0
+			expected.ActiveThread().Registers[7] = c.v1
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysEpoll(t *testing.T) {
+	cases := []struct {
+		name       string
+		syscallNum Word
+		v0         Word
+	}{
+		{name: "epoll_create1", syscallNum: arch.SysEpollCreate1, v0: exec.FdEpoll},
+		{name: "epoll_create", syscallNum: arch.SysEpollCreate, v0: exec.FdEpoll},
+		{name: "epoll_ctl", syscallNum: arch.SysEpollCtl, v0: 0},
+		{name: "epoll_pwait", syscallNum: arch.SysEpollPwait, v0: 0},
+		{name: "epoll_wait", syscallNum: arch.SysEpollWait, v0: 0},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 6120+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = c.syscallNum // Set syscall number
+			state.GetRegistersRef()[4] = exec.FdEpoll
+			state.GetRegistersRef()[5] = 0x1000
+			state.GetRegistersRef()[6] = 1
+			state.GetRegistersRef()[7] = ^Word(0) // infinite timeout
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			expected.ActiveThread().Registers[2] = c.v0
+			expected.ActiveThread().Registers[7] = 0
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SyscallBadPointer(t *testing.T) {
+	cases := []struct {
+		name       string
+		syscallNum Word
+		a0         Word
+		a1         Word
+	}{
+		{name: "clock_gettime, null timespec", syscallNum: arch.SysClockGetTime, a0: exec.ClockGettimeMonotonicFlag, a1: 0},
+		{name: "clock_gettime, timespec in null page", syscallNum: arch.SysClockGetTime, a0: exec.ClockGettimeRealtimeFlag, a1: 0xff8},
+		{name: "clock_gettime, wrapping timespec", syscallNum: arch.SysClockGetTime, a0: exec.ClockGettimeMonotonicFlag, a1: ^Word(0) - arch.WordSizeBytes},
+		{name: "gettimeofday, timeval in null page", syscallNum: arch.SysGetTimeOfDay, a0: 0xff8},
+		{name: "gettimeofday, wrapping timeval", syscallNum: arch.SysGetTimeOfDay, a0: ^Word(0) - arch.WordSizeBytes},
+		{name: "gettimeofday, timezone in null page", syscallNum: arch.SysGetTimeOfDay, a0: 0x1000, a1: 0xff8},
+		{name: "gettimeofday, wrapping timezone", syscallNum: arch.SysGetTimeOfDay, a1: ^Word(0) - 4},
+		{name: "futex wait, null addr", syscallNum: arch.SysFutex, a0: 0, a1: exec.FutexWaitPrivate},
+		{name: "futex wake, null addr", syscallNum: arch.SysFutex, a0: 0x4, a1: exec.FutexWakePrivate},
+		{name: "futex wait, wrapping addr", syscallNum: arch.SysFutex, a0: ^Word(0) - 1, a1: exec.FutexWaitBitsetPrivate},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 7340+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = c.syscallNum // Set syscall number
+			state.GetRegistersRef()[4] = c.a0
+			state.GetRegistersRef()[5] = c.a1
+			state.GetRegistersRef()[6] = 0
+			state.GetRegistersRef()[7] = 0
+			if c.syscallNum == arch.SysFutex {
+				setFutexBitset(state, futexStackPtr, exec.FutexBitsetMatchAny)
+			}
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			expected.ExpectStep()
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
+func TestEVM_SysTgkill(t *testing.T) {
+	cases := []struct {
+		name  string
+		sig   Word
+		abort bool
+	}{
+		{name: "SIGABRT", sig: exec.SigAbrt, abort: true},
+		{name: "SIGURG", sig: 23, abort: false},
+		{name: "SIGKILL", sig: 9, abort: false},
+	}
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			goVm, state, contracts := setup(t, 3310+i, nil)
+
+			testutil.StoreInstruction(state.Memory, state.GetPC(), syscallInsn)
+			state.GetRegistersRef()[2] = arch.SysTgkill // Set syscall number
+			state.GetRegistersRef()[6] = c.sig
+			step := state.Step
+
+			// Set up post-state expectations
+			expected := mttestutil.NewExpectedMTState(state)
+			if c.abort {
+				expected.Step += 1
+				expected.StepsSinceLastContextSwitch += 1
+				expected.Exited = true
+				expected.ExitCode = mipsevm.VMStatusPanic
+			} else {
+				expected.ExpectStep()
+				expected.ActiveThread().Registers[2] = 0
+				expected.ActiveThread().Registers[7] = 0
+			}
+
+			// State transition
+			var err error
+			var stepWitness *mipsevm.StepWitness
+			stepWitness, err = goVm.Step(true)
+			require.NoError(t, err)
+
+			// Validate post-state
+			expected.Validate(t, state)
+			_, postHash := state.EncodeWitness()
+			if c.abort {
+				require.Equal(t, uint8(mipsevm.VMStatusPanic), postHash[0])
+			} else {
+				require.Equal(t, uint8(mipsevm.VMStatusUnfinished), postHash[0])
+			}
+			testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), contracts)
+		})
+	}
+}
+
 func TestEVM_SysGetPID(t *testing.T) {
 	goVm, state, contracts := setup(t, 1929, nil)
 
@@ -926,27 +1573,26 @@ var NoopSyscalls = map[string]uint32{
 	"SysFstat64":       4215,
 	"SysOpenAt":        4288,
 	"SysReadlink":      4085,
-	"SysReadlinkAt":    4298,
 	"SysIoctl":         4054,
-	"SysEpollCreate1":  4326,
 	"SysPipe2":         4328,
-	"SysEpollCtl":      4249,
-	"SysEpollPwait":    4313,
 	"SysGetRandom":     4353,
 	"SysUname":         4122,
 	"SysStat64":        4213,
 	"SysGetuid":        4024,
 	"SysGetgid":        4047,
+	"SysGeteuid":       4049,
+	"SysGetegid":       4050,
 	"SysLlseek":        4140,
 	"SysMinCore":       4217,
-	"SysTgkill":        4266,
 	"SysGetRLimit":     4076,
 	"SysLseek":         4019,
-	"SysMunmap":        4091,
 	"SysSetITimer":     4104,
 	"SysTimerCreate":   4257,
 	"SysTimerSetTime":  4258,
 	"SysTimerDelete":   4261,
+	"SysSetRobustList": 4309,
+	"SysPoll":          4188,
+	"SysPpoll":         4302,
 }
 
 func TestEVM_NoopSyscall32(t *testing.T) {
@@ -959,7 +1605,7 @@ func TestEVM_UnsupportedSyscall32(t *testing.T) {
 	t.Parallel()
 
 	var noopSyscallNums = maps.Values(NoopSyscalls)
-	var supportedSyscalls = []uint32{arch.SysMmap, arch.SysBrk, arch.SysClone, arch.SysExitGroup, arch.SysRead, arch.SysWrite, arch.SysFcntl, arch.SysExit, arch.SysSchedYield, arch.SysGetTID, arch.SysFutex, arch.SysOpen, arch.SysNanosleep, arch.SysClockGetTime, arch.SysGetpid}
+	var supportedSyscalls = []uint32{arch.SysMmap, arch.SysBrk, arch.SysClone, arch.SysExitGroup, arch.SysRead, arch.SysWrite, arch.SysFcntl, arch.SysExit, arch.SysSchedYield, arch.SysGetTID, arch.SysFutex, arch.SysTgkill, arch.SysOpen, arch.SysNanosleep, arch.SysClockGetTime, arch.SysClockNanosleep, arch.SysGetTimeOfDay, arch.SysMunmap, arch.SysRseq, arch.SysGetpid, arch.SysSocket, arch.SysConnect, arch.SysAccept, arch.SysBind, arch.SysListen, arch.SysSendto, arch.SysRecvfrom, arch.SysReadlinkAt, arch.SysPrctl, arch.SysFaccessat, arch.SysEpollCreate1, arch.SysEpollCreate, arch.SysEpollCtl, arch.SysEpollPwait, arch.SysEpollWait, arch.SysShmget, arch.SysShmat, arch.SysShmctl, arch.SysShmdt}
 	unsupportedSyscalls := make([]uint32, 0, 400)
 	for i := 4000; i < 4400; i++ {
 		candidate := uint32(i)
@@ -1051,6 +1697,7 @@ func TestEVM_NormalTraversalStep_HandleWaitingThread(t *testing.T) {
 				activeThread.FutexAddr = c.futexAddr
 				activeThread.FutexVal = c.targetValue
 				activeThread.FutexTimeoutStep = c.timeoutStep
+				activeThread.FutexBitset = 0x1
 				state.GetMemory().SetWord(effAddr, c.actualValue)
 
 				// Set up post-state expectations
@@ -1060,6 +1707,7 @@ func TestEVM_NormalTraversalStep_HandleWaitingThread(t *testing.T) {
 					expected.ActiveThread().FutexAddr = exec.FutexEmptyAddr
 					expected.ActiveThread().FutexVal = 0
 					expected.ActiveThread().FutexTimeoutStep = 0
+					expected.ActiveThread().FutexBitset = 0
 					// PC and return registers are updated onWaitComplete
 					expected.ActiveThread().PC = state.GetCpu().NextPC
 					expected.ActiveThread().NextPC = state.GetCpu().NextPC + 4
@@ -1154,6 +1802,8 @@ func TestEVM_WakeupTraversalStep(t *testing.T) {
 		traverseRight     bool
 		activeStackSize   int
 		otherStackSize    int
+		wakeupBitset      uint32 // defaults to FUTEX_BITSET_MATCH_ANY
+		futexBitset       uint32 // defaults to FUTEX_BITSET_MATCH_ANY
 		shouldClearWakeup bool
 		shouldPreempt     bool
 	}{
@@ -1179,6 +1829,10 @@ func TestEVM_WakeupTraversalStep(t *testing.T) {
 		{name: "Mismatched addr, futex unaligned", wakeupAddr: addr, futexAddr: addr + 6, traverseRight: true, activeStackSize: 2, otherStackSize: 2, shouldPreempt: true},
 		{name: "Mismatched addr, wakeup & futex unaligned", wakeupAddr: addr + 2, futexAddr: addr + 6, traverseRight: true, activeStackSize: 2, otherStackSize: 2, shouldPreempt: true},
 		{name: "Non-waiting thread, last thread, unaligned wakeup", wakeupAddr: addr + 3, futexAddr: exec.FutexEmptyAddr, traverseRight: true, activeStackSize: 1, otherStackSize: 1, shouldPreempt: true, shouldClearWakeup: true},
+		// Check that only waiters with a bitset that intersects the wakeup bitset are found
+		{name: "Matching addr, intersecting bitset", wakeupAddr: addr, futexAddr: addr, targetVal: wakeupVal, traverseRight: false, activeStackSize: 2, otherStackSize: 1, wakeupBitset: 0x6, futexBitset: 0x3, shouldClearWakeup: true},
+		{name: "Matching addr, disjoint bitset", wakeupAddr: addr, futexAddr: addr, targetVal: wakeupVal, traverseRight: false, activeStackSize: 2, otherStackSize: 1, wakeupBitset: 0x2, futexBitset: 0x1, shouldPreempt: true},
+		{name: "Matching addr, disjoint bitset, last thread", wakeupAddr: addr, futexAddr: addr, targetVal: wakeupVal, traverseRight: true, activeStackSize: 1, otherStackSize: 2, wakeupBitset: 0x8000_0000, futexBitset: 0x7FFF_FFFF, shouldPreempt: true, shouldClearWakeup: true},
 	}
 
 	for i, c := range cases {
@@ -1188,17 +1842,28 @@ func TestEVM_WakeupTraversalStep(t *testing.T) {
 			step := state.Step
 
 			state.Wakeup = c.wakeupAddr
+			state.WakeupBitset = exec.FutexBitsetMatchAny
+			if c.wakeupBitset != 0 {
+				state.WakeupBitset = c.wakeupBitset
+			}
 			state.GetMemory().SetWord(c.wakeupAddr&arch.AddressMask, wakeupVal)
 			activeThread := state.GetCurrentThread()
 			activeThread.FutexAddr = c.futexAddr
 			activeThread.FutexVal = c.targetVal
 			activeThread.FutexTimeoutStep = exec.FutexNoTimeout
+			if c.futexAddr != exec.FutexEmptyAddr {
+				activeThread.FutexBitset = exec.FutexBitsetMatchAny
+				if c.futexBitset != 0 {
+					activeThread.FutexBitset = c.futexBitset
+				}
+			}
 
 			// Set up post-state expectations
 			expected := mttestutil.NewExpectedMTState(state)
 			expected.Step += 1
 			if c.shouldClearWakeup {
 				expected.Wakeup = exec.FutexEmptyAddr
+				expected.WakeupBitset = 0
 			}
 			if c.shouldPreempt {
 				// Just preempt the current thread
@@ -1233,6 +1898,7 @@ func TestEVM_WakeupTraversal_Full(t *testing.T) {
 			goVm, state, contracts := setup(t, i*789, nil)
 			mttestutil.SetupThreads(int64(i*2947), state, false, c.threadCount, 0)
 			state.Wakeup = 0x08
+			state.WakeupBitset = exec.FutexBitsetMatchAny
 			step := state.Step
 
 			initialState := mttestutil.NewExpectedMTState(state)
@@ -1254,6 +1920,7 @@ func TestEVM_WakeupTraversal_Full(t *testing.T) {
 				// We should clear the wakeup on the last step
 				if i == iterations-1 {
 					expected.Wakeup = exec.FutexEmptyAddr
+					expected.WakeupBitset = 0
 				}
 
 				// Validate post-state
@@ -1265,6 +1932,7 @@ func TestEVM_WakeupTraversal_Full(t *testing.T) {
 			initialState.Step += uint64(iterations)
 			initialState.StepsSinceLastContextSwitch = 0
 			initialState.Wakeup = exec.FutexEmptyAddr
+			initialState.WakeupBitset = 0
 			initialState.Validate(t, state)
 		})
 	}
@@ -1301,6 +1969,7 @@ func TestEVM_WakeupTraversal_WithExitedThreads(t *testing.T) {
 			step := state.Step
 
 			state.Wakeup = c.wakeupAddr
+			state.WakeupBitset = exec.FutexBitsetMatchAny
 			state.GetMemory().SetWord(c.wakeupAddr&arch.AddressMask, wakeupVal)
 
 			threads := mttestutil.GetAllThreads(state)
@@ -1311,6 +1980,7 @@ func TestEVM_WakeupTraversal_WithExitedThreads(t *testing.T) {
 					thread.FutexAddr = c.futexAddr
 					thread.FutexVal = c.targetVal
 					thread.FutexTimeoutStep = exec.FutexNoTimeout
+					thread.FutexBitset = exec.FutexBitsetMatchAny
 				}
 			}
 
@@ -1320,12 +1990,14 @@ func TestEVM_WakeupTraversal_WithExitedThreads(t *testing.T) {
 			activeThread.FutexAddr = c.activeThreadFutexAddr
 			activeThread.FutexVal = c.activeThreadFutexVal
 			activeThread.FutexTimeoutStep = exec.FutexNoTimeout
+			activeThread.FutexBitset = exec.FutexBitsetMatchAny
 
 			expected := mttestutil.NewExpectedMTState(state)
 			expected.Step += 1
 
 			if c.shouldClearWakeup {
 				expected.Wakeup = exec.FutexEmptyAddr
+				expected.WakeupBitset = 0
 			}
 			if c.shouldPreempt {
 				// Just preempt the current thread
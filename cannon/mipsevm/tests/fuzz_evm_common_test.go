@@ -12,6 +12,7 @@ import (
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/program"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/testutil"
 	preimage "github.com/ethereum-optimism/optimism/op-preimage"
@@ -54,6 +55,12 @@ func FuzzStateSyscallMmap(f *testing.F) {
 	f.Add(Word(0), Word(1<<31), Word(program.HEAP_START), int64(2))
 	// Check edge case - just within bounds
 	f.Add(Word(0), Word(0x1000), Word(program.HEAP_END-4096), int64(3))
+	// Fixed mappings, page-aligned and unaligned
+	f.Add(Word(0x10_000), Word(0x1000), Word(program.HEAP_START), int64(4))
+	f.Add(Word(0x10_001), Word(0x1000), Word(program.HEAP_START), int64(5))
+	f.Add(Word(0x10_fff), Word(0x2000), Word(program.HEAP_START), int64(6))
+	// Fixed mapping overlapping the current heap must not move the heap
+	f.Add(Word(program.HEAP_START), Word(0x3000), Word(program.HEAP_START+0x1000), int64(7))
 
 	versions := GetMipsVersionTestCases(f)
 	f.Fuzz(func(t *testing.T, addr Word, siz Word, heap Word, seed int64) {
@@ -87,6 +94,10 @@ func FuzzStateSyscallMmap(f *testing.F) {
 						expected.Registers[2] = heap
 						expected.Registers[7] = 0 // no error
 					}
+				} else if _, isMT := state.(*multithreaded.State); isMT && addr&memory.PageAddrMask != 0 {
+					// Only the multithreaded VMs reject unaligned fixed mappings
+					expected.Registers[2] = exec.SysErrorSignal
+					expected.Registers[7] = exec.MipsEINVAL
 				} else {
 					expected.Registers[2] = addr
 					expected.Registers[7] = 0 // no error
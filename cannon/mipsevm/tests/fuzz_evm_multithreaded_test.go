@@ -1,13 +1,16 @@
 package tests
 
 import (
+	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/memory"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded"
 	mttestutil "github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded/testutil"
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/register"
@@ -66,3 +69,334 @@ func FuzzStateSyscallCloneMT(f *testing.F) {
 		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
 	})
 }
+
+func FuzzStateSyscallReadlinkat(f *testing.F) {
+	// readlinkat(AT_FDCWD, "/proc/self/exe", buf, bufsiz) as issued by the Go runtime
+	f.Add(Word(0x1010), Word(0x2000), Word(128), true, int64(1))
+	f.Add(Word(0x1010), Word(0x2005), Word(128), true, int64(2))
+	f.Add(Word(0x1000), Word(0x2000), Word(2), true, int64(3))
+	f.Add(Word(0x1000), Word(0x1000), Word(128), true, int64(4))
+	f.Add(Word(0x1000), Word(0x2000), Word(0), true, int64(5))
+	f.Add(Word(0x1013), Word(0x2000), Word(128), true, int64(6))
+	f.Add(Word(0x1000), Word(0x2000), Word(128), false, int64(7))
+	f.Add(Word(0x1000), Word(0x10), Word(128), true, int64(8))
+	f.Add(Word(0), Word(0x2000), ^Word(0), false, int64(9))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, pathname, buf, bufsiz Word, storePath bool, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+		// Leave no reservation for the buffer write to clear
+		state.LLReservationStatus = multithreaded.LLStatusNone
+		state.LLAddress = 0
+		state.LLOwnerThread = 0
+
+		pathLen := Word(len(exec.ProcSelfExe))
+		validPath := pathname >= memory.PageSize && pathname <= ^Word(0)-(pathLen-1)
+		if storePath && validPath {
+			require.NoError(t, state.GetMemory().SetMemoryRange(pathname, strings.NewReader(exec.ProcSelfExe)))
+		}
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysReadlinkAt
+		state.GetRegistersRef()[4] = ^Word(99) // AT_FDCWD
+		state.GetRegistersRef()[5] = pathname
+		state.GetRegistersRef()[6] = buf
+		state.GetRegistersRef()[7] = bufsiz
+		step := state.GetStep()
+
+		// The path may have been overwritten by the instruction
+		var path [len(exec.ProcSelfExe)]byte
+		if validPath {
+			_, err := io.ReadFull(state.GetMemory().ReadMemoryRange(pathname, pathLen), path[:])
+			require.NoError(t, err)
+		}
+		// At most the word holding buf is written
+		n := min(bufsiz, Word(len(exec.ExePath)), arch.WordSizeBytes-buf&arch.ExtMask)
+		validBuf := buf >= memory.PageSize && buf <= ^Word(0)-(n-1)
+
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		switch {
+		case bufsiz == 0:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		case !validPath:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+		case pathname&31+pathLen > 32:
+			// A path spanning two memory leaves cannot be read
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		case string(path[:]) != exec.ProcSelfExe:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsENOENT
+		case !validBuf:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+		default:
+			expected.ActiveThread().Registers[2] = n
+			expected.ActiveThread().Registers[7] = 0
+			effAddr := buf & arch.AddressMask
+			var word [arch.WordSizeBytes]byte
+			_, err := io.ReadFull(state.GetMemory().ReadMemoryRange(effAddr, arch.WordSizeBytes), word[:])
+			require.NoError(t, err)
+			copy(word[buf-effAddr:], exec.ExePath[:n])
+			expected.ExpectMemoryWordWrite(effAddr, arch.ByteOrderWord.Word(word[:]))
+		}
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
+
+func FuzzStateSyscallClockNanosleep(f *testing.F) {
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), Word(0), Word(0x1000), int64(1))
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), Word(0), Word(0), int64(2))
+	f.Add(Word(exec.ClockGettimeRealtimeFlag), Word(exec.TimerAbstime), Word(0x1003), int64(3))
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), Word(0), Word(0xff8), int64(4))
+	f.Add(Word(exec.ClockGettimeRealtimeFlag), Word(0x2), ^Word(0)-arch.WordSizeBytes, int64(5))
+	f.Add(Word(0xdead), Word(0), Word(0x1000), int64(6))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, clkid, flags, remAddr Word, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+		// Leave no reservation for the rem writes to clear
+		state.LLReservationStatus = multithreaded.LLStatusNone
+		state.LLAddress = 0
+		state.LLOwnerThread = 0
+
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysClockNanosleep
+		state.GetRegistersRef()[4] = clkid
+		state.GetRegistersRef()[5] = flags
+		state.GetRegistersRef()[6] = 0x2000 // request, not read
+		state.GetRegistersRef()[7] = remAddr
+		step := state.GetStep()
+
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		validPtr := remAddr >= memory.PageSize && remAddr <= ^Word(0)-(2*arch.WordSizeBytes-1)
+		switch {
+		case clkid != exec.ClockGettimeRealtimeFlag && clkid != exec.ClockGettimeMonotonicFlag:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		case remAddr == 0 || flags&exec.TimerAbstime != 0:
+			expected.ActiveThread().Registers[2] = 0
+			expected.ActiveThread().Registers[7] = 0
+		case !validPtr:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+		default:
+			// No time remains
+			expected.ActiveThread().Registers[2] = 0
+			expected.ActiveThread().Registers[7] = 0
+			effAddr := remAddr & arch.AddressMask
+			expected.ExpectMemoryWordWrite(effAddr, 0)
+			expected.ExpectMemoryWordWrite(effAddr+arch.WordSizeBytes, 0)
+		}
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
+
+func FuzzStateSyscallGetTimeOfDay(f *testing.F) {
+	f.Add(Word(0x1000), Word(0), uint64(0), int64(1))
+	f.Add(Word(0x1003), Word(0x2000), uint64(12_345_678), int64(2))
+	f.Add(Word(0), Word(0x2000), uint64(99), int64(3))
+	f.Add(Word(0xff8), Word(0), uint64(99), int64(4))
+	f.Add(^Word(0)-arch.WordSizeBytes, Word(0), uint64(99), int64(5))
+	f.Add(Word(0x1000), Word(0x1008), uint64(99), int64(6))
+	f.Add(Word(0x101c), Word(0x2000), uint64(99), int64(7))
+	f.Add(Word(0x1000), Word(0xff8), uint64(99), int64(8))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, tv, tz Word, step uint64, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+		state.Step = step
+		// Leave no reservation for the timeval writes to clear
+		state.LLReservationStatus = multithreaded.LLStatusNone
+		state.LLAddress = 0
+		state.LLOwnerThread = 0
+
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysGetTimeOfDay
+		state.GetRegistersRef()[4] = tv
+		state.GetRegistersRef()[5] = tz
+
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		validTv := tv == 0 || (tv >= memory.PageSize && tv <= ^Word(0)-(2*arch.WordSizeBytes-1))
+		validTz := tz == 0 || (tz >= memory.PageSize && tz <= ^Word(0)-(exec.TimezoneSize-1))
+		// The time is derived from the step count after it is incremented, and the timezone is zeroed
+		var addrs, vals []Word
+		if tv != 0 {
+			effAddr := tv & arch.AddressMask
+			addrs = append(addrs, effAddr, effAddr+arch.WordSizeBytes)
+			vals = append(vals, Word((step+1)/exec.HZ), Word(((step+1)%exec.HZ)/(exec.HZ/1_000_000)))
+		}
+		if tz != 0 {
+			effAddr := tz & arch.AddressMask
+			for off := Word(0); off < exec.TimezoneSize; off += arch.WordSizeBytes {
+				addrs = append(addrs, effAddr+off)
+				vals = append(vals, 0)
+			}
+		}
+		leaves := make(map[Word]bool)
+		for _, addr := range addrs {
+			leaves[addr&^31] = true
+		}
+		switch {
+		case !validTv || !validTz:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+		case len(leaves) > 2:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		default:
+			expected.ActiveThread().Registers[2] = 0
+			expected.ActiveThread().Registers[7] = 0
+			for i, addr := range addrs {
+				expected.ExpectMemoryWordWrite(addr, vals[i])
+			}
+		}
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
+
+func FuzzStateSyscallRseq(f *testing.F) {
+	f.Add(Word(0x1000), Word(0), int64(1))
+	f.Add(Word(0x1000), Word(exec.RseqFlagUnregister), int64(2))
+	f.Add(Word(0), Word(0), int64(3))
+	f.Add(Word(0x1000), Word(0x2), int64(4))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, rseqAddr, flags Word, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysRseq
+		state.GetRegistersRef()[4] = rseqAddr
+		state.GetRegistersRef()[5] = 32 // sizeof(struct rseq)
+		state.GetRegistersRef()[6] = flags
+		state.GetRegistersRef()[7] = 0x53053053 // RSEQ_SIG
+		step := state.GetStep()
+
+		// The rseq area is never written
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		if flags&^exec.RseqFlagUnregister != 0 {
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		} else {
+			expected.ActiveThread().Registers[2] = 0
+			expected.ActiveThread().Registers[7] = 0
+		}
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
+
+func FuzzStateSyscallMadvise(f *testing.F) {
+	f.Add(Word(0x10000), Word(0x4000), Word(4), int64(1))    // MADV_DONTNEED
+	f.Add(Word(0x10000), Word(0x4000), Word(8), int64(2))    // MADV_FREE
+	f.Add(Word(0x10000), Word(0x200000), Word(14), int64(3)) // MADV_HUGEPAGE
+	f.Add(Word(0x10003), Word(0), Word(0xdead), int64(4))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, addr, length, advice Word, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysMadvise
+		state.GetRegistersRef()[4] = addr
+		state.GetRegistersRef()[5] = length
+		state.GetRegistersRef()[6] = advice
+		step := state.GetStep()
+
+		// Any advice succeeds, and memory is unchanged
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		expected.ActiveThread().Registers[2] = 0
+		expected.ActiveThread().Registers[7] = 0
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
+
+func FuzzStateSyscallClockGettime(f *testing.F) {
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), Word(0x1000), uint64(0), int64(1))
+	f.Add(Word(exec.ClockGettimeRealtimeFlag), Word(0x1003), uint64(12_345_678), int64(2))
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), Word(0xff8), uint64(99), int64(3))
+	f.Add(Word(exec.ClockGettimeMonotonicFlag), ^Word(0)-arch.WordSizeBytes, uint64(99), int64(4))
+	f.Add(Word(0xdead), Word(0x1000), uint64(1), int64(5))
+	v := GetMultiThreadedTestCase(f)
+	f.Fuzz(func(t *testing.T, clkid, timespecAddr Word, step uint64, seed int64) {
+		goVm := v.VMFactory(nil, os.Stdout, os.Stderr, testutil.CreateLogger(), testutil.WithRandomization(seed))
+		state := mttestutil.GetMtState(t, goVm)
+		state.Step = step
+		// Leave no reservation for the timespec writes to clear
+		state.LLReservationStatus = multithreaded.LLStatusNone
+		state.LLAddress = 0
+		state.LLOwnerThread = 0
+
+		testutil.StoreInstruction(state.GetMemory(), state.GetPC(), syscallInsn)
+		state.GetRegistersRef()[2] = arch.SysClockGetTime
+		state.GetRegistersRef()[4] = clkid
+		state.GetRegistersRef()[5] = timespecAddr
+
+		expected := mttestutil.NewExpectedMTState(state)
+		expected.ExpectStep()
+		validPtr := timespecAddr >= memory.PageSize && timespecAddr <= ^Word(0)-(2*arch.WordSizeBytes-1)
+		switch {
+		case clkid != exec.ClockGettimeRealtimeFlag && clkid != exec.ClockGettimeMonotonicFlag:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEINVAL
+		case !validPtr:
+			expected.ActiveThread().Registers[2] = exec.SysErrorSignal
+			expected.ActiveThread().Registers[7] = exec.MipsEFAULT
+		default:
+			expected.ActiveThread().Registers[2] = 0
+			expected.ActiveThread().Registers[7] = 0
+			// The time is derived from the step count after it is incremented, and is zero for the realtime clock
+			var secs, nsecs Word
+			if clkid == exec.ClockGettimeMonotonicFlag {
+				secs = Word((step + 1) / exec.HZ)
+				nsecs = Word(((step + 1) % exec.HZ) * (1_000_000_000 / exec.HZ))
+			}
+			effAddr := timespecAddr & arch.AddressMask
+			expected.ExpectMemoryWordWrite(effAddr, secs)
+			expected.ExpectMemoryWordWrite(effAddr+arch.WordSizeBytes, nsecs)
+		}
+
+		stepWitness, err := goVm.Step(true)
+		require.NoError(t, err)
+		require.False(t, stepWitness.HasPreimage())
+
+		expected.Validate(t, state)
+		testutil.ValidateEVM(t, stepWitness, step, goVm, multithreaded.GetStateHashFn(), v.Contracts)
+	})
+}
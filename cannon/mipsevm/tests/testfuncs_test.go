@@ -345,7 +345,7 @@ type testMTSysReadPreimageTestCase struct {
 	preimageOffset Word
 	prestateMem    Word
 	postateMem     Word
-	shouldPanic    bool
+	shouldError    bool
 }
 
 func testMTSysReadPreimage(t *testing.T, preimageValue []byte, cases []testMTSysReadPreimageTestCase) {
@@ -409,8 +409,9 @@ func testMTSysReadPreimage(t *testing.T, preimageValue []byte, cases []testMTSys
 					expected.LLOwnerThread = 0
 				}
 
-				if c.shouldPanic {
-					require.Panics(t, func() { _, _ = goVm.Step(true) })
+				if c.shouldError {
+					_, err := goVm.Step(true)
+					require.ErrorIs(t, err, exec.ErrPreimageOffsetOutOfBounds)
 					testutil.AssertPreimageOracleReverts(t, preimageKey, preimageValue, c.preimageOffset, contracts)
 				} else {
 					stepWitness, err := goVm.Step(true)
@@ -0,0 +1,17 @@
+package testutil
+
+import (
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+)
+
+// NextPCAfterBranch returns the PC and NextPC after executing a branch at pc, which must not be in a delay slot.
+// The delay slot at pc+4 is executed next, followed by the branch target if the branch is taken, or the instruction
+// after the delay slot otherwise. offset is the sign-extended immediate of the branch, counted in instructions from
+// the delay slot. A jump behaves as a taken branch to its target, with an offset of (target-(pc+4))/4.
+func NextPCAfterBranch(pc Word, taken bool, offset int32) (nextPC, nextNextPC Word) {
+	nextPC = pc + 4
+	if !taken {
+		return nextPC, nextPC + 4
+	}
+	return nextPC, nextPC + Word(arch.SignedInteger(offset)<<2)
+}
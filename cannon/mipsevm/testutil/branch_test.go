@@ -0,0 +1,56 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+)
+
+func TestNextPCAfterBranch(t *testing.T) {
+	cases := []struct {
+		name       string
+		pc         Word
+		taken      bool
+		offset     int32
+		nextNextPC Word
+	}{
+		{name: "not taken", pc: 0x10, offset: 0x100, nextNextPC: 0x18},
+		{name: "taken", pc: 0x10, taken: true, offset: 0x100, nextNextPC: 0x414},
+		{name: "taken backwards", pc: 0x1000, taken: true, offset: -2, nextNextPC: 0xffc},
+		{name: "taken to delay slot", pc: 0x10, taken: true, offset: 0, nextNextPC: 0x14},
+		{name: "taken, sign-extended offset", pc: 0x10, taken: true, offset: -0x8000, nextNextPC: ^Word(0) - 0x1_FF_EB}, // 0x14 - 0x2_00_00
+		{name: "not taken, wraps around", pc: ^Word(0) - 7, offset: 0x100, nextNextPC: 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			nextPC, nextNextPC := NextPCAfterBranch(c.pc, c.taken, c.offset)
+			require.Equal(t, c.pc+4, nextPC)
+			require.Equal(t, c.nextNextPC, nextNextPC)
+
+			// Compare against the VM, using beq $zero, $zero or bne $zero, $zero
+			opcode := uint32(5)
+			if c.taken {
+				opcode = 4
+			}
+			insn := opcode<<26 | uint32(c.offset)&0xFFFF
+			cpu := mipsevm.CpuScalars{PC: c.pc, NextPC: c.pc + 4}
+			var registers [32]Word
+			require.NoError(t, exec.HandleBranch(&cpu, &registers, opcode, insn, 0, 0, &exec.NoopStackTracker{}))
+			require.Equal(t, nextPC, cpu.PC)
+			require.Equal(t, nextNextPC, cpu.NextPC)
+		})
+	}
+
+	t.Run("jump", func(t *testing.T) {
+		pc, target := Word(0x1000), Word(0x2000)
+		nextPC, nextNextPC := NextPCAfterBranch(pc, true, int32(target-(pc+4))/4)
+		cpu := mipsevm.CpuScalars{PC: pc, NextPC: pc + 4}
+		var registers [32]Word
+		require.NoError(t, exec.HandleJump(&cpu, &registers, 0, target))
+		require.Equal(t, nextPC, cpu.PC)
+		require.Equal(t, nextNextPC, cpu.NextPC)
+	})
+}
@@ -2,6 +2,10 @@ package testutil
 
 import (
 	"debug/elf"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
 
 	"github.com/stretchr/testify/require"
 
@@ -28,6 +32,62 @@ func LoadELFProgram[T mipsevm.FPVMState](t require.TestingT, name string, initSt
 	return state, meta
 }
 
+// LoadELFPrograms loads the named ELF files concurrently, using at most runtime.NumCPU workers.
+// The returned error joins the errors of all files that failed to load, each prefixed with the file name.
+func LoadELFPrograms[T mipsevm.FPVMState](names []string, initState program.CreateInitialFPVMState[T], doPatchGoGC bool) (map[string]T, error) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		results = make(map[string]T, len(names))
+		workers = make(chan struct{}, runtime.NumCPU())
+	)
+	for _, name := range names {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			state, err := loadELFProgram(name, initState, doPatchGoGC)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				return
+			}
+			results[name] = state
+		}(name)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return results, nil
+}
+
+func loadELFProgram[T mipsevm.FPVMState](name string, initState program.CreateInitialFPVMState[T], doPatchGoGC bool) (T, error) {
+	var empty T
+	elfProgram, err := elf.Open(name)
+	if err != nil {
+		return empty, fmt.Errorf("open ELF file: %w", err)
+	}
+	defer elfProgram.Close()
+
+	state, err := program.LoadELF(elfProgram, initState)
+	if err != nil {
+		return empty, fmt.Errorf("load ELF into state: %w", err)
+	}
+	if doPatchGoGC {
+		if err := program.PatchGoGC(elfProgram, state); err != nil {
+			return empty, fmt.Errorf("apply Go runtime patches: %w", err)
+		}
+	}
+	if err := program.PatchStack(state); err != nil {
+		return empty, fmt.Errorf("add initial stack: %w", err)
+	}
+	return state, nil
+}
+
 // ProgramPath returns the appropriate ELF test program for the current architecture
 func ProgramPath(programName string) string {
 	basename := programName + ".elf"
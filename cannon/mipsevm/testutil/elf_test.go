@@ -0,0 +1,40 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded"
+)
+
+func TestLoadELFPrograms(t *testing.T) {
+	names := []string{
+		ProgramPath("hello"),
+		ProgramPath("claim"),
+		ProgramPath("entry"),
+		ProgramPath("alloc"),
+		ProgramPath("mt-wg"),
+	}
+
+	t.Run("all succeed", func(t *testing.T) {
+		states, err := LoadELFPrograms(names, multithreaded.CreateInitialState, true)
+		require.NoError(t, err)
+		require.Len(t, states, len(names))
+		for _, name := range names {
+			expected, _ := LoadELFProgram(t, name, multithreaded.CreateInitialState, true)
+			_, expectedHash := expected.EncodeWitness()
+			_, actualHash := states[name].EncodeWitness()
+			require.Equal(t, expectedHash, actualHash, name)
+		}
+	})
+
+	t.Run("errors are reported per file", func(t *testing.T) {
+		missing := []string{ProgramPath("missing-a"), ProgramPath("missing-b")}
+		states, err := LoadELFPrograms(append(names, missing...), multithreaded.CreateInitialState, true)
+		require.Nil(t, states)
+		require.ErrorContains(t, err, missing[0])
+		require.ErrorContains(t, err, missing[1])
+		require.NotContains(t, err.Error(), names[0])
+	})
+}
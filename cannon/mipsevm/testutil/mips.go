@@ -1,11 +1,14 @@
 package testutil
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
@@ -227,6 +230,53 @@ func ValidateEVM(t *testing.T, stepWitness *mipsevm.StepWitness, step uint64, go
 	validator.ValidateEVM(t, stepWitness, step, goVm)
 }
 
+// RunParity runs the program in state on the Go VM until it exits or maxSteps is reached, executing every
+// step on the EVM as well. The test fails at the first step where the EVM post-state diverges from the Go VM,
+// reporting the step, the pre-step PC and instruction, and the state fields that differ.
+func RunParity(t *testing.T, state mipsevm.FPVMState, oracle mipsevm.PreimageOracle, hashFn mipsevm.HashFn, contracts *ContractMetadata, maxSteps uint64, opts ...evmOption) {
+	opts = append([]evmOption{WithLocalOracle(oracle)}, opts...)
+	evm := newMIPSEVM(contracts, opts...)
+	LogStepFailureAtCleanup(t, evm)
+	goVm := state.CreateVM(CreateLogger(), oracle, os.Stdout, os.Stderr, nil)
+	fielder, ok := state.(witnessFielder)
+	require.Truef(t, ok, "state %T does not name its witness fields", state)
+	fields := fielder.WitnessFields()
+
+	for i := uint64(0); i < maxSteps && !state.GetExited(); i++ {
+		step := state.GetStep()
+		pc := state.GetPC()
+		insn := GetInstruction(state.GetMemory(), pc)
+
+		stepWitness, err := goVm.Step(true)
+		require.NoErrorf(t, err, "go vm failed at step %d, pc 0x%x, insn 0x%08x", step, pc, insn)
+		evmPost := evm.Step(t, stepWitness, step, hashFn)
+		goPost, _ := state.EncodeWitness()
+		if diff := witnessDiff(fields, goPost, evmPost); diff != "" {
+			t.Fatalf("mipsevm and EVM diverged at step %d, pc 0x%x, insn 0x%08x:\n%s", step, pc, insn, diff)
+		}
+	}
+}
+
+// witnessFielder is implemented by states that name the fields of their witness.
+type witnessFielder interface {
+	WitnessFields() []mipsevm.WitnessField
+}
+
+// witnessDiff describes the state fields that differ between two encoded state witnesses.
+func witnessDiff(fields []mipsevm.WitnessField, goPost, evmPost []byte) string {
+	if len(goPost) != len(evmPost) {
+		return fmt.Sprintf("witness length: go=%d evm=%d", len(goPost), len(evmPost))
+	}
+	var out strings.Builder
+	for _, f := range fields {
+		goVal, evmVal := goPost[f.Offset:f.Offset+f.Size], evmPost[f.Offset:f.Offset+f.Size]
+		if !bytes.Equal(goVal, evmVal) {
+			fmt.Fprintf(&out, "\t%s: go=%x evm=%x\n", f.Name, goVal, evmVal)
+		}
+	}
+	return out.String()
+}
+
 type ErrMatcher func(*testing.T, []byte)
 
 func CreateNoopErrorMatcher() ErrMatcher {
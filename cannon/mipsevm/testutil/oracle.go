@@ -166,3 +166,56 @@ func (t *HintTrackingOracle) GetPreimage(k [32]byte) []byte {
 func (t *HintTrackingOracle) Hints() [][]byte {
 	return t.hints
 }
+
+// ChainOracles combines the given oracles into a single oracle.
+// Hints are broadcast to every oracle, and preimages are served by the first oracle that returns a non-empty result.
+func ChainOracles(oracles ...mipsevm.PreimageOracle) mipsevm.PreimageOracle {
+	return &TestOracle{
+		hint: func(v []byte) {
+			for _, o := range oracles {
+				o.Hint(v)
+			}
+		},
+		getPreimage: func(k [32]byte) []byte {
+			for _, o := range oracles {
+				if p := o.GetPreimage(k); len(p) > 0 {
+					return p
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// ComputingOracle returns an oracle that records each hinted value as a Keccak256 preimage.
+// The preimage can then be read back with the Keccak256 key type, as the real oracle would serve it.
+func ComputingOracle() mipsevm.PreimageOracle {
+	images := make(map[[32]byte][]byte)
+	return &TestOracle{
+		hint: func(v []byte) {
+			data := make([]byte, len(v))
+			copy(data, v)
+			images[preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()] = data
+		},
+		getPreimage: func(k [32]byte) []byte {
+			return images[k]
+		},
+	}
+}
+
+// FailingAfterOracle returns an oracle that serves the first n preimage requests from inner,
+// and returns an empty preimage for every request after that, as an exhausted oracle would.
+// Hints are always forwarded to inner.
+func FailingAfterOracle(inner mipsevm.PreimageOracle, n int) mipsevm.PreimageOracle {
+	served := 0
+	return &TestOracle{
+		hint: inner.Hint,
+		getPreimage: func(k [32]byte) []byte {
+			if served >= n {
+				return nil
+			}
+			served++
+			return inner.GetPreimage(k)
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/exec"
+	preimage "github.com/ethereum-optimism/optimism/op-preimage"
+)
+
+func TestChainOracles(t *testing.T) {
+	data := []byte("hello world")
+	key := preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+
+	first := &HintTrackingOracle{}
+	second := &TestOracle{
+		hint: func(v []byte) {},
+		getPreimage: func(k [32]byte) []byte {
+			require.Equal(t, key, k)
+			return data
+		},
+	}
+	oracle := ChainOracles(first, second)
+
+	require.Equal(t, data, oracle.GetPreimage(key))
+
+	oracle.Hint([]byte("hint"))
+	require.Equal(t, [][]byte{[]byte("hint")}, first.Hints())
+}
+
+func TestChainOracles_Missing(t *testing.T) {
+	oracle := ChainOracles(&HintTrackingOracle{}, &HintTrackingOracle{})
+	require.Nil(t, oracle.GetPreimage([32]byte{1}))
+}
+
+func TestComputingOracle(t *testing.T) {
+	data := []byte("hello world")
+	oracle := ComputingOracle()
+
+	key := preimage.Keccak256Key(crypto.Keccak256Hash(data)).PreimageKey()
+	require.Nil(t, oracle.GetPreimage(key))
+
+	oracle.Hint(data)
+	require.Equal(t, data, oracle.GetPreimage(key))
+
+	// The raw hash, without the key type prefix, must not resolve
+	require.Nil(t, oracle.GetPreimage(crypto.Keccak256Hash(data)))
+}
+
+func TestFailingAfterOracle(t *testing.T) {
+	oracle := ComputingOracle()
+	var keys [][32]byte
+	for _, v := range []string{"a", "b", "c"} {
+		oracle.Hint([]byte(v))
+		keys = append(keys, preimage.Keccak256Key(crypto.Keccak256Hash([]byte(v))).PreimageKey())
+	}
+
+	reader := exec.NewTrackingPreimageOracleReader(FailingAfterOracle(oracle, 2))
+	// The first two requests are served, so data past the length prefix is readable
+	require.NoError(t, reader.CheckOffset(keys[0], 8))
+	require.NoError(t, reader.CheckOffset(keys[1], 8))
+
+	// The third request is not, so only the (zero) length prefix can be read
+	require.ErrorIs(t, reader.CheckOffset(keys[2], 8), exec.ErrPreimageOffsetOutOfBounds)
+	require.NoError(t, reader.CheckOffset(keys[2], 0))
+	require.Nil(t, FailingAfterOracle(oracle, 0).GetPreimage(keys[0]))
+}
@@ -141,3 +141,15 @@ func RunVMTest_Claim[T mipsevm.FPVMState](t *testing.T, initState program.Create
 	require.Equal(t, expectedStdOut, stdOutBuf.String(), "stdout")
 	require.Equal(t, expectedStdErr, stdErrBuf.String(), "stderr")
 }
+
+// RequireExit runs the program in state until it exits, failing the test if it does not exit with wantCode within
+// maxSteps steps. Failures report the step and PC at which the program stopped.
+func RequireExit(t require.TestingT, state mipsevm.FPVMState, oracle mipsevm.PreimageOracle, wantCode uint8, maxSteps uint64) {
+	goVm := state.CreateVM(CreateLogger(), oracle, os.Stdout, os.Stderr, nil)
+	for i := uint64(0); i < maxSteps && !state.GetExited(); i++ {
+		_, err := goVm.Step(false)
+		require.NoErrorf(t, err, "vm failed at step %d, pc 0x%x", state.GetStep(), state.GetPC())
+	}
+	require.Truef(t, state.GetExited(), "must exit within %d steps. still running at step %d, pc 0x%x", maxSteps, state.GetStep(), state.GetPC())
+	require.Equalf(t, wantCode, state.GetExitCode(), "must exit with code %d. exited with %d at step %d, pc 0x%x", wantCode, state.GetExitCode(), state.GetStep(), state.GetPC())
+}
@@ -0,0 +1,65 @@
+package testutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
+	"github.com/ethereum-optimism/optimism/cannon/mipsevm/multithreaded"
+)
+
+// exitState returns a state running a program that calls exit_group with exitCode
+func exitState(exitCode uint8) *multithreaded.State {
+	state := multithreaded.CreateInitialState(0x1000, arch.HeapStart)
+	program := []uint32{
+		0x24_04_00_00 | uint32(exitCode),          // addiu $a0, $zero, exitCode
+		0x24_02_00_00 | uint32(arch.SysExitGroup), // addiu $v0, $zero, SysExitGroup
+		0x00_00_00_0c, // syscall
+	}
+	for i, insn := range program {
+		StoreInstruction(state.Memory, state.GetPC()+arch.Word(4*i), insn)
+	}
+	return state
+}
+
+func TestRequireExit(t *testing.T) {
+	t.Run("exit 0", func(t *testing.T) {
+		state, _ := LoadELFProgram(t, ProgramPath("hello"), multithreaded.CreateInitialState, false)
+		RequireExit(t, state, nil, 0, 500_000)
+	})
+
+	t.Run("exit non-zero", func(t *testing.T) {
+		state := exitState(3)
+		RequireExit(t, state, nil, 3, 3)
+		require.Equal(t, uint64(3), state.GetStep())
+	})
+
+	t.Run("wrong exit code", func(t *testing.T) {
+		mockT := &failureRecorder{}
+		RequireExit(mockT, exitState(3), nil, 0, 10)
+		require.Len(t, mockT.failures, 1)
+		require.Contains(t, mockT.failures[0], "must exit with code 0. exited with 3 at step 3, pc 0x1008")
+	})
+
+	t.Run("step budget exceeded", func(t *testing.T) {
+		mockT := &failureRecorder{}
+		RequireExit(mockT, exitState(0), nil, 0, 2)
+		require.NotEmpty(t, mockT.failures)
+		require.Contains(t, mockT.failures[0], "must exit within 2 steps. still running at step 2, pc 0x1008")
+	})
+}
+
+// failureRecorder is a require.TestingT that records failures instead of stopping the test
+type failureRecorder struct {
+	failures []string
+}
+
+var _ require.TestingT = (*failureRecorder)(nil)
+
+func (r *failureRecorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *failureRecorder) FailNow() {}
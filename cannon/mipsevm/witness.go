@@ -1,12 +1,23 @@
 package mipsevm
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/ethereum-optimism/optimism/cannon/mipsevm/arch"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
 type LocalContext common.Hash
 
+// WitnessField is a named field of an encoded state witness, at Size bytes from Offset.
+type WitnessField struct {
+	Name   string
+	Offset int
+	Size   int
+}
+
 type StepWitness struct {
 	// encoded state witness
 	State     []byte
@@ -23,6 +34,50 @@ func (wit *StepWitness) HasPreimage() bool {
 	return wit.PreimageKey != ([32]byte{})
 }
 
+type stepWitnessMarshaling struct {
+	State     hexutil.Bytes `json:"state"`
+	StateHash common.Hash   `json:"stateHash"`
+	ProofData hexutil.Bytes `json:"proofData"`
+
+	PreimageKey    hexutil.Bytes `json:"preimageKey,omitempty"`
+	PreimageValue  hexutil.Bytes `json:"preimageValue,omitempty"`
+	PreimageOffset arch.Word     `json:"preimageOffset,omitempty"`
+}
+
+func (wit *StepWitness) MarshalJSON() ([]byte, error) { // nosemgrep
+	wm := &stepWitnessMarshaling{
+		State:     wit.State,
+		StateHash: wit.StateHash,
+		ProofData: wit.ProofData,
+	}
+	if wit.HasPreimage() {
+		wm.PreimageKey = wit.PreimageKey[:]
+		wm.PreimageValue = wit.PreimageValue
+		wm.PreimageOffset = wit.PreimageOffset
+	}
+	return json.Marshal(wm)
+}
+
+func (wit *StepWitness) UnmarshalJSON(data []byte) error {
+	wm := new(stepWitnessMarshaling)
+	if err := json.Unmarshal(data, wm); err != nil {
+		return err
+	}
+	wit.State = wm.State
+	wit.StateHash = wm.StateHash
+	wit.ProofData = wm.ProofData
+	wit.PreimageKey = [32]byte{}
+	if len(wm.PreimageKey) > 0 {
+		if len(wm.PreimageKey) != len(wit.PreimageKey) {
+			return fmt.Errorf("invalid preimage key length: %d", len(wm.PreimageKey))
+		}
+		copy(wit.PreimageKey[:], wm.PreimageKey)
+	}
+	wit.PreimageValue = wm.PreimageValue
+	wit.PreimageOffset = wm.PreimageOffset
+	return nil
+}
+
 type HashFn func(sw []byte) (common.Hash, error)
 
 func AppendBoolToWitness(witnessData []byte, boolVal bool) []byte {
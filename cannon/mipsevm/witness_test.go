@@ -0,0 +1,65 @@
+package mipsevm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/packages/contracts-bedrock/snapshots"
+)
+
+func TestStepWitness_JSONCodec(t *testing.T) {
+	cases := []struct {
+		name string
+		wit  *StepWitness
+	}{
+		{
+			name: "without preimage",
+			wit: &StepWitness{
+				State:     []byte{0x01, 0x02, 0x03},
+				StateHash: common.Hash{0xaa},
+				ProofData: []byte{0x04, 0x05},
+			},
+		},
+		{
+			name: "with preimage",
+			wit: &StepWitness{
+				State:          []byte{0x01, 0x02, 0x03},
+				StateHash:      common.Hash{0xbb},
+				ProofData:      []byte{0x04, 0x05},
+				PreimageKey:    [32]byte{0x02, 0xff},
+				PreimageValue:  []byte{0, 0, 0, 0, 0, 0, 0, 2, 0xde, 0xad},
+				PreimageOffset: 4,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data, err := json.Marshal(c.wit)
+			require.NoError(t, err)
+
+			var decoded StepWitness
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			require.Equal(t, c.wit, &decoded)
+			require.Equal(t, c.wit.HasPreimage(), decoded.HasPreimage())
+
+			// The decoded witness produces identical step calldata
+			mips := snapshots.LoadMIPSABI()
+			localContext := LocalContext{0x01}
+			expected, err := mips.Pack("step", c.wit.State, c.wit.ProofData, localContext)
+			require.NoError(t, err)
+			actual, err := mips.Pack("step", decoded.State, decoded.ProofData, localContext)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestStepWitness_UnmarshalInvalidPreimageKey(t *testing.T) {
+	var wit StepWitness
+	err := json.Unmarshal([]byte(`{"state":"0x01","proofData":"0x02","preimageKey":"0x0102"}`), &wit)
+	require.ErrorContains(t, err, "invalid preimage key length")
+}
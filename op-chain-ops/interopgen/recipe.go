@@ -1,6 +1,7 @@
 package interopgen
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -14,6 +15,23 @@ import (
 	"github.com/ethereum-optimism/optimism/op-chain-ops/genesis"
 )
 
+// ErrZeroAddress is returned when a key resolves to the zero address,
+// e.g. because of a malformed derivation path or an empty key.
+var ErrZeroAddress = errors.New("key resolved to zero address")
+
+// roleAddress resolves the address of the given key, and rejects the zero address,
+// so that a broken key source cannot silently configure an unusable role.
+func roleAddress(addrs devkeys.Addresses, key devkeys.Key) (common.Address, error) {
+	addr, err := addrs.Address(key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if addr == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s", ErrZeroAddress, key)
+	}
+	return addr, nil
+}
+
 type InteropDevRecipe struct {
 	L1ChainID        uint64
 	L2ChainIDs       []uint64
@@ -35,7 +53,7 @@ func (r *InteropDevRecipe) Build(addrs devkeys.Addresses) (*WorldConfig, error)
 	// TODO(#11887): consider making the number of prefunded keys configurable.
 	l1Users := devkeys.ChainUserKeys(l1Cfg.ChainID)
 	for i := uint64(0); i < 20; i++ {
-		userAddr, err := addrs.Address(l1Users(i))
+		userAddr, err := roleAddress(addrs, l1Users(i))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get L1 user addr %d: %w", i, err)
 		}
@@ -44,19 +62,19 @@ func (r *InteropDevRecipe) Build(addrs devkeys.Addresses) (*WorldConfig, error)
 
 	superchainOps := devkeys.SuperchainOperatorKeys(l1Cfg.ChainID)
 
-	superchainDeployer, err := addrs.Address(superchainOps(devkeys.SuperchainDeployerKey))
+	superchainDeployer, err := roleAddress(addrs, superchainOps(devkeys.SuperchainDeployerKey))
 	if err != nil {
 		return nil, err
 	}
-	superchainProxyAdmin, err := addrs.Address(superchainOps(devkeys.SuperchainProxyAdminOwner))
+	superchainProxyAdmin, err := roleAddress(addrs, superchainOps(devkeys.SuperchainProxyAdminOwner))
 	if err != nil {
 		return nil, err
 	}
-	superchainProtocolVersionsOwner, err := addrs.Address(superchainOps(devkeys.SuperchainProtocolVersionsOwner))
+	superchainProtocolVersionsOwner, err := roleAddress(addrs, superchainOps(devkeys.SuperchainProtocolVersionsOwner))
 	if err != nil {
 		return nil, err
 	}
-	superchainConfigGuardian, err := addrs.Address(superchainOps(devkeys.SuperchainConfigGuardianKey))
+	superchainConfigGuardian, err := roleAddress(addrs, superchainOps(devkeys.SuperchainConfigGuardianKey))
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +128,7 @@ func prefundL2Accounts(l1Cfg *L1Config, l2Cfg *L2Config, addrs devkeys.Addresses
 	l1Cfg.Prefund[l2Cfg.Deployer] = Ether(10_000_000)
 	l1Cfg.Prefund[l2Cfg.FinalSystemOwner] = Ether(10_000_000)
 	l1Cfg.Prefund[l2Cfg.SystemConfigOwner] = Ether(10_000_000)
-	proposer, err := addrs.Address(devkeys.ChainOperatorKey{
+	proposer, err := roleAddress(addrs, devkeys.ChainOperatorKey{
 		ChainID: new(big.Int).SetUint64(l2Cfg.L2ChainID),
 		Role:    devkeys.ProposerRole,
 	})
@@ -118,7 +136,7 @@ func prefundL2Accounts(l1Cfg *L1Config, l2Cfg *L2Config, addrs devkeys.Addresses
 		return err
 	}
 	l1Cfg.Prefund[proposer] = Ether(10_000_000)
-	challenger, err := addrs.Address(devkeys.ChainOperatorKey{
+	challenger, err := roleAddress(addrs, devkeys.ChainOperatorKey{
 		ChainID: new(big.Int).SetUint64(l2Cfg.L2ChainID),
 		Role:    devkeys.ChallengerRole,
 	})
@@ -134,47 +152,47 @@ func InteropL2DevConfig(l1ChainID, l2ChainID uint64, addrs devkeys.Addresses) (*
 	batchInboxAddress := common.HexToAddress(fmt.Sprintf("0xff02%016x", l2ChainID))
 	chainOps := devkeys.ChainOperatorKeys(new(big.Int).SetUint64(l2ChainID))
 
-	deployer, err := addrs.Address(chainOps(devkeys.DeployerRole))
+	deployer, err := roleAddress(addrs, chainOps(devkeys.DeployerRole))
 	if err != nil {
 		return nil, err
 	}
-	l1ProxyAdminOwner, err := addrs.Address(chainOps(devkeys.L1ProxyAdminOwnerRole))
+	l1ProxyAdminOwner, err := roleAddress(addrs, chainOps(devkeys.L1ProxyAdminOwnerRole))
 	if err != nil {
 		return nil, err
 	}
-	l2ProxyAdminOwner, err := addrs.Address(chainOps(devkeys.L2ProxyAdminOwnerRole))
+	l2ProxyAdminOwner, err := roleAddress(addrs, chainOps(devkeys.L2ProxyAdminOwnerRole))
 	if err != nil {
 		return nil, err
 	}
-	baseFeeVaultRecipient, err := addrs.Address(chainOps(devkeys.BaseFeeVaultRecipientRole))
+	baseFeeVaultRecipient, err := roleAddress(addrs, chainOps(devkeys.BaseFeeVaultRecipientRole))
 	if err != nil {
 		return nil, err
 	}
-	l1FeeVaultRecipient, err := addrs.Address(chainOps(devkeys.L1FeeVaultRecipientRole))
+	l1FeeVaultRecipient, err := roleAddress(addrs, chainOps(devkeys.L1FeeVaultRecipientRole))
 	if err != nil {
 		return nil, err
 	}
-	sequencerFeeVaultRecipient, err := addrs.Address(chainOps(devkeys.SequencerFeeVaultRecipientRole))
+	sequencerFeeVaultRecipient, err := roleAddress(addrs, chainOps(devkeys.SequencerFeeVaultRecipientRole))
 	if err != nil {
 		return nil, err
 	}
-	sequencerP2P, err := addrs.Address(chainOps(devkeys.SequencerP2PRole))
+	sequencerP2P, err := roleAddress(addrs, chainOps(devkeys.SequencerP2PRole))
 	if err != nil {
 		return nil, err
 	}
-	batcher, err := addrs.Address(chainOps(devkeys.BatcherRole))
+	batcher, err := roleAddress(addrs, chainOps(devkeys.BatcherRole))
 	if err != nil {
 		return nil, err
 	}
-	proposer, err := addrs.Address(chainOps(devkeys.ProposerRole))
+	proposer, err := roleAddress(addrs, chainOps(devkeys.ProposerRole))
 	if err != nil {
 		return nil, err
 	}
-	challenger, err := addrs.Address(chainOps(devkeys.ChallengerRole))
+	challenger, err := roleAddress(addrs, chainOps(devkeys.ChallengerRole))
 	if err != nil {
 		return nil, err
 	}
-	systemConfigOwner, err := addrs.Address(chainOps(devkeys.SystemConfigOwner))
+	systemConfigOwner, err := roleAddress(addrs, chainOps(devkeys.SystemConfigOwner))
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +285,7 @@ func InteropL2DevConfig(l1ChainID, l2ChainID uint64, addrs devkeys.Addresses) (*
 	// TODO(#11887): consider making the number of prefunded keys configurable.
 	l2Users := devkeys.ChainUserKeys(new(big.Int).SetUint64(l2ChainID))
 	for i := uint64(0); i < 20; i++ {
-		userAddr, err := addrs.Address(l2Users(i))
+		userAddr, err := roleAddress(addrs, l2Users(i))
 		if err != nil {
 			return nil, fmt.Errorf("failed to get L2 user addr %d: %w", i, err)
 		}
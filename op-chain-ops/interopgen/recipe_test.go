@@ -0,0 +1,53 @@
+package interopgen
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ethereum-optimism/optimism/op-chain-ops/devkeys"
+)
+
+// zeroRoleAddresses resolves every key through the wrapped key source,
+// except for the zero key, which resolves to the zero address.
+type zeroRoleAddresses struct {
+	devkeys.Addresses
+	zero devkeys.Key
+}
+
+func (z *zeroRoleAddresses) Address(key devkeys.Key) (common.Address, error) {
+	if key.String() == z.zero.String() {
+		return common.Address{}, nil
+	}
+	return z.Addresses.Address(key)
+}
+
+func TestInteropDevRecipe_ZeroAddress(t *testing.T) {
+	dk, err := devkeys.NewMnemonicDevKeys(devkeys.TestMnemonic)
+	require.NoError(t, err)
+
+	recipe := &InteropDevRecipe{
+		L1ChainID:  900100,
+		L2ChainIDs: []uint64{900200},
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		_, err := recipe.Build(dk)
+		require.NoError(t, err)
+	})
+
+	t.Run("zero batcher", func(t *testing.T) {
+		batcher := devkeys.ChainOperatorKeys(new(big.Int).SetUint64(900200))(devkeys.BatcherRole)
+		_, err := recipe.Build(&zeroRoleAddresses{Addresses: dk, zero: batcher})
+		require.ErrorIs(t, err, ErrZeroAddress)
+		require.ErrorContains(t, err, batcher.String())
+	})
+
+	t.Run("zero superchain guardian", func(t *testing.T) {
+		guardian := devkeys.SuperchainOperatorKeys(new(big.Int).SetUint64(900100))(devkeys.SuperchainConfigGuardianKey)
+		_, err := recipe.Build(&zeroRoleAddresses{Addresses: dk, zero: guardian})
+		require.ErrorIs(t, err, ErrZeroAddress)
+	})
+}
@@ -9,7 +9,9 @@ import (
 )
 
 var (
-	ErrPositionDepthTooSmall = errors.New("position depth is too small")
+	ErrPositionDepthTooSmall   = errors.New("position depth is too small")
+	ErrNegativeBisectionDepth  = errors.New("bisection depth is negative")
+	ErrBisectionTargetTooLarge = errors.New("bisection target is beyond the last step")
 
 	RootPosition = NewPositionFromGIndex(big.NewInt(1))
 )
@@ -154,3 +156,41 @@ func bigMSB(x *big.Int) Depth {
 	}
 	return Depth(x.BitLen() - 1)
 }
+
+// BisectionSteps returns the trace index committed to at each level of a game of the given depth when bisecting
+// towards the target trace index, the first step whose post-state is disputed. Each claim that commits to the target
+// or a later index is attacked and each claim that commits to an earlier index is defended, so the leaf is either the
+// target or the step before it. Element i is the trace index of the claim at depth i, so the root is first and the
+// leaf that is verified by executing a single step is last.
+// Trace indices beyond the last step of a trace with totalSteps steps are clamped to the last step, matching how the
+// trace is extended with no-op instructions after the program exits.
+func BisectionSteps(totalSteps uint64, depth int, target uint64) ([]uint64, error) {
+	if depth < 0 {
+		return nil, ErrNegativeBisectionDepth
+	}
+	maxDepth := Depth(depth)
+	lastStep := uint64(0)
+	if totalSteps > 0 {
+		lastStep = totalSteps - 1
+	}
+	if target > lastStep {
+		return nil, fmt.Errorf("%w: target %d, last step %d", ErrBisectionTargetTooLarge, target, lastStep)
+	}
+	steps := make([]uint64, 0, depth+1)
+	pos := RootPosition
+	for {
+		traceIndex := lastStep
+		if ti := pos.TraceIndex(maxDepth); ti.IsUint64() && ti.Uint64() < lastStep {
+			traceIndex = ti.Uint64()
+		}
+		steps = append(steps, traceIndex)
+		if pos.Depth() == maxDepth {
+			return steps, nil
+		}
+		if traceIndex >= target {
+			pos = pos.Attack()
+		} else {
+			pos = pos.Defend()
+		}
+	}
+}
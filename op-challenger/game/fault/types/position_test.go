@@ -314,3 +314,72 @@ func TestRelativeMoves(t *testing.T) {
 		})
 	}
 }
+
+func TestBisectionSteps(t *testing.T) {
+	tests := []struct {
+		name       string
+		totalSteps uint64
+		depth      int
+		target     uint64
+		expected   []uint64
+	}{
+		{name: "RootOnly", totalSteps: 10, depth: 0, target: 0, expected: []uint64{0}},
+		{name: "FirstStep", totalSteps: 16, depth: 4, target: 0, expected: []uint64{15, 7, 3, 1, 0}},
+		{name: "MiddleStep", totalSteps: 16, depth: 4, target: 5, expected: []uint64{15, 7, 3, 5, 4}},
+		{name: "LastStep", totalSteps: 16, depth: 4, target: 15, expected: []uint64{15, 7, 11, 13, 14}},
+		{name: "LongerThanGame", totalSteps: 1000, depth: 4, target: 0, expected: []uint64{15, 7, 3, 1, 0}},
+		{name: "ShortTrace", totalSteps: 6, depth: 4, target: 5, expected: []uint64{5, 5, 3, 5, 4}},
+		{name: "SingleStep", totalSteps: 1, depth: 3, target: 0, expected: []uint64{0, 0, 0, 0}},
+		{name: "EmptyTrace", totalSteps: 0, depth: 2, target: 0, expected: []uint64{0, 0, 0}},
+		{name: "MaxDepth", totalSteps: math.MaxUint64, depth: 64, target: 0, expected: func() []uint64 {
+			steps := make([]uint64, 65)
+			for i := range steps {
+				steps[i] = math.MaxUint64 >> i
+			}
+			steps[0] = math.MaxUint64 - 1 // clamped to the last step
+			return steps
+		}()},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			steps, err := BisectionSteps(test.totalSteps, test.depth, test.target)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, steps)
+		})
+	}
+
+	t.Run("NegativeDepth", func(t *testing.T) {
+		_, err := BisectionSteps(16, -1, 0)
+		require.ErrorIs(t, err, ErrNegativeBisectionDepth)
+	})
+
+	t.Run("TargetBeyondLastStep", func(t *testing.T) {
+		_, err := BisectionSteps(16, 4, 16)
+		require.ErrorIs(t, err, ErrBisectionTargetTooLarge)
+	})
+
+	t.Run("EveryTarget", func(t *testing.T) {
+		const depth = 4
+		for target := uint64(0); target < 1<<depth; target++ {
+			steps, err := BisectionSteps(1<<depth, depth, target)
+			require.NoError(t, err)
+			// Each claim is attacked or defended to reach the next one
+			pos := RootPosition
+			for i, step := range steps {
+				require.Equal(t, pos.TraceIndex(depth).Uint64(), step)
+				if i == len(steps)-1 {
+					break
+				}
+				if step >= target {
+					pos = pos.Attack()
+				} else {
+					pos = pos.Defend()
+				}
+			}
+			// The leaf is the target or the step before it
+			leaf := steps[len(steps)-1]
+			require.True(t, leaf == target || leaf+1 == target, "target %d, leaf %d", target, leaf)
+		}
+	})
+}
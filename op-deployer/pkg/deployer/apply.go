@@ -3,6 +3,7 @@ package deployer
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -31,11 +32,17 @@ import (
 	"github.com/urfave/cli/v2"
 )
 
+var (
+	ErrOfflineL1Required        = errors.New("deployment requires an L1 connection, but offline mode is enabled")
+	ErrOfflineArtifactsRequired = errors.New("artifacts must be read from a file:// locator in offline mode")
+)
+
 type ApplyConfig struct {
 	L1RPCUrl   string
 	Workdir    string
 	PrivateKey string
 	Logger     log.Logger
+	Offline    bool
 
 	privateKeyECDSA *ecdsa.PrivateKey
 }
@@ -81,6 +88,7 @@ func ApplyCLI() func(cliCtx *cli.Context) error {
 		l1RPCUrl := cliCtx.String(L1RPCURLFlagName)
 		workdir := cliCtx.String(WorkdirFlagName)
 		privateKey := cliCtx.String(PrivateKeyFlagName)
+		offline := cliCtx.Bool(OfflineFlagName)
 
 		ctx := ctxinterrupt.WithCancelOnInterrupt(cliCtx.Context)
 
@@ -89,6 +97,7 @@ func ApplyCLI() func(cliCtx *cli.Context) error {
 			Workdir:    workdir,
 			PrivateKey: privateKey,
 			Logger:     l,
+			Offline:    offline,
 		})
 	}
 }
@@ -115,6 +124,7 @@ func Apply(ctx context.Context, cfg ApplyConfig) error {
 		State:              st,
 		Logger:             cfg.Logger,
 		StateWriter:        pipeline.WorkdirStateWriter(cfg.Workdir),
+		Offline:            cfg.Offline,
 	}); err != nil {
 		return err
 	}
@@ -134,6 +144,9 @@ type ApplyPipelineOpts struct {
 	State              *state.State
 	Logger             log.Logger
 	StateWriter        pipeline.StateWriter
+	// Offline prevents any network access. Deployments that need on-chain data fail with ErrOfflineL1Required, and
+	// artifacts that would have to be downloaded fail with ErrOfflineArtifactsRequired.
+	Offline bool
 }
 
 func ApplyPipeline(
@@ -141,6 +154,17 @@ func ApplyPipeline(
 	opts ApplyPipelineOpts,
 ) error {
 	intent := opts.Intent
+	if opts.Offline {
+		if intent.DeploymentStrategy == state.DeploymentStrategyLive {
+			return ErrOfflineL1Required
+		}
+		// Undefined locators are reported by intent.Check below.
+		for _, loc := range []*artifacts.Locator{intent.L1ContractsLocator, intent.L2ContractsLocator} {
+			if loc != nil && !loc.IsLocal() {
+				return ErrOfflineArtifactsRequired
+			}
+		}
+	}
 	if err := intent.Check(); err != nil {
 		return err
 	}
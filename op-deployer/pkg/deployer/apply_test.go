@@ -0,0 +1,78 @@
+package deployer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/op-deployer/pkg/deployer/artifacts"
+	"github.com/ethereum-optimism/optimism/op-deployer/pkg/deployer/state"
+	"github.com/ethereum-optimism/optimism/op-service/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPipeline_Offline(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := ApplyPipeline(context.Background(), ApplyPipelineOpts{
+		L1RPCUrl: srv.URL,
+		Intent: &state.Intent{
+			DeploymentStrategy: state.DeploymentStrategyLive,
+		},
+		State:   &state.State{},
+		Logger:  testlog.Logger(t, log.LevelInfo),
+		Offline: true,
+	})
+	require.ErrorIs(t, err, ErrOfflineL1Required)
+	require.Zero(t, requests.Load(), "must not connect to L1 in offline mode")
+}
+
+func TestApplyPipeline_OfflineRemoteArtifacts(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	srvURL, err := url.Parse(srv.URL + "/artifacts.tar.gz")
+	require.NoError(t, err)
+	localURL, err := url.Parse("file:///artifacts")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		l1   *artifacts.Locator
+		l2   *artifacts.Locator
+	}{
+		{"remote L1 artifacts", &artifacts.Locator{URL: srvURL}, &artifacts.Locator{URL: localURL}},
+		{"remote L2 artifacts", &artifacts.Locator{URL: localURL}, &artifacts.Locator{URL: srvURL}},
+		{"tagged artifacts", artifacts.DefaultL1ContractsLocator, artifacts.DefaultL2ContractsLocator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ApplyPipeline(context.Background(), ApplyPipelineOpts{
+				L1RPCUrl: srv.URL,
+				Intent: &state.Intent{
+					DeploymentStrategy: state.DeploymentStrategyGenesis,
+					L1ContractsLocator: tt.l1,
+					L2ContractsLocator: tt.l2,
+				},
+				State:   &state.State{},
+				Logger:  testlog.Logger(t, log.LevelInfo),
+				Offline: true,
+			})
+			require.ErrorIs(t, err, ErrOfflineArtifactsRequired)
+		})
+	}
+	require.Zero(t, requests.Load(), "must not access the network in offline mode")
+}
@@ -77,6 +77,11 @@ func (a *Locator) IsTag() bool {
 	return a.Tag != ""
 }
 
+// IsLocal returns whether the artifacts are read from the local filesystem, without a download.
+func (a *Locator) IsLocal() bool {
+	return a.URL != nil && a.URL.Scheme == "file"
+}
+
 func unmarshalTag(tag string) (*Locator, error) {
 	tag = strings.TrimPrefix(tag, "tag://")
 	if !strings.HasPrefix(tag, "op-contracts/") {
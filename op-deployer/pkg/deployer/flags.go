@@ -21,6 +21,7 @@ const (
 	PrivateKeyFlagName         = "private-key"
 	DeploymentStrategyFlagName = "deployment-strategy"
 	IntentConfigTypeFlagName   = "intent-config-type"
+	OfflineFlagName            = "offline"
 )
 
 var (
@@ -74,6 +75,12 @@ var (
 		EnvVars: PrefixEnvVar("INTENT_CONFIG_TYPE"),
 		Value:   string(state.IntentConfigTypeStandard),
 	}
+	OfflineFlag = &cli.BoolFlag{
+		Name: OfflineFlagName,
+		Usage: "Never access the network. Deployments that require on-chain data, such as " +
+			"the live deployment strategy, or artifacts that are not file:// locators, will fail instead.",
+		EnvVars: PrefixEnvVar("OFFLINE"),
+	}
 )
 
 var GlobalFlags = append([]cli.Flag{}, oplog.CLIFlags(EnvVarPrefix)...)
@@ -90,6 +97,7 @@ var ApplyFlags = []cli.Flag{
 	L1RPCURLFlag,
 	WorkdirFlag,
 	PrivateKeyFlag,
+	OfflineFlag,
 }
 
 func PrefixEnvVar(name string) []string {
@@ -0,0 +1,217 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum-optimism/optimism/op-deployer/pkg/deployer/state"
+	"github.com/ethereum-optimism/optimism/op-service/ioutil"
+	"github.com/ethereum-optimism/optimism/op-service/jsonutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+// Change is a single field that differs between two deployment states. Old or New is nil
+// when the field is only present on one side.
+type Change struct {
+	Path string `json:"path"`
+	Old  any    `json:"old"`
+	New  any    `json:"new"`
+}
+
+func DiffCLI(cliCtx *cli.Context) error {
+	outfile := cliCtx.String(OutfileFlagName)
+	if outfile == "" {
+		return fmt.Errorf("outfile flag is required")
+	}
+
+	if cliCtx.NArg() != 2 {
+		return fmt.Errorf("expected exactly two state files, got %d", cliCtx.NArg())
+	}
+
+	changes, err := DiffStateFiles(cliCtx.Args().Get(0), cliCtx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	if changes == nil {
+		changes = []Change{}
+	}
+	if err := jsonutil.WriteJSON(changes, ioutil.ToStdOutOrFileOrNoop(outfile, 0o666)); err != nil {
+		return fmt.Errorf("failed to write diff: %w", err)
+	}
+
+	return nil
+}
+
+// DiffStateFiles reads two state.json files and returns the changes between them.
+func DiffStateFiles(oldPath, newPath string) ([]Change, error) {
+	oldState, err := jsonutil.LoadJSON[state.State](oldPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read old state: %w", err)
+	}
+	newState, err := jsonutil.LoadJSON[state.State](newPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new state: %w", err)
+	}
+	return DiffStates(oldState, newState)
+}
+
+// DiffStates returns a field-level diff of the applied intents of two states and of the deploy
+// configs derived from them. Chains are matched by ID, so reordering chains in the intent is not
+// reported as a change.
+func DiffStates(oldState, newState *state.State) ([]Change, error) {
+	if oldState.AppliedIntent == nil || newState.AppliedIntent == nil {
+		return nil, fmt.Errorf("can only diff states following a full apply")
+	}
+
+	oldIntent := *oldState.AppliedIntent
+	oldIntent.Chains = nil
+	newIntent := *newState.AppliedIntent
+	newIntent.Chains = nil
+
+	changes, err := diffJSON("intent", &oldIntent, &newIntent)
+	if err != nil {
+		return nil, err
+	}
+
+	oldChains := chainIntentsByID(oldState.AppliedIntent)
+	newChains := chainIntentsByID(newState.AppliedIntent)
+	for _, id := range chainIDs(oldState.AppliedIntent, newState.AppliedIntent) {
+		prefix := fmt.Sprintf("chains[%s]", id.Hex())
+
+		oldChain, newChain := oldChains[id], newChains[id]
+		if oldChain == nil || newChain == nil {
+			changes = append(changes, Change{Path: prefix, Old: chainPresence(oldChain), New: chainPresence(newChain)})
+			continue
+		}
+
+		intentChanges, err := diffJSON(prefix+".intent", oldChain, newChain)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, intentChanges...)
+
+		oldCfg, err := DeployConfig(oldState, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate old deploy config for chain %s: %w", id.Hex(), err)
+		}
+		newCfg, err := DeployConfig(newState, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate new deploy config for chain %s: %w", id.Hex(), err)
+		}
+		cfgChanges, err := diffJSON(prefix+".deployConfig", oldCfg, newCfg)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, cfgChanges...)
+	}
+
+	return changes, nil
+}
+
+func chainIntentsByID(intent *state.Intent) map[common.Hash]*state.ChainIntent {
+	out := make(map[common.Hash]*state.ChainIntent, len(intent.Chains))
+	for _, chain := range intent.Chains {
+		out[chain.ID] = chain
+	}
+	return out
+}
+
+// chainIDs returns the IDs of the chains in the old intent, in order, followed by the IDs of any
+// chains only present in the new intent.
+func chainIDs(oldIntent, newIntent *state.Intent) []common.Hash {
+	seen := make(map[common.Hash]bool)
+	var ids []common.Hash
+	for _, intent := range []*state.Intent{oldIntent, newIntent} {
+		for _, chain := range intent.Chains {
+			if !seen[chain.ID] {
+				seen[chain.ID] = true
+				ids = append(ids, chain.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func chainPresence(chain *state.ChainIntent) any {
+	if chain == nil {
+		return nil
+	}
+	return "present"
+}
+
+// diffJSON compares the JSON encodings of a and b and returns a Change for every leaf value that
+// differs. Object keys are visited in sorted order so that the output is deterministic.
+func diffJSON(path string, a, b any) ([]Change, error) {
+	aVal, err := toJSONValue(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	bVal, err := toJSONValue(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	var changes []Change
+	diffValues(path, aVal, bVal, &changes)
+	return changes, nil
+}
+
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out any
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func diffValues(path string, a, b any, changes *[]Change) {
+	switch aVal := a.(type) {
+	case map[string]any:
+		if bVal, ok := b.(map[string]any); ok {
+			keys := make([]string, 0, len(aVal)+len(bVal))
+			for k := range aVal {
+				keys = append(keys, k)
+			}
+			for k := range bVal {
+				if _, ok := aVal[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				diffValues(path+"."+k, aVal[k], bVal[k], changes)
+			}
+			return
+		}
+	case []any:
+		if bVal, ok := b.([]any); ok {
+			for i := 0; i < max(len(aVal), len(bVal)); i++ {
+				var aElem, bElem any
+				if i < len(aVal) {
+					aElem = aVal[i]
+				}
+				if i < len(bVal) {
+					bElem = bVal[i]
+				}
+				diffValues(path+"["+strconv.Itoa(i)+"]", aElem, bElem, changes)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Old: a, New: b})
+	}
+}
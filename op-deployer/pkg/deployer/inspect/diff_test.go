@@ -0,0 +1,86 @@
+package inspect
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum-optimism/optimism/op-deployer/pkg/deployer/state"
+	"github.com/ethereum-optimism/optimism/op-service/ioutil"
+	"github.com/ethereum-optimism/optimism/op-service/jsonutil"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffStateFiles(t *testing.T) {
+	chainID := common.HexToHash("0x123")
+	newState := func() *state.State {
+		return &state.State{
+			AppliedIntent: &state.Intent{
+				L1ChainID: 1,
+				Chains: []*state.ChainIntent{
+					{
+						ID:                         chainID,
+						Eip1559Denominator:         50,
+						Eip1559Elasticity:          6,
+						BaseFeeVaultRecipient:      common.HexToAddress("0x123"),
+						L1FeeVaultRecipient:        common.HexToAddress("0x456"),
+						SequencerFeeVaultRecipient: common.HexToAddress("0x789"),
+						Roles: state.ChainRoles{
+							SystemConfigOwner: common.HexToAddress("0x123"),
+							L1ProxyAdminOwner: common.HexToAddress("0x456"),
+							L2ProxyAdminOwner: common.HexToAddress("0x789"),
+							UnsafeBlockSigner: common.HexToAddress("0xabc"),
+							Batcher:           common.HexToAddress("0xdef"),
+						},
+					},
+				},
+			},
+			SuperchainDeployment: &state.SuperchainDeployment{
+				ProtocolVersionsProxyAddress: common.HexToAddress("0x123"),
+			},
+			Chains: []*state.ChainState{
+				{
+					ID:                       chainID,
+					SystemConfigProxyAddress: common.HexToAddress("0xaaa"),
+				},
+			},
+		}
+	}
+
+	oldSt := newState()
+	newSt := newState()
+	newSt.AppliedIntent.Chains[0].Roles.Batcher = common.HexToAddress("0xbbb")
+	newSt.AppliedIntent.Chains[0].Eip1559Elasticity = 10
+	newSt.AppliedIntent.FundDevAccounts = true
+
+	dir := t.TempDir()
+	oldPath := filepath.Join(dir, "old.json")
+	newPath := filepath.Join(dir, "new.json")
+	require.NoError(t, jsonutil.WriteJSON(oldSt, ioutil.ToStdOutOrFileOrNoop(oldPath, 0o666)))
+	require.NoError(t, jsonutil.WriteJSON(newSt, ioutil.ToStdOutOrFileOrNoop(newPath, 0o666)))
+
+	changes, err := DiffStateFiles(oldPath, newPath)
+	require.NoError(t, err)
+
+	prefix := "chains[" + chainID.Hex() + "]"
+	require.Equal(t, []Change{
+		{Path: "intent.fundDevAccounts", Old: false, New: true},
+		{Path: prefix + ".intent.eip1559Elasticity", Old: json.Number("6"), New: json.Number("10")},
+		{
+			Path: prefix + ".intent.roles.batcher",
+			Old:  "0x0000000000000000000000000000000000000def",
+			New:  "0x0000000000000000000000000000000000000bbb",
+		},
+		{
+			Path: prefix + ".deployConfig.batchSenderAddress",
+			Old:  "0x0000000000000000000000000000000000000def",
+			New:  "0x0000000000000000000000000000000000000bbb",
+		},
+		{Path: prefix + ".deployConfig.eip1559Elasticity", Old: json.Number("6"), New: json.Number("10")},
+	}, changes)
+
+	unchanged, err := DiffStateFiles(oldPath, oldPath)
+	require.NoError(t, err)
+	require.Empty(t, unchanged)
+}
@@ -77,6 +77,14 @@ var Commands = []*cli.Command{
 		Action:    SuperchainRegistryCLI,
 		Flags:     Flags,
 	},
+	{
+		Name:      "diff",
+		Usage:     "outputs the changes to the intent and deploy configs between two state files",
+		Args:      true,
+		ArgsUsage: "<old-state-file> <new-state-file>",
+		Action:    DiffCLI,
+		Flags:     []cli.Flag{FlagOutfile},
+	},
 }
 
 type cliConfig struct {
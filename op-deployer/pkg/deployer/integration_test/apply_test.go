@@ -12,8 +12,11 @@ import (
 	"log/slog"
 	"maps"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -485,6 +488,29 @@ func TestApplyGenesisStrategy(t *testing.T) {
 	}
 }
 
+func TestApplyGenesisStrategyOffline(t *testing.T) {
+	op_e2e.InitParallel(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	opts, intent, st := setupGenesisChain(t, defaultL1ChainID)
+	opts.L1RPCUrl = srv.URL
+	opts.Offline = true
+
+	require.NoError(t, deployer.ApplyPipeline(ctx, opts))
+	require.Zero(t, requests.Load(), "must not access the network in offline mode")
+	require.NotNil(t, st.L1StateDump)
+	require.Equal(t, intent, st.AppliedIntent)
+}
+
 func TestProofParamOverrides(t *testing.T) {
 	op_e2e.InitParallel(t)
 
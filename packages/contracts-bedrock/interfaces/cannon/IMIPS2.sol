@@ -14,6 +14,7 @@ interface IMIPS2 is ISemver {
         uint32 futexAddr;
         uint32 futexVal;
         uint64 futexTimeoutStep;
+        uint32 futexBitset;
         uint32 pc;
         uint32 nextPC;
         uint32 lo;
@@ -34,6 +35,7 @@ interface IMIPS2 is ISemver {
         uint64 step;
         uint64 stepsSinceLastContextSwitch;
         uint32 wakeup;
+        uint32 wakeupBitset;
         bool traverseRight;
         bytes32 leftThreadStack;
         bytes32 rightThreadStack;
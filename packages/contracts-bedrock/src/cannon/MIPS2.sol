@@ -20,7 +20,7 @@ import { IPreimageOracle } from "interfaces/cannon/IPreimageOracle.sol";
 ///         It differs from MIPS.sol in that it supports multi-threading.
 contract MIPS2 is ISemver {
     /// @notice The thread context.
-    ///         Total state size: 4 + 1 + 1 + 4 + 4 + 8 + 4 + 4 + 4 + 4 + 32 * 4 = 166 bytes
+    ///         Total state size: 4 + 1 + 1 + 4 + 4 + 8 + 4 + 4 + 4 + 4 + 4 + 32 * 4 = 170 bytes
     struct ThreadState {
         // metadata
         uint32 threadID;
@@ -30,6 +30,7 @@ contract MIPS2 is ISemver {
         uint32 futexAddr;
         uint32 futexVal;
         uint64 futexTimeoutStep;
+        uint32 futexBitset;
         uint32 pc;
         uint32 nextPC;
         uint32 lo;
@@ -41,7 +42,7 @@ contract MIPS2 is ISemver {
     uint8 internal constant LL_STATUS_ACTIVE = 1;
 
     /// @notice Stores the VM state.
-    ///         Total state size: 32 + 32 + 4 + 4 + 1 + 4 + 4 + 1 + 1 + 8 + 8 + 4 + 1 + 32 + 32 + 4 = 172 bytes
+    ///         Total state size: 32 + 32 + 4 + 4 + 1 + 4 + 4 + 1 + 1 + 8 + 8 + 4 + 4 + 1 + 32 + 32 + 4 = 176 bytes
     ///         If nextPC != pc + 4, then the VM is executing a branch/jump delay slot.
     struct State {
         bytes32 memRoot;
@@ -56,6 +57,7 @@ contract MIPS2 is ISemver {
         uint64 step;
         uint64 stepsSinceLastContextSwitch;
         uint32 wakeup;
+        uint32 wakeupBitset;
         bool traverseRight;
         bytes32 leftThreadStack;
         bytes32 rightThreadStack;
@@ -63,8 +65,8 @@ contract MIPS2 is ISemver {
     }
 
     /// @notice The semantic version of the MIPS2 contract.
-    /// @custom:semver 1.0.0-beta.26
-    string public constant version = "1.0.0-beta.26";
+    /// @custom:semver 1.0.0-beta.27
+    string public constant version = "1.0.0-beta.27";
 
     /// @notice The preimage oracle contract.
     IPreimageOracle internal immutable ORACLE;
@@ -73,7 +75,7 @@ contract MIPS2 is ISemver {
     uint256 internal constant THREAD_PROOF_OFFSET = 356;
 
     // The offset of the start of proof calldata (_memProof.offset) in the step() function
-    uint256 internal constant MEM_PROOF_OFFSET = THREAD_PROOF_OFFSET + 166 + 32;
+    uint256 internal constant MEM_PROOF_OFFSET = THREAD_PROOF_OFFSET + 170 + 32;
 
     // The empty thread root - keccak256(bytes32(0) ++ bytes32(0))
     bytes32 internal constant EMPTY_THREAD_ROOT = hex"ad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5";
@@ -82,7 +84,7 @@ contract MIPS2 is ISemver {
     uint256 internal constant STATE_MEM_OFFSET = 0x80;
 
     // ThreadState memory offset allocated during step
-    uint256 internal constant TC_MEM_OFFSET = 0x280;
+    uint256 internal constant TC_MEM_OFFSET = 0x2a0;
 
     /// @param _oracle The address of the preimage oracle contract.
     constructor(IPreimageOracle _oracle) {
@@ -151,8 +153,8 @@ contract MIPS2 is ISemver {
                     // expected thread mem offset check
                     revert(0, 0)
                 }
-                if iszero(eq(mload(0x40), shl(5, 63))) {
-                    // 4 + 16 state slots + 43 thread slots = 63 expected memory check
+                if iszero(eq(mload(0x40), shl(5, 65))) {
+                    // 4 + 17 state slots + 44 thread slots = 65 expected memory check
                     revert(0, 0)
                 }
                 if iszero(eq(_stateData.offset, 132)) {
@@ -188,6 +190,7 @@ contract MIPS2 is ISemver {
                 c, m := putField(c, m, 8) // step
                 c, m := putField(c, m, 8) // stepsSinceLastContextSwitch
                 c, m := putField(c, m, 4) // wakeup
+                c, m := putField(c, m, 4) // wakeupBitset
                 c, m := putField(c, m, 1) // traverseRight
                 c, m := putField(c, m, 32) // leftThreadStack
                 c, m := putField(c, m, 32) // rightThreadStack
@@ -215,10 +218,11 @@ contract MIPS2 is ISemver {
             // Search for the first thread blocked by the wakeup call, if wakeup is set
             // Don't allow regular execution until we resolved if we have woken up any thread.
             if (state.wakeup != sys.FUTEX_EMPTY_ADDR) {
-                if (state.wakeup == thread.futexAddr) {
+                if (state.wakeup == thread.futexAddr && (state.wakeupBitset & thread.futexBitset) != 0) {
                     // completed wake traversal
                     // resume execution on woken up thread
                     state.wakeup = sys.FUTEX_EMPTY_ADDR;
+                    state.wakeupBitset = 0;
                     return outputState();
                 } else {
                     bool traversingRight = state.traverseRight;
@@ -227,6 +231,7 @@ contract MIPS2 is ISemver {
                         // then we've completed wake traversal
                         // resume thread execution
                         state.wakeup = sys.FUTEX_EMPTY_ADDR;
+                        state.wakeupBitset = 0;
                     }
                     return outputState();
                 }
@@ -313,6 +318,53 @@ contract MIPS2 is ISemver {
         }
     }
 
+    /// @notice Writes words, in order, when they lie in at most two memory leaves.
+    ///         The leaf of the first write is proven by the first memory proof, and the other leaf by the second.
+    /// @return ok_ False, without writing, if the writes span more than two leaves.
+    function writeWords(
+        State memory _state,
+        uint32[] memory _addrs,
+        uint32[] memory _vals
+    )
+        internal
+        pure
+        returns (bool ok_)
+    {
+        if (_addrs.length == 0) {
+            return true;
+        }
+        uint32 leaf1 = _addrs[0] & 0xFFffFFe0;
+        uint32 leaf2 = leaf1;
+        for (uint256 i = 0; i < _addrs.length; i++) {
+            uint32 leaf = _addrs[i] & 0xFFffFFe0;
+            if (leaf == leaf1 || leaf == leaf2) {
+                continue;
+            }
+            if (leaf2 != leaf1) {
+                return false;
+            }
+            leaf2 = leaf;
+        }
+
+        if (!MIPSMemory.isValidProof(_state.memRoot, leaf1, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1))) {
+            revert InvalidMemoryProof();
+        }
+        _state.memRoot =
+            MIPSMemory.writeMemLeaf(leaf1, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1), _addrs, _vals);
+        if (leaf2 != leaf1) {
+            // The second proof is taken after the writes to the first leaf
+            if (!MIPSMemory.isValidProof(_state.memRoot, leaf2, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2))) {
+                revert InvalidSecondMemoryProof();
+            }
+            _state.memRoot =
+                MIPSMemory.writeMemLeaf(leaf2, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2), _addrs, _vals);
+        }
+        for (uint256 i = 0; i < _addrs.length; i++) {
+            handleMemoryUpdate(_state, _addrs[i]);
+        }
+        return true;
+    }
+
     function clearLLMemoryReservation(State memory _state) internal pure {
         _state.llReservationStatus = LL_STATUS_NONE;
         _state.llAddress = 0;
@@ -402,7 +454,13 @@ contract MIPS2 is ISemver {
             uint32 v1 = 0;
 
             if (syscall_no == sys.SYS_MMAP) {
-                (v0, v1, state.heap) = sys.handleSysMmap(a0, a1, state.heap);
+                if (a0 & 4095 != 0) {
+                    // Fixed mappings must be page-aligned; the heap is left untouched
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    (v0, v1, state.heap) = sys.handleSysMmap(a0, a1, state.heap);
+                }
             } else if (syscall_no == sys.SYS_BRK) {
                 // brk: Returns a fixed address for the program break at 0x40000000
                 v0 = sys.PROGRAM_BREAK;
@@ -421,6 +479,7 @@ contract MIPS2 is ISemver {
                 newThread.futexAddr = sys.FUTEX_EMPTY_ADDR;
                 newThread.futexVal = 0;
                 newThread.futexTimeoutStep = 0;
+                newThread.futexBitset = 0;
                 newThread.pc = thread.nextPC;
                 newThread.nextPC = thread.nextPC + 4;
                 newThread.lo = thread.lo;
@@ -487,9 +546,28 @@ contract MIPS2 is ISemver {
                 updateCurrentThreadRoot();
                 return outputState();
             } else if (syscall_no == sys.SYS_FUTEX) {
-                // args: a0 = addr, a1 = op, a2 = val, a3 = timeout
+                // args: a0 = addr, a1 = op, a2 = val, a3 = timeout, val3 = bitset
                 uint32 effAddr = a0 & 0xFFffFFfc;
-                if (a1 == sys.FUTEX_WAIT_PRIVATE) {
+                // The non-bitset variants behave as if called with FUTEX_BITSET_MATCH_ANY
+                uint32 bitset = sys.FUTEX_BITSET_MATCH_ANY;
+                uint32 bitsetErrno = 0;
+                if (a1 == sys.FUTEX_WAIT_BITSET_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE) {
+                    (bitset, bitsetErrno) = sys.futexBitset(
+                        state.memRoot, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2), thread.registers[29]
+                    );
+                }
+                if (bitsetErrno != 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = bitsetErrno;
+                } else if (
+                    (
+                        a1 == sys.FUTEX_WAIT_PRIVATE || a1 == sys.FUTEX_WAIT_BITSET_PRIVATE
+                            || a1 == sys.FUTEX_WAKE_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE
+                    ) && !sys.isValidUserPtr(a0, 4)
+                ) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a1 == sys.FUTEX_WAIT_PRIVATE || a1 == sys.FUTEX_WAIT_BITSET_PRIVATE) {
                     uint32 mem =
                         MIPSMemory.readMem(state.memRoot, effAddr, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1));
                     if (mem != a2) {
@@ -499,14 +577,16 @@ contract MIPS2 is ISemver {
                         thread.futexAddr = effAddr;
                         thread.futexVal = a2;
                         thread.futexTimeoutStep = a3 == 0 ? sys.FUTEX_NO_TIMEOUT : state.step + sys.FUTEX_TIMEOUT_STEPS;
+                        thread.futexBitset = bitset;
                         // Leave cpu scalars as-is. This instruction will be completed by `onWaitComplete`
                         updateCurrentThreadRoot();
                         return outputState();
                     }
-                } else if (a1 == sys.FUTEX_WAKE_PRIVATE) {
+                } else if (a1 == sys.FUTEX_WAKE_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE) {
                     // Trigger thread traversal starting from the left stack until we find one waiting on the wakeup
                     // address
                     state.wakeup = effAddr;
+                    state.wakeupBitset = bitset;
                     // Don't indicate to the program that we've woken up a waiting thread, as there are no guarantees.
                     // The woken up thread should indicate this in userspace.
                     v0 = 0;
@@ -532,8 +612,81 @@ contract MIPS2 is ISemver {
             } else if (syscall_no == sys.SYS_OPEN) {
                 v0 = sys.SYS_ERROR_SIGNAL;
                 v1 = sys.EBADF;
+            } else if (syscall_no == sys.SYS_READLINKAT) {
+                // There is no filesystem, so only /proc/self/exe resolves, to a fixed path. The path is read from the
+                // first memory proof, and the result is written to the word holding buf with the second. A path
+                // spanning two memory leaves fails with EINVAL, whatever it holds.
+                uint32 n = a3;
+                if (n > sys.EXE_PATH_LENGTH) {
+                    n = sys.EXE_PATH_LENGTH;
+                }
+                if (n > 4 - (a2 & 3)) {
+                    n = 4 - (a2 & 3);
+                }
+                if (a3 == 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (!sys.isValidUserPtr(a1, sys.PROC_SELF_EXE_LENGTH)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if ((a1 & 31) + sys.PROC_SELF_EXE_LENGTH > 32) {
+                    // A path spanning two memory leaves cannot be read from one memory proof
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (!sys.isProcSelfExe(state.memRoot, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1), a1)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.ENOENT;
+                } else if (!sys.isValidUserPtr(a2, n)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    v0 = n;
+                    v1 = 0;
+                    uint32 effAddr = a2 & 0xFFffFFfc;
+                    uint32 mem =
+                        MIPSMemory.readMem(state.memRoot, effAddr, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2));
+                    state.memRoot = MIPSMemory.writeMem(
+                        effAddr, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2), sys.writeExePath(a2, mem, n)
+                    );
+                    handleMemoryUpdate(state, effAddr);
+                }
+            } else if (syscall_no == sys.SYS_FACCESSAT) {
+                // There is no filesystem, so no path exists
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.ENOENT;
+            } else if (syscall_no == sys.SYS_PRCTL) {
+                // naming options are accepted without effect
+                if (a0 == sys.PR_SET_NAME || a0 == sys.PR_SET_VMA) {
+                    v0 = 0;
+                    v1 = 0;
+                } else {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                }
+            } else if (
+                syscall_no == sys.SYS_SOCKET || syscall_no == sys.SYS_CONNECT || syscall_no == sys.SYS_ACCEPT
+                    || syscall_no == sys.SYS_BIND || syscall_no == sys.SYS_LISTEN || syscall_no == sys.SYS_SENDTO
+                    || syscall_no == sys.SYS_RECVFROM
+            ) {
+                // no network is available
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.EAFNOSUPPORT;
+            } else if (
+                syscall_no == sys.SYS_SHMGET || syscall_no == sys.SYS_SHMAT || syscall_no == sys.SYS_SHMCTL
+                    || syscall_no == sys.SYS_SHMDT
+            ) {
+                // there is a single address space, so shared memory is not modeled
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.ENOSYS;
             } else if (syscall_no == sys.SYS_CLOCKGETTIME) {
-                if (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
+                if (
+                    (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG)
+                        && !sys.isValidUserPtr(a1, 8)
+                ) {
+                    // a1 = timespec, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
                     v0 = 0;
                     v1 = 0;
                     uint32 secs = 0;
@@ -570,11 +723,123 @@ contract MIPS2 is ISemver {
                     v0 = sys.SYS_ERROR_SIGNAL;
                     v1 = sys.EINVAL;
                 }
+            } else if (syscall_no == sys.SYS_CLOCK_NANOSLEEP) {
+                // the sleep elapses immediately, and a relative sleep reports no remaining time in rem
+                if (a0 != sys.CLOCK_GETTIME_REALTIME_FLAG && a0 != sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (a3 == 0 || (a1 & sys.TIMER_ABSTIME) != 0) {
+                    v0 = 0;
+                    v1 = 0;
+                } else if (!sys.isValidUserPtr(a3, 8)) {
+                    // a3 = rem, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                    uint32 effAddr = a3 & 0xFFffFFfc;
+                    if (
+                        !MIPSMemory.isValidProof(
+                            state.memRoot, effAddr, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1)
+                        )
+                    ) {
+                        revert InvalidMemoryProof();
+                    }
+                    state.memRoot =
+                        MIPSMemory.writeMem(effAddr, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1), 0);
+                    handleMemoryUpdate(state, effAddr);
+                    if (
+                        !MIPSMemory.isValidProof(
+                            state.memRoot, effAddr + 4, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2)
+                        )
+                    ) {
+                        revert InvalidSecondMemoryProof();
+                    }
+                    state.memRoot =
+                        MIPSMemory.writeMem(effAddr + 4, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 2), 0);
+                    handleMemoryUpdate(state, effAddr + 4);
+                }
+            } else if (syscall_no == sys.SYS_GETTIMEOFDAY) {
+                // The time of day is derived from the step count, and the obsolete timezone is zeroed.
+                // A step can only prove two memory leaves, so the timeval and timezone fail with EINVAL if they
+                // span more.
+                if (a0 != 0 && !sys.isValidUserPtr(a0, 8)) {
+                    // a0 = timeval, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a1 != 0 && !sys.isValidUserPtr(a1, sys.TIMEZONE_SIZE)) {
+                    // a1 = timezone, two 32-bit ints
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    uint256 count = (a0 != 0 ? 2 : 0) + (a1 != 0 ? sys.TIMEZONE_SIZE / 4 : 0);
+                    uint32[] memory addrs = new uint32[](count);
+                    uint32[] memory vals = new uint32[](count);
+                    uint256 n = 0;
+                    if (a0 != 0) {
+                        uint32 effAddr = a0 & 0xFFffFFfc;
+                        addrs[0] = effAddr;
+                        vals[0] = uint32(state.step / sys.HZ);
+                        addrs[1] = effAddr + 4;
+                        vals[1] = uint32((state.step % sys.HZ) / (sys.HZ / 1_000_000));
+                        n = 2;
+                    }
+                    if (a1 != 0) {
+                        uint32 effAddr = a1 & 0xFFffFFfc;
+                        for (uint32 off = 0; off < sys.TIMEZONE_SIZE; off += 4) {
+                            addrs[n++] = effAddr + off;
+                        }
+                    }
+                    if (writeWords(state, addrs, vals)) {
+                        v0 = 0;
+                        v1 = 0;
+                    } else {
+                        v0 = sys.SYS_ERROR_SIGNAL;
+                        v1 = sys.EINVAL;
+                    }
+                }
+            } else if (syscall_no == sys.SYS_MUNMAP) {
+                // A block whose size is a power of two, and that is aligned to its size, is zeroed with one memory
+                // proof, and the heap shrinks if the block is at its top. Other ranges cannot be zeroed in a single
+                // step, and fail with EINVAL, as do ranges that are empty or wrap around the address space.
+                // The unmapped range extends to the end of the last page.
+                uint32 size = (a1 + 4095) & ~uint32(4095);
+                if (
+                    (a0 & 4095) != 0 || a1 == 0 || a0 > type(uint32).max - (a1 - 1) || size == 0
+                        || (size & (size - 1)) != 0 || (a0 & (size - 1)) != 0
+                ) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                    if (
+                        !MIPSMemory.isValidProof(state.memRoot, a0, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1))
+                    ) {
+                        revert InvalidMemoryProof();
+                    }
+                    state.memRoot = MIPSMemory.zeroMem(a0, MIPSMemory.memoryProofOffset(MEM_PROOF_OFFSET, 1), size);
+                    if ((state.llAddress & 0xFFFFFFFC) - a0 < size) {
+                        // Reserved address was zeroed, clear the reservation
+                        clearLLMemoryReservation(state);
+                    }
+                    if (a0 < state.heap && state.heap - a0 == size) {
+                        state.heap = a0;
+                    }
+                }
+            } else if (syscall_no == sys.SYS_RSEQ) {
+                // the registration is ignored, only the flags are validated
+                if ((a2 & ~sys.RSEQ_FLAG_UNREGISTER) != 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                }
             } else if (syscall_no == sys.SYS_GETPID) {
                 v0 = 0;
                 v1 = 0;
-            } else if (syscall_no == sys.SYS_MUNMAP) {
-                // ignored
             } else if (syscall_no == sys.SYS_GETAFFINITY) {
                 // ignored
             } else if (syscall_no == sys.SYS_MADVISE) {
@@ -599,18 +864,22 @@ contract MIPS2 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_READLINK) {
                 // ignored
-            } else if (syscall_no == sys.SYS_READLINKAT) {
-                // ignored
             } else if (syscall_no == sys.SYS_IOCTL) {
                 // ignored
-            } else if (syscall_no == sys.SYS_EPOLLCREATE1) {
-                // ignored
+            } else if (syscall_no == sys.SYS_EPOLLCREATE1 || syscall_no == sys.SYS_EPOLLCREATE) {
+                // There is no I/O to wait for, so the netpoller is kept inert with a fake epoll instance
+                v0 = sys.FD_EPOLL;
+                v1 = 0;
             } else if (syscall_no == sys.SYS_PIPE2) {
                 // ignored
             } else if (syscall_no == sys.SYS_EPOLLCTL) {
-                // ignored
-            } else if (syscall_no == sys.SYS_EPOLLPWAIT) {
-                // ignored
+                // Registrations are accepted, but no fd ever becomes ready
+                v0 = 0;
+                v1 = 0;
+            } else if (syscall_no == sys.SYS_EPOLLPWAIT || syscall_no == sys.SYS_EPOLLWAIT) {
+                // No events are pending, so the wait times out immediately
+                v0 = 0;
+                v1 = 0;
             } else if (syscall_no == sys.SYS_GETRANDOM) {
                 // ignored
             } else if (syscall_no == sys.SYS_UNAME) {
@@ -619,10 +888,19 @@ contract MIPS2 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_GETGID) {
                 // ignored
+            } else if (syscall_no == sys.SYS_GETEUID) {
+                // ignored
+            } else if (syscall_no == sys.SYS_GETEGID) {
+                // ignored
             } else if (syscall_no == sys.SYS_MINCORE) {
                 // ignored
             } else if (syscall_no == sys.SYS_TGKILL) {
-                // ignored
+                // An aborting guest is treated as a crash. Other signals are ignored.
+                if (a2 == sys.SIGABRT) {
+                    state.exited = true;
+                    state.exitCode = VMStatuses.PANIC.raw();
+                    return outputState();
+                }
             } else if (syscall_no == sys.SYS_SETITIMER) {
                 // ignored
             } else if (syscall_no == sys.SYS_TIMERCREATE) {
@@ -635,6 +913,10 @@ contract MIPS2 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_LSEEK) {
                 // ignored
+            } else if (syscall_no == sys.SYS_POLL || syscall_no == sys.SYS_PPOLL) {
+                // no fd can become ready: time out immediately with no events
+            } else if (syscall_no == sys.SYS_SETROBUSTLIST) {
+                // ignored
             } else {
                 if (syscall_no == sys.SYS_FSTAT64 || syscall_no == sys.SYS_STAT64 || syscall_no == sys.SYS_LLSEEK) {
                     // noop
@@ -702,6 +984,7 @@ contract MIPS2 is ISemver {
             from, to := copyMem(from, to, 8) // step
             from, to := copyMem(from, to, 8) // stepsSinceLastContextSwitch
             from, to := copyMem(from, to, 4) // wakeup
+            from, to := copyMem(from, to, 4) // wakeupBitset
             from, to := copyMem(from, to, 1) // traverseRight
             from, to := copyMem(from, to, 32) // leftThreadStack
             from, to := copyMem(from, to, 32) // rightThreadStack
@@ -759,6 +1042,7 @@ contract MIPS2 is ISemver {
         _thread.futexAddr = sys.FUTEX_EMPTY_ADDR;
         _thread.futexVal = 0;
         _thread.futexTimeoutStep = 0;
+        _thread.futexBitset = 0;
 
         // Complete the FUTEX_WAIT syscall
         uint32 v0 = _isTimedOut ? sys.SYS_ERROR_SIGNAL : 0;
@@ -860,6 +1144,7 @@ contract MIPS2 is ISemver {
             from, to := copyMem(from, to, 4) // futexAddr
             from, to := copyMem(from, to, 4) // futexVal
             from, to := copyMem(from, to, 8) // futexTimeoutStep
+            from, to := copyMem(from, to, 4) // futexBitset
             from, to := copyMem(from, to, 4) // pc
             from, to := copyMem(from, to, 4) // nextPC
             from, to := copyMem(from, to, 4) // lo
@@ -901,7 +1186,7 @@ contract MIPS2 is ISemver {
             s := calldatasize()
         }
         // verify we have enough calldata
-        require(s >= (THREAD_PROOF_OFFSET + 166), "insufficient calldata for thread witness");
+        require(s >= (THREAD_PROOF_OFFSET + 170), "insufficient calldata for thread witness");
 
         unchecked {
             assembly {
@@ -921,6 +1206,7 @@ contract MIPS2 is ISemver {
                 c, m := putField(c, m, 4) // futexAddr
                 c, m := putField(c, m, 4) // futexVal
                 c, m := putField(c, m, 8) // futexTimeoutStep
+                c, m := putField(c, m, 4) // futexBitset
                 c, m := putField(c, m, 4) // pc
                 c, m := putField(c, m, 4) // nextPC
                 c, m := putField(c, m, 4) // lo
@@ -937,9 +1223,9 @@ contract MIPS2 is ISemver {
         uint256 s = 0;
         assembly {
             s := calldatasize()
-            innerThreadRoot_ := calldataload(add(THREAD_PROOF_OFFSET, 166))
+            innerThreadRoot_ := calldataload(add(THREAD_PROOF_OFFSET, 170))
         }
         // verify we have enough calldata
-        require(s >= (THREAD_PROOF_OFFSET + 198), "insufficient calldata for thread witness"); // 166 + 32
+        require(s >= (THREAD_PROOF_OFFSET + 202), "insufficient calldata for thread witness"); // 170 + 32
     }
 }
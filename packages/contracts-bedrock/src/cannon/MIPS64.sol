@@ -21,7 +21,7 @@ import { IPreimageOracle } from "interfaces/cannon/IPreimageOracle.sol";
 ///         It differs from MIPS.sol in that it supports MIPS64 instructions and multi-tasking.
 contract MIPS64 is ISemver {
     /// @notice The thread context.
-    ///         Total state size: 8 + 1 + 1 + 8 + 8 + 8 + 8 + 8 + 8 + 8 + 32 * 8 = 322 bytes
+    ///         Total state size: 8 + 1 + 1 + 8 + 8 + 8 + 4 + 8 + 8 + 8 + 8 + 32 * 8 = 326 bytes
     struct ThreadState {
         // metadata
         uint64 threadID;
@@ -31,6 +31,7 @@ contract MIPS64 is ISemver {
         uint64 futexAddr;
         uint64 futexVal;
         uint64 futexTimeoutStep;
+        uint32 futexBitset;
         uint64 pc;
         uint64 nextPC;
         uint64 lo;
@@ -38,14 +39,14 @@ contract MIPS64 is ISemver {
         uint64[32] registers;
     }
 
-    uint32 internal constant PACKED_THREAD_STATE_SIZE = 322;
+    uint32 internal constant PACKED_THREAD_STATE_SIZE = 326;
 
     uint8 internal constant LL_STATUS_NONE = 0;
     uint8 internal constant LL_STATUS_ACTIVE_32_BIT = 0x1;
     uint8 internal constant LL_STATUS_ACTIVE_64_BIT = 0x2;
 
     /// @notice Stores the VM state.
-    ///         Total state size: 32 + 32 + 8 + 8 + 1 + 8 + 8 + 1 + 1 + 8 + 8 + 8 + 1 + 32 + 32 + 8 = 196 bytes
+    ///         Total state size: 32 + 32 + 8 + 8 + 1 + 8 + 8 + 1 + 1 + 8 + 8 + 8 + 4 + 1 + 32 + 32 + 8 = 200 bytes
     ///         If nextPC != pc + 4, then the VM is executing a branch/jump delay slot.
     struct State {
         bytes32 memRoot;
@@ -60,6 +61,7 @@ contract MIPS64 is ISemver {
         uint64 step;
         uint64 stepsSinceLastContextSwitch;
         uint64 wakeup;
+        uint32 wakeupBitset;
         bool traverseRight;
         bytes32 leftThreadStack;
         bytes32 rightThreadStack;
@@ -67,8 +69,8 @@ contract MIPS64 is ISemver {
     }
 
     /// @notice The semantic version of the MIPS64 contract.
-    /// @custom:semver 1.0.0-beta.7
-    string public constant version = "1.0.0-beta.7";
+    /// @custom:semver 1.0.0-beta.8
+    string public constant version = "1.0.0-beta.8";
 
     /// @notice The preimage oracle contract.
     IPreimageOracle internal immutable ORACLE;
@@ -86,7 +88,7 @@ contract MIPS64 is ISemver {
     uint256 internal constant STATE_MEM_OFFSET = 0x80;
 
     // ThreadState memory offset allocated during step
-    uint256 internal constant TC_MEM_OFFSET = 0x280;
+    uint256 internal constant TC_MEM_OFFSET = 0x2a0;
 
     /// @param _oracle The address of the preimage oracle contract.
     constructor(IPreimageOracle _oracle) {
@@ -155,8 +157,8 @@ contract MIPS64 is ISemver {
                     // expected thread mem offset check
                     revert(0, 0)
                 }
-                if iszero(eq(mload(0x40), shl(5, 63))) {
-                    // 4 + 16 state slots + 43 thread slots = 63 expected memory check
+                if iszero(eq(mload(0x40), shl(5, 65))) {
+                    // 4 + 17 state slots + 44 thread slots = 65 expected memory check
                     revert(0, 0)
                 }
                 if iszero(eq(_stateData.offset, 132)) {
@@ -165,8 +167,8 @@ contract MIPS64 is ISemver {
                 }
                 if iszero(eq(_proof.offset, THREAD_PROOF_OFFSET)) {
                     // _stateData.offset = 132
-                    // stateData.length = 196
-                    // 32-byte align padding = 28
+                    // stateData.length = 200
+                    // 32-byte align padding = 24
                     // _proof size prefix = 32
                     // expected thread proof offset equals the sum of the above is 388
                     revert(0, 0)
@@ -196,6 +198,7 @@ contract MIPS64 is ISemver {
                 c, m := putField(c, m, 8) // step
                 c, m := putField(c, m, 8) // stepsSinceLastContextSwitch
                 c, m := putField(c, m, 8) // wakeup
+                c, m := putField(c, m, 4) // wakeupBitset
                 c, m := putField(c, m, 1) // traverseRight
                 c, m := putField(c, m, 32) // leftThreadStack
                 c, m := putField(c, m, 32) // rightThreadStack
@@ -223,10 +226,11 @@ contract MIPS64 is ISemver {
             // Search for the first thread blocked by the wakeup call, if wakeup is set
             // Don't allow regular execution until we resolved if we have woken up any thread.
             if (state.wakeup != sys.FUTEX_EMPTY_ADDR) {
-                if (state.wakeup == thread.futexAddr) {
+                if (state.wakeup == thread.futexAddr && (state.wakeupBitset & thread.futexBitset) != 0) {
                     // completed wake traversal
                     // resume execution on woken up thread
                     state.wakeup = sys.FUTEX_EMPTY_ADDR;
+                    state.wakeupBitset = 0;
                     return outputState();
                 } else {
                     bool traversingRight = state.traverseRight;
@@ -235,6 +239,7 @@ contract MIPS64 is ISemver {
                         // then we've completed wake traversal
                         // resume thread execution
                         state.wakeup = sys.FUTEX_EMPTY_ADDR;
+                        state.wakeupBitset = 0;
                     }
                     return outputState();
                 }
@@ -326,6 +331,57 @@ contract MIPS64 is ISemver {
         }
     }
 
+    /// @notice Writes words, in order, when they lie in at most two memory leaves.
+    ///         The leaf of the first write is proven by the first memory proof, and the other leaf by the second.
+    /// @return ok_ False, without writing, if the writes span more than two leaves.
+    function writeWords(
+        State memory _state,
+        uint64[] memory _addrs,
+        uint64[] memory _vals
+    )
+        internal
+        pure
+        returns (bool ok_)
+    {
+        if (_addrs.length == 0) {
+            return true;
+        }
+        uint64 leaf1 = _addrs[0] & ~uint64(31);
+        uint64 leaf2 = leaf1;
+        for (uint256 i = 0; i < _addrs.length; i++) {
+            uint64 leaf = _addrs[i] & ~uint64(31);
+            if (leaf == leaf1 || leaf == leaf2) {
+                continue;
+            }
+            if (leaf2 != leaf1) {
+                return false;
+            }
+            leaf2 = leaf;
+        }
+
+        if (!MIPS64Memory.isValidProof(_state.memRoot, leaf1, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1))) {
+            revert InvalidMemoryProof();
+        }
+        _state.memRoot =
+            MIPS64Memory.writeMemLeaf(leaf1, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1), _addrs, _vals);
+        if (leaf2 != leaf1) {
+            // The second proof is taken after the writes to the first leaf
+            if (
+                !MIPS64Memory.isValidProof(
+                    _state.memRoot, leaf2, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2)
+                )
+            ) {
+                revert InvalidSecondMemoryProof();
+            }
+            _state.memRoot =
+                MIPS64Memory.writeMemLeaf(leaf2, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2), _addrs, _vals);
+        }
+        for (uint256 i = 0; i < _addrs.length; i++) {
+            handleMemoryUpdate(_state, _addrs[i]);
+        }
+        return true;
+    }
+
     function clearLLMemoryReservation(State memory _state) internal pure {
         _state.llReservationStatus = LL_STATUS_NONE;
         _state.llAddress = 0;
@@ -444,7 +500,13 @@ contract MIPS64 is ISemver {
             uint64 v1 = 0;
 
             if (syscall_no == sys.SYS_MMAP) {
-                (v0, v1, state.heap) = sys.handleSysMmap(a0, a1, state.heap);
+                if (a0 & sys.PAGE_ADDR_MASK != 0) {
+                    // Fixed mappings must be page-aligned; the heap is left untouched
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    (v0, v1, state.heap) = sys.handleSysMmap(a0, a1, state.heap);
+                }
             } else if (syscall_no == sys.SYS_BRK) {
                 // brk: Returns a fixed address for the program break at 0x40000000
                 v0 = sys.PROGRAM_BREAK;
@@ -463,6 +525,7 @@ contract MIPS64 is ISemver {
                 newThread.futexAddr = sys.FUTEX_EMPTY_ADDR;
                 newThread.futexVal = 0;
                 newThread.futexTimeoutStep = 0;
+                newThread.futexBitset = 0;
                 newThread.pc = thread.nextPC;
                 newThread.nextPC = thread.nextPC + 4;
                 newThread.lo = thread.lo;
@@ -529,9 +592,26 @@ contract MIPS64 is ISemver {
                 updateCurrentThreadRoot();
                 return outputState();
             } else if (syscall_no == sys.SYS_FUTEX) {
-                // args: a0 = addr, a1 = op, a2 = val, a3 = timeout
+                // args: a0 = addr, a1 = op, a2 = val, a3 = timeout, val3 = bitset
                 uint64 effAddr = a0 & arch.ADDRESS_MASK;
-                if (a1 == sys.FUTEX_WAIT_PRIVATE) {
+                // The non-bitset variants behave as if called with FUTEX_BITSET_MATCH_ANY
+                uint32 bitset = uint32(sys.FUTEX_BITSET_MATCH_ANY);
+                uint64 bitsetErrno = 0;
+                if (a1 == sys.FUTEX_WAIT_BITSET_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE) {
+                    (bitset, bitsetErrno) = sys.futexBitset(thread.registers[9]);
+                }
+                if (bitsetErrno != 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = bitsetErrno;
+                } else if (
+                    (
+                        a1 == sys.FUTEX_WAIT_PRIVATE || a1 == sys.FUTEX_WAIT_BITSET_PRIVATE
+                            || a1 == sys.FUTEX_WAKE_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE
+                    ) && !sys.isValidUserPtr(a0, 4)
+                ) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a1 == sys.FUTEX_WAIT_PRIVATE || a1 == sys.FUTEX_WAIT_BITSET_PRIVATE) {
                     uint64 mem = MIPS64Memory.readMem(
                         state.memRoot, effAddr, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1)
                     );
@@ -542,14 +622,16 @@ contract MIPS64 is ISemver {
                         thread.futexAddr = effAddr;
                         thread.futexVal = a2;
                         thread.futexTimeoutStep = a3 == 0 ? sys.FUTEX_NO_TIMEOUT : state.step + sys.FUTEX_TIMEOUT_STEPS;
+                        thread.futexBitset = bitset;
                         // Leave cpu scalars as-is. This instruction will be completed by `onWaitComplete`
                         updateCurrentThreadRoot();
                         return outputState();
                     }
-                } else if (a1 == sys.FUTEX_WAKE_PRIVATE) {
+                } else if (a1 == sys.FUTEX_WAKE_PRIVATE || a1 == sys.FUTEX_WAKE_BITSET_PRIVATE) {
                     // Trigger thread traversal starting from the left stack until we find one waiting on the wakeup
                     // address
                     state.wakeup = effAddr;
+                    state.wakeupBitset = bitset;
                     // Don't indicate to the program that we've woken up a waiting thread, as there are no guarantees.
                     // The woken up thread should indicate this in userspace.
                     v0 = 0;
@@ -575,8 +657,82 @@ contract MIPS64 is ISemver {
             } else if (syscall_no == sys.SYS_OPEN) {
                 v0 = sys.SYS_ERROR_SIGNAL;
                 v1 = sys.EBADF;
+            } else if (syscall_no == sys.SYS_READLINKAT) {
+                // There is no filesystem, so only /proc/self/exe resolves, to a fixed path. The path is read from the
+                // first memory proof, and the result is written to the word holding buf with the second. A path
+                // spanning two memory leaves fails with EINVAL, whatever it holds.
+                uint64 n = a3;
+                if (n > sys.EXE_PATH_LENGTH) {
+                    n = sys.EXE_PATH_LENGTH;
+                }
+                if (n > 8 - (a2 & 7)) {
+                    n = 8 - (a2 & 7);
+                }
+                if (a3 == 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (!sys.isValidUserPtr(a1, sys.PROC_SELF_EXE_LENGTH)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if ((a1 & 31) + sys.PROC_SELF_EXE_LENGTH > 32) {
+                    // A path spanning two memory leaves cannot be read from one memory proof
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (!sys.isProcSelfExe(state.memRoot, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1), a1)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.ENOENT;
+                } else if (!sys.isValidUserPtr(a2, n)) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    v0 = n;
+                    v1 = 0;
+                    uint64 effAddr = a2 & arch.ADDRESS_MASK;
+                    uint64 mem = MIPS64Memory.readMem(
+                        state.memRoot, effAddr, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2)
+                    );
+                    state.memRoot = MIPS64Memory.writeMem(
+                        effAddr, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2), sys.writeExePath(a2, mem, n)
+                    );
+                    handleMemoryUpdate(state, effAddr);
+                }
+            } else if (syscall_no == sys.SYS_FACCESSAT) {
+                // There is no filesystem, so no path exists
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.ENOENT;
+            } else if (syscall_no == sys.SYS_PRCTL) {
+                // naming options are accepted without effect
+                if (a0 == sys.PR_SET_NAME || a0 == sys.PR_SET_VMA) {
+                    v0 = 0;
+                    v1 = 0;
+                } else {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                }
+            } else if (
+                syscall_no == sys.SYS_SOCKET || syscall_no == sys.SYS_CONNECT || syscall_no == sys.SYS_ACCEPT
+                    || syscall_no == sys.SYS_BIND || syscall_no == sys.SYS_LISTEN || syscall_no == sys.SYS_SENDTO
+                    || syscall_no == sys.SYS_RECVFROM
+            ) {
+                // no network is available
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.EAFNOSUPPORT;
+            } else if (
+                syscall_no == sys.SYS_SHMGET || syscall_no == sys.SYS_SHMAT || syscall_no == sys.SYS_SHMCTL
+                    || syscall_no == sys.SYS_SHMDT
+            ) {
+                // there is a single address space, so shared memory is not modeled
+                v0 = sys.SYS_ERROR_SIGNAL;
+                v1 = sys.ENOSYS;
             } else if (syscall_no == sys.SYS_CLOCKGETTIME) {
-                if (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
+                if (
+                    (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG)
+                        && !sys.isValidUserPtr(a1, 16)
+                ) {
+                    // a1 = timespec, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a0 == sys.CLOCK_GETTIME_REALTIME_FLAG || a0 == sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
                     v0 = 0;
                     v1 = 0;
                     uint64 secs = 0;
@@ -613,11 +769,126 @@ contract MIPS64 is ISemver {
                     v0 = sys.SYS_ERROR_SIGNAL;
                     v1 = sys.EINVAL;
                 }
+            } else if (syscall_no == sys.SYS_CLOCK_NANOSLEEP) {
+                // the sleep elapses immediately, and a relative sleep reports no remaining time in rem
+                if (a0 != sys.CLOCK_GETTIME_REALTIME_FLAG && a0 != sys.CLOCK_GETTIME_MONOTONIC_FLAG) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else if (a3 == 0 || (a1 & sys.TIMER_ABSTIME) != 0) {
+                    v0 = 0;
+                    v1 = 0;
+                } else if (!sys.isValidUserPtr(a3, 16)) {
+                    // a3 = rem, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                    uint64 effAddr = a3 & arch.ADDRESS_MASK;
+                    if (
+                        !MIPS64Memory.isValidProof(
+                            state.memRoot, effAddr, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1)
+                        )
+                    ) {
+                        revert InvalidMemoryProof();
+                    }
+                    state.memRoot =
+                        MIPS64Memory.writeMem(effAddr, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1), 0);
+                    handleMemoryUpdate(state, effAddr);
+                    if (
+                        !MIPS64Memory.isValidProof(
+                            state.memRoot, effAddr + 8, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2)
+                        )
+                    ) {
+                        revert InvalidSecondMemoryProof();
+                    }
+                    state.memRoot =
+                        MIPS64Memory.writeMem(effAddr + 8, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 2), 0);
+                    handleMemoryUpdate(state, effAddr + 8);
+                }
+            } else if (syscall_no == sys.SYS_GETTIMEOFDAY) {
+                // The time of day is derived from the step count, and the obsolete timezone is zeroed.
+                // A step can only prove two memory leaves, so the timeval and timezone fail with EINVAL if they
+                // span more.
+                if (a0 != 0 && !sys.isValidUserPtr(a0, 16)) {
+                    // a0 = timeval, two words
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else if (a1 != 0 && !sys.isValidUserPtr(a1, sys.TIMEZONE_SIZE)) {
+                    // a1 = timezone, two 32-bit ints
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EFAULT;
+                } else {
+                    uint256 count = (a0 != 0 ? 2 : 0) + (a1 != 0 ? sys.TIMEZONE_SIZE / 8 : 0);
+                    uint64[] memory addrs = new uint64[](count);
+                    uint64[] memory vals = new uint64[](count);
+                    uint256 n = 0;
+                    if (a0 != 0) {
+                        uint64 effAddr = a0 & arch.ADDRESS_MASK;
+                        addrs[0] = effAddr;
+                        vals[0] = uint64(state.step / sys.HZ);
+                        addrs[1] = effAddr + 8;
+                        vals[1] = uint64((state.step % sys.HZ) / (sys.HZ / 1_000_000));
+                        n = 2;
+                    }
+                    if (a1 != 0) {
+                        uint64 effAddr = a1 & arch.ADDRESS_MASK;
+                        for (uint64 off = 0; off < sys.TIMEZONE_SIZE; off += 8) {
+                            addrs[n++] = effAddr + off;
+                        }
+                    }
+                    if (writeWords(state, addrs, vals)) {
+                        v0 = 0;
+                        v1 = 0;
+                    } else {
+                        v0 = sys.SYS_ERROR_SIGNAL;
+                        v1 = sys.EINVAL;
+                    }
+                }
+            } else if (syscall_no == sys.SYS_MUNMAP) {
+                // A block whose size is a power of two, and that is aligned to its size, is zeroed with one memory
+                // proof, and the heap shrinks if the block is at its top. Other ranges cannot be zeroed in a single
+                // step, and fail with EINVAL, as do ranges that are empty or wrap around the address space.
+                // The unmapped range extends to the end of the last page.
+                uint64 size = (a1 + sys.PAGE_ADDR_MASK) & ~uint64(sys.PAGE_ADDR_MASK);
+                if (
+                    (a0 & sys.PAGE_ADDR_MASK) != 0 || a1 == 0 || a0 > type(uint64).max - (a1 - 1) || size == 0
+                        || (size & (size - 1)) != 0 || (a0 & (size - 1)) != 0
+                ) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                    if (
+                        !MIPS64Memory.isValidProof(
+                            state.memRoot, a0, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1)
+                        )
+                    ) {
+                        revert InvalidMemoryProof();
+                    }
+                    state.memRoot =
+                        MIPS64Memory.zeroMem(a0, MIPS64Memory.memoryProofOffset(MEM_PROOF_OFFSET, 1), size);
+                    if ((state.llAddress & arch.ADDRESS_MASK) - a0 < size) {
+                        // Reserved address was zeroed, clear the reservation
+                        clearLLMemoryReservation(state);
+                    }
+                    if (a0 < state.heap && state.heap - a0 == size) {
+                        state.heap = a0;
+                    }
+                }
+            } else if (syscall_no == sys.SYS_RSEQ) {
+                // the registration is ignored, only the flags are validated
+                if ((a2 & ~sys.RSEQ_FLAG_UNREGISTER) != 0) {
+                    v0 = sys.SYS_ERROR_SIGNAL;
+                    v1 = sys.EINVAL;
+                } else {
+                    v0 = 0;
+                    v1 = 0;
+                }
             } else if (syscall_no == sys.SYS_GETPID) {
                 v0 = 0;
                 v1 = 0;
-            } else if (syscall_no == sys.SYS_MUNMAP) {
-                // ignored
             } else if (syscall_no == sys.SYS_GETAFFINITY) {
                 // ignored
             } else if (syscall_no == sys.SYS_MADVISE) {
@@ -642,18 +913,22 @@ contract MIPS64 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_READLINK) {
                 // ignored
-            } else if (syscall_no == sys.SYS_READLINKAT) {
-                // ignored
             } else if (syscall_no == sys.SYS_IOCTL) {
                 // ignored
-            } else if (syscall_no == sys.SYS_EPOLLCREATE1) {
-                // ignored
+            } else if (syscall_no == sys.SYS_EPOLLCREATE1 || syscall_no == sys.SYS_EPOLLCREATE) {
+                // There is no I/O to wait for, so the netpoller is kept inert with a fake epoll instance
+                v0 = sys.FD_EPOLL;
+                v1 = 0;
             } else if (syscall_no == sys.SYS_PIPE2) {
                 // ignored
             } else if (syscall_no == sys.SYS_EPOLLCTL) {
-                // ignored
-            } else if (syscall_no == sys.SYS_EPOLLPWAIT) {
-                // ignored
+                // Registrations are accepted, but no fd ever becomes ready
+                v0 = 0;
+                v1 = 0;
+            } else if (syscall_no == sys.SYS_EPOLLPWAIT || syscall_no == sys.SYS_EPOLLWAIT) {
+                // No events are pending, so the wait times out immediately
+                v0 = 0;
+                v1 = 0;
             } else if (syscall_no == sys.SYS_GETRANDOM) {
                 // ignored
             } else if (syscall_no == sys.SYS_UNAME) {
@@ -662,10 +937,19 @@ contract MIPS64 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_GETGID) {
                 // ignored
+            } else if (syscall_no == sys.SYS_GETEUID) {
+                // ignored
+            } else if (syscall_no == sys.SYS_GETEGID) {
+                // ignored
             } else if (syscall_no == sys.SYS_MINCORE) {
                 // ignored
             } else if (syscall_no == sys.SYS_TGKILL) {
-                // ignored
+                // An aborting guest is treated as a crash. Other signals are ignored.
+                if (a2 == sys.SIGABRT) {
+                    state.exited = true;
+                    state.exitCode = VMStatuses.PANIC.raw();
+                    return outputState();
+                }
             } else if (syscall_no == sys.SYS_SETITIMER) {
                 // ignored
             } else if (syscall_no == sys.SYS_TIMERCREATE) {
@@ -678,6 +962,10 @@ contract MIPS64 is ISemver {
                 // ignored
             } else if (syscall_no == sys.SYS_LSEEK) {
                 // ignored
+            } else if (syscall_no == sys.SYS_POLL || syscall_no == sys.SYS_PPOLL) {
+                // no fd can become ready: time out immediately with no events
+            } else if (syscall_no == sys.SYS_SETROBUSTLIST) {
+                // ignored
             } else {
                 revert("MIPS64: unimplemented syscall");
             }
@@ -741,6 +1029,7 @@ contract MIPS64 is ISemver {
             from, to := copyMem(from, to, 8) // step
             from, to := copyMem(from, to, 8) // stepsSinceLastContextSwitch
             from, to := copyMem(from, to, 8) // wakeup
+            from, to := copyMem(from, to, 4) // wakeupBitset
             from, to := copyMem(from, to, 1) // traverseRight
             from, to := copyMem(from, to, 32) // leftThreadStack
             from, to := copyMem(from, to, 32) // rightThreadStack
@@ -798,6 +1087,7 @@ contract MIPS64 is ISemver {
         _thread.futexAddr = sys.FUTEX_EMPTY_ADDR;
         _thread.futexVal = 0;
         _thread.futexTimeoutStep = 0;
+        _thread.futexBitset = 0;
 
         // Complete the FUTEX_WAIT syscall
         uint64 v0 = _isTimedOut ? sys.SYS_ERROR_SIGNAL : 0;
@@ -899,6 +1189,7 @@ contract MIPS64 is ISemver {
             from, to := copyMem(from, to, 8) // futexAddr
             from, to := copyMem(from, to, 8) // futexVal
             from, to := copyMem(from, to, 8) // futexTimeoutStep
+            from, to := copyMem(from, to, 4) // futexBitset
             from, to := copyMem(from, to, 8) // pc
             from, to := copyMem(from, to, 8) // nextPC
             from, to := copyMem(from, to, 8) // lo
@@ -962,6 +1253,7 @@ contract MIPS64 is ISemver {
                 c, m := putField(c, m, 8) // futexAddr
                 c, m := putField(c, m, 8) // futexVal
                 c, m := putField(c, m, 8) // futexTimeoutStep
+                c, m := putField(c, m, 4) // futexBitset
                 c, m := putField(c, m, 8) // pc
                 c, m := putField(c, m, 8) // nextPC
                 c, m := putField(c, m, 8) // lo
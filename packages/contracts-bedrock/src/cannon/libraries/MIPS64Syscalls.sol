@@ -65,9 +65,12 @@ library MIPS64Syscalls {
     uint32 internal constant SYS_OPEN = 5002;
     uint32 internal constant SYS_NANOSLEEP = 5034;
     uint32 internal constant SYS_CLOCKGETTIME = 5222;
+    uint32 internal constant SYS_CLOCK_NANOSLEEP = 5224;
+    uint32 internal constant SYS_GETTIMEOFDAY = 5094;
+    uint32 internal constant SYS_MUNMAP = 5011;
+    uint32 internal constant SYS_RSEQ = 5327;
     uint32 internal constant SYS_GETPID = 5038;
     // no-op syscalls
-    uint32 internal constant SYS_MUNMAP = 5011;
     uint32 internal constant SYS_GETAFFINITY = 5196;
     uint32 internal constant SYS_MADVISE = 5027;
     uint32 internal constant SYS_RTSIGPROCMASK = 5014;
@@ -87,21 +90,43 @@ library MIPS64Syscalls {
     uint32 internal constant SYS_PIPE2 = 5287;
     uint32 internal constant SYS_EPOLLCTL = 5208;
     uint32 internal constant SYS_EPOLLPWAIT = 5272;
+    uint32 internal constant SYS_EPOLLCREATE = 5207;
+    uint32 internal constant SYS_EPOLLWAIT = 5209;
     uint32 internal constant SYS_GETRANDOM = 5313;
     uint32 internal constant SYS_UNAME = 5061;
     //uint32 internal constant SYS_STAT64 = 0xFFFFFFFF;  // UndefinedSysNr - not supported by MIPS64
     uint32 internal constant SYS_GETUID = 5100;
     uint32 internal constant SYS_GETGID = 5102;
+    uint32 internal constant SYS_GETEUID = 5105;
+    uint32 internal constant SYS_GETEGID = 5106;
     //uint32 internal constant SYS_LLSEEK = 0xFFFFFFFF;  // UndefinedSysNr - not supported by MIPS64
     uint32 internal constant SYS_MINCORE = 5026;
     uint32 internal constant SYS_TGKILL = 5225;
     uint32 internal constant SYS_GETRLIMIT = 5095;
     uint32 internal constant SYS_LSEEK = 5008;
+    uint32 internal constant SYS_SETROBUSTLIST = 5268;
+    uint32 internal constant SYS_PRCTL = 5153;
+    uint32 internal constant SYS_FACCESSAT = 5259;
+    uint32 internal constant SYS_POLL = 5007;
+    uint32 internal constant SYS_PPOLL = 5261;
     // profiling-related syscalls - ignored
     uint32 internal constant SYS_SETITIMER = 5036;
     uint32 internal constant SYS_TIMERCREATE = 5216;
     uint32 internal constant SYS_TIMERSETTIME = 5217;
     uint32 internal constant SYS_TIMERDELETE = 5220;
+    // network syscalls - no network is available, so these fail with EAFNOSUPPORT
+    uint32 internal constant SYS_SOCKET = 5040;
+    uint32 internal constant SYS_CONNECT = 5041;
+    uint32 internal constant SYS_ACCEPT = 5042;
+    uint32 internal constant SYS_BIND = 5048;
+    uint32 internal constant SYS_LISTEN = 5049;
+    uint32 internal constant SYS_SENDTO = 5043;
+    uint32 internal constant SYS_RECVFROM = 5044;
+    // SysV shared memory syscalls - shared memory is not modeled, so these fail with ENOSYS
+    uint32 internal constant SYS_SHMGET = 5028;
+    uint32 internal constant SYS_SHMAT = 5029;
+    uint32 internal constant SYS_SHMCTL = 5030;
+    uint32 internal constant SYS_SHMDT = 5065;
 
     uint32 internal constant FD_STDIN = 0;
     uint32 internal constant FD_STDOUT = 1;
@@ -110,15 +135,28 @@ library MIPS64Syscalls {
     uint32 internal constant FD_HINT_WRITE = 4;
     uint32 internal constant FD_PREIMAGE_READ = 5;
     uint32 internal constant FD_PREIMAGE_WRITE = 6;
+    uint32 internal constant FD_EPOLL = 7;
 
     uint64 internal constant SYS_ERROR_SIGNAL = U64_MASK;
     uint64 internal constant EBADF = 0x9;
     uint64 internal constant EINVAL = 0x16;
     uint64 internal constant EAGAIN = 0xb;
     uint64 internal constant ETIMEDOUT = 0x91;
+    uint64 internal constant EAFNOSUPPORT = 0x7c;
+    uint64 internal constant ENOENT = 0x2;
+    uint64 internal constant EFAULT = 0xe;
+    uint64 internal constant ENOSYS = 0x59;
+
+    uint64 internal constant SIGABRT = 6;
+
+    uint64 internal constant PR_SET_NAME = 15;
+    uint64 internal constant PR_SET_VMA = 0x53564d41;
 
     uint64 internal constant FUTEX_WAIT_PRIVATE = 128;
     uint64 internal constant FUTEX_WAKE_PRIVATE = 129;
+    uint64 internal constant FUTEX_WAIT_BITSET_PRIVATE = 137;
+    uint64 internal constant FUTEX_WAKE_BITSET_PRIVATE = 138;
+    uint64 internal constant FUTEX_BITSET_MATCH_ANY = 0xFF_FF_FF_FF;
     uint64 internal constant FUTEX_TIMEOUT_STEPS = 10000;
     uint64 internal constant FUTEX_NO_TIMEOUT = type(uint64).max;
     uint64 internal constant FUTEX_EMPTY_ADDR = U64_MASK;
@@ -127,6 +165,15 @@ library MIPS64Syscalls {
     uint64 internal constant HZ = 10_000_000;
     uint64 internal constant CLOCK_GETTIME_REALTIME_FLAG = 0;
     uint64 internal constant CLOCK_GETTIME_MONOTONIC_FLAG = 1;
+    uint64 internal constant TIMER_ABSTIME = 1;
+    uint64 internal constant TIMEZONE_SIZE = 8;
+    uint64 internal constant RSEQ_FLAG_UNREGISTER = 1;
+    /// @notice The only path that readlinkat resolves, /proc/self/exe, including its NUL terminator.
+    uint120 internal constant PROC_SELF_EXE = 0x2f70726f632f73656c662f65786500;
+    uint64 internal constant PROC_SELF_EXE_LENGTH = 15;
+    /// @notice The fixed target of PROC_SELF_EXE, "/exe".
+    uint64 internal constant EXE_PATH = 0x2f657865;
+    uint64 internal constant EXE_PATH_LENGTH = 4;
     /// @notice Start of the data segment.
     uint64 internal constant PROGRAM_BREAK = 0x00_00_40_00_00_00_00_00;
     uint64 internal constant HEAP_END = 0x00_00_60_00_00_00_00_00;
@@ -422,6 +469,63 @@ library MIPS64Syscalls {
         }
     }
 
+    /// @notice Checks that a guest buffer passed to a syscall is plausible, so that wild pointers fail with EFAULT.
+    /// The null page is never mapped, and a buffer must not wrap around the address space.
+    /// @param _addr The address of the buffer.
+    /// @param _size The size of the buffer in bytes.
+    /// @return valid_ Whether the buffer is valid.
+    function isValidUserPtr(uint64 _addr, uint64 _size) internal pure returns (bool valid_) {
+        valid_ = _addr >= 4096 && (_size == 0 || _addr <= type(uint64).max - (_size - 1));
+    }
+
+    /// @notice Reads the bitset of a futex bitset operation. The bitset is the 32-bit 6th syscall argument.
+    ///         An empty bitset is invalid, as it could never match a waiter.
+    /// @param _arg The 6th syscall argument.
+    /// @return bitset_ The bitset argument.
+    /// @return errno_ The error for the bitset, or 0 if the bitset is valid.
+    function futexBitset(uint64 _arg) internal pure returns (uint32 bitset_, uint64 errno_) {
+        bitset_ = uint32(_arg);
+        if (bitset_ == 0) {
+            return (0, EINVAL);
+        }
+    }
+
+    /// @notice Checks whether the path at an address is /proc/self/exe.
+    /// @param _memRoot The current memory root.
+    /// @param _proofOffset The offset of the memory proof of the leaf holding the path.
+    /// @param _addr The address of the path, which must lie within a single memory leaf.
+    /// @return match_ Whether the path is /proc/self/exe.
+    function isProcSelfExe(bytes32 _memRoot, uint256 _proofOffset, uint64 _addr) internal pure returns (bool match_) {
+        unchecked {
+            // Gather the words holding the path, in order
+            uint64 start = _addr & arch.ADDRESS_MASK;
+            uint64 end = (_addr + PROC_SELF_EXE_LENGTH - 1) & arch.ADDRESS_MASK;
+            uint256 data = 0;
+            for (uint64 i = 0; i <= (end - start) / 8; i++) {
+                data = (data << 64) | MIPS64Memory.readMem(_memRoot, start + i * 8, _proofOffset);
+            }
+            // Drop the bytes following the path
+            uint64 trailing = end + 8 - (_addr + PROC_SELF_EXE_LENGTH);
+            match_ = uint120(data >> (trailing * 8)) == PROC_SELF_EXE;
+        }
+    }
+
+    /// @notice Writes the leading bytes of EXE_PATH into a memory word.
+    /// @param _addr The address of the first byte to write.
+    /// @param _mem The memory word holding _addr.
+    /// @param _n The number of bytes to write, which must all lie within the word.
+    /// @return out_ The updated memory word.
+    function writeExePath(uint64 _addr, uint64 _mem, uint64 _n) internal pure returns (uint64 out_) {
+        unchecked {
+            out_ = _mem;
+            for (uint64 i = 0; i < _n; i++) {
+                uint64 shamt = (7 - ((_addr + i) & 7)) * 8;
+                uint64 b = (EXE_PATH >> ((EXE_PATH_LENGTH - 1 - i) * 8)) & 0xFF;
+                out_ = (out_ & ~(uint64(0xFF) << shamt)) | (b << shamt);
+            }
+        }
+    }
+
     function handleSyscallUpdates(
         st.CpuScalars memory _cpu,
         uint64[32] memory _registers,
@@ -120,6 +120,113 @@ library MIPSMemory {
         }
     }
 
+    /// @notice Writes the 32-bit values whose addresses lie in one leaf of memory, in order.
+    ///         The writes share the memory proof of the leaf, unlike a writeMem for each of them.
+    ///         Values at addresses in other leaves are skipped.
+    /// @param _leafAddr An address in the leaf to write to.
+    /// @param _proofOffset The offset of the memory proof in calldata.
+    /// @param _addrs The addresses to write to.
+    /// @param _vals The values to write.
+    /// @return newMemRoot_ The new memory root after modification
+    function writeMemLeaf(
+        uint32 _leafAddr,
+        uint256 _proofOffset,
+        uint32[] memory _addrs,
+        uint32[] memory _vals
+    )
+        internal
+        pure
+        returns (bytes32 newMemRoot_)
+    {
+        unchecked {
+            validateMemoryProofAvailability(_proofOffset);
+            assembly {
+                // Load the leaf value.
+                let leaf := calldataload(_proofOffset)
+                _proofOffset := add(_proofOffset, 32)
+
+                let count := mload(_addrs)
+                for { let i := 0 } lt(i, count) { i := add(i, 1) } {
+                    let addr := mload(add(_addrs, mul(add(i, 1), 32)))
+                    if eq(shr(5, addr), shr(5, _leafAddr)) {
+                        // Validate the address alignement.
+                        if and(addr, 3) { revert(0, 0) }
+
+                        // Mask out 4 bytes, and OR in the value
+                        let val := mload(add(_vals, mul(add(i, 1), 32)))
+                        let shamt := shl(3, sub(sub(32, 4), and(addr, 31)))
+                        leaf := or(and(leaf, not(shl(shamt, 0xFFffFFff))), shl(shamt, val))
+                    }
+                }
+
+                // Convenience function to hash two nodes together in scratch space.
+                function hashPair(a, b) -> h {
+                    mstore(0, a)
+                    mstore(32, b)
+                    h := keccak256(0, 64)
+                }
+
+                // Start with the leaf node.
+                // Work back up by combining with siblings, to reconstruct the root.
+                let path := shr(5, _leafAddr)
+                let node := leaf
+                for { let i := 0 } lt(i, 27) { i := add(i, 1) } {
+                    let sibling := calldataload(_proofOffset)
+                    _proofOffset := add(_proofOffset, 32)
+                    switch and(shr(i, path), 1)
+                    case 0 { node := hashPair(node, sibling) }
+                    case 1 { node := hashPair(sibling, node) }
+                }
+
+                newMemRoot_ := node
+            }
+            return newMemRoot_;
+        }
+    }
+
+    /// @notice Zeroes a block of memory whose size is a power of two, and that is aligned to its size.
+    ///         The block is a subtree of the memory merkle tree, which is replaced by the root of a zeroed subtree.
+    ///         The memory proof of any leaf in the block proves the whole block.
+    /// @param _addr The address of the block.
+    /// @param _proofOffset The offset of the memory proof in calldata.
+    /// @param _size The size of the block in bytes, at least a leaf.
+    /// @return newMemRoot_ The new memory root after modification
+    function zeroMem(uint32 _addr, uint256 _proofOffset, uint32 _size) internal pure returns (bytes32 newMemRoot_) {
+        unchecked {
+            validateMemoryProofAvailability(_proofOffset);
+            assembly {
+                // Convenience function to hash two nodes together in scratch space.
+                function hashPair(a, b) -> h {
+                    mstore(0, a)
+                    mstore(32, b)
+                    h := keccak256(0, 64)
+                }
+
+                // The depth of the block below its root, counted in leaves of 32 bytes.
+                let depth := 0
+                for { let s := shr(6, _size) } s { s := shr(1, s) } { depth := add(depth, 1) }
+
+                // Start with the root of a zeroed subtree of that depth.
+                let node := 0
+                for { let i := 0 } lt(i, depth) { i := add(i, 1) } { node := hashPair(node, node) }
+
+                // Work back up from the block by combining with siblings, to reconstruct the root.
+                // The leaf and the siblings within the block are skipped.
+                let path := shr(5, _addr)
+                let end := 27
+                for { let i := depth } lt(i, end) { i := add(i, 1) } {
+                    let sibling := calldataload(add(_proofOffset, mul(add(i, 1), 32)))
+                    switch and(shr(i, path), 1)
+                    case 0 { node := hashPair(node, sibling) }
+                    case 1 { node := hashPair(sibling, node) }
+                }
+
+                newMemRoot_ := node
+            }
+            return newMemRoot_;
+        }
+    }
+
     /// @notice Verifies a memory proof.
     /// @param _memRoot The expected memory root
     /// @param _addr The _addr proven.
@@ -60,9 +60,12 @@ library MIPSSyscalls {
     uint32 internal constant SYS_OPEN = 4005;
     uint32 internal constant SYS_NANOSLEEP = 4166;
     uint32 internal constant SYS_CLOCKGETTIME = 4263;
+    uint32 internal constant SYS_CLOCK_NANOSLEEP = 4265;
+    uint32 internal constant SYS_GETTIMEOFDAY = 4078;
+    uint32 internal constant SYS_MUNMAP = 4091;
+    uint32 internal constant SYS_RSEQ = 4367;
     uint32 internal constant SYS_GETPID = 4020;
     // unused syscalls
-    uint32 internal constant SYS_MUNMAP = 4091;
     uint32 internal constant SYS_GETAFFINITY = 4240;
     uint32 internal constant SYS_MADVISE = 4218;
     uint32 internal constant SYS_RTSIGPROCMASK = 4195;
@@ -82,22 +85,44 @@ library MIPSSyscalls {
     uint32 internal constant SYS_PIPE2 = 4328;
     uint32 internal constant SYS_EPOLLCTL = 4249;
     uint32 internal constant SYS_EPOLLPWAIT = 4313;
+    uint32 internal constant SYS_EPOLLCREATE = 4248;
+    uint32 internal constant SYS_EPOLLWAIT = 4250;
     uint32 internal constant SYS_GETRANDOM = 4353;
     uint32 internal constant SYS_UNAME = 4122;
     uint32 internal constant SYS_STAT64 = 4213;
     uint32 internal constant SYS_GETUID = 4024;
     uint32 internal constant SYS_GETGID = 4047;
+    uint32 internal constant SYS_GETEUID = 4049;
+    uint32 internal constant SYS_GETEGID = 4050;
     uint32 internal constant SYS_LLSEEK = 4140;
     uint32 internal constant SYS_MINCORE = 4217;
     uint32 internal constant SYS_TGKILL = 4266;
     uint32 internal constant SYS_GETRLIMIT = 4076;
     uint32 internal constant SYS_LSEEK = 4019;
+    uint32 internal constant SYS_SETROBUSTLIST = 4309;
+    uint32 internal constant SYS_PRCTL = 4192;
+    uint32 internal constant SYS_FACCESSAT = 4300;
+    uint32 internal constant SYS_POLL = 4188;
+    uint32 internal constant SYS_PPOLL = 4302;
 
     // profiling-related syscalls - ignored
     uint32 internal constant SYS_SETITIMER = 4104;
     uint32 internal constant SYS_TIMERCREATE = 4257;
     uint32 internal constant SYS_TIMERSETTIME = 4258;
     uint32 internal constant SYS_TIMERDELETE = 4261;
+    // network syscalls - no network is available, so these fail with EAFNOSUPPORT
+    uint32 internal constant SYS_SOCKET = 4183;
+    uint32 internal constant SYS_CONNECT = 4170;
+    uint32 internal constant SYS_ACCEPT = 4168;
+    uint32 internal constant SYS_BIND = 4169;
+    uint32 internal constant SYS_LISTEN = 4174;
+    uint32 internal constant SYS_SENDTO = 4180;
+    uint32 internal constant SYS_RECVFROM = 4176;
+    // SysV shared memory syscalls - shared memory is not modeled, so these fail with ENOSYS
+    uint32 internal constant SYS_SHMGET = 4395;
+    uint32 internal constant SYS_SHMAT = 4397;
+    uint32 internal constant SYS_SHMCTL = 4396;
+    uint32 internal constant SYS_SHMDT = 4398;
 
     uint32 internal constant FD_STDIN = 0;
     uint32 internal constant FD_STDOUT = 1;
@@ -106,15 +131,28 @@ library MIPSSyscalls {
     uint32 internal constant FD_HINT_WRITE = 4;
     uint32 internal constant FD_PREIMAGE_READ = 5;
     uint32 internal constant FD_PREIMAGE_WRITE = 6;
+    uint32 internal constant FD_EPOLL = 7;
 
     uint32 internal constant SYS_ERROR_SIGNAL = 0xFF_FF_FF_FF;
     uint32 internal constant EBADF = 0x9;
     uint32 internal constant EINVAL = 0x16;
     uint32 internal constant EAGAIN = 0xb;
     uint32 internal constant ETIMEDOUT = 0x91;
+    uint32 internal constant EAFNOSUPPORT = 0x7c;
+    uint32 internal constant ENOENT = 0x2;
+    uint32 internal constant EFAULT = 0xe;
+    uint32 internal constant ENOSYS = 0x59;
+
+    uint32 internal constant SIGABRT = 6;
+
+    uint32 internal constant PR_SET_NAME = 15;
+    uint32 internal constant PR_SET_VMA = 0x53564d41;
 
     uint32 internal constant FUTEX_WAIT_PRIVATE = 128;
     uint32 internal constant FUTEX_WAKE_PRIVATE = 129;
+    uint32 internal constant FUTEX_WAIT_BITSET_PRIVATE = 137;
+    uint32 internal constant FUTEX_WAKE_BITSET_PRIVATE = 138;
+    uint32 internal constant FUTEX_BITSET_MATCH_ANY = 0xFF_FF_FF_FF;
     uint32 internal constant FUTEX_TIMEOUT_STEPS = 10000;
     uint64 internal constant FUTEX_NO_TIMEOUT = type(uint64).max;
     uint32 internal constant FUTEX_EMPTY_ADDR = 0xFF_FF_FF_FF;
@@ -123,6 +161,17 @@ library MIPSSyscalls {
     uint32 internal constant HZ = 10_000_000;
     uint32 internal constant CLOCK_GETTIME_REALTIME_FLAG = 0;
     uint32 internal constant CLOCK_GETTIME_MONOTONIC_FLAG = 1;
+    uint32 internal constant TIMER_ABSTIME = 1;
+    uint32 internal constant TIMEZONE_SIZE = 8;
+    uint32 internal constant RSEQ_FLAG_UNREGISTER = 1;
+    /// @notice The offset from the stack pointer of the 6th syscall argument.
+    uint32 internal constant SYSCALL_PARAM6_STACK_OFFSET = 20;
+    /// @notice The only path that readlinkat resolves, /proc/self/exe, including its NUL terminator.
+    uint120 internal constant PROC_SELF_EXE = 0x2f70726f632f73656c662f65786500;
+    uint32 internal constant PROC_SELF_EXE_LENGTH = 15;
+    /// @notice The fixed target of PROC_SELF_EXE, "/exe".
+    uint32 internal constant EXE_PATH = 0x2f657865;
+    uint32 internal constant EXE_PATH_LENGTH = 4;
     /// @notice Start of the data segment.
     uint32 internal constant PROGRAM_BREAK = 0x40000000;
     uint32 internal constant HEAP_END = 0x60000000;
@@ -413,6 +462,76 @@ library MIPSSyscalls {
         }
     }
 
+    /// @notice Checks that a guest buffer passed to a syscall is plausible, so that wild pointers fail with EFAULT.
+    /// The null page is never mapped, and a buffer must not wrap around the address space.
+    /// @param _addr The address of the buffer.
+    /// @param _size The size of the buffer in bytes.
+    /// @return valid_ Whether the buffer is valid.
+    function isValidUserPtr(uint32 _addr, uint32 _size) internal pure returns (bool valid_) {
+        valid_ = _addr >= 4096 && (_size == 0 || _addr <= type(uint32).max - (_size - 1));
+    }
+
+    /// @notice Reads the bitset of a futex bitset operation. The bitset is the 6th syscall argument, passed on the
+    ///         stack. An empty bitset is invalid, as it could never match a waiter.
+    /// @param _memRoot The current memory root.
+    /// @param _proofOffset The offset of the memory proof of the stack argument.
+    /// @param _sp The stack pointer.
+    /// @return bitset_ The bitset argument.
+    /// @return errno_ The error for the bitset, or 0 if the bitset is valid.
+    function futexBitset(
+        bytes32 _memRoot,
+        uint256 _proofOffset,
+        uint32 _sp
+    )
+        internal
+        pure
+        returns (uint32 bitset_, uint32 errno_)
+    {
+        if (!isValidUserPtr(_sp, SYSCALL_PARAM6_STACK_OFFSET + 4) || (_sp & 3) != 0) {
+            return (0, EFAULT);
+        }
+        bitset_ = MIPSMemory.readMem(_memRoot, _sp + SYSCALL_PARAM6_STACK_OFFSET, _proofOffset);
+        if (bitset_ == 0) {
+            return (0, EINVAL);
+        }
+    }
+
+    /// @notice Checks whether the path at an address is /proc/self/exe.
+    /// @param _memRoot The current memory root.
+    /// @param _proofOffset The offset of the memory proof of the leaf holding the path.
+    /// @param _addr The address of the path, which must lie within a single memory leaf.
+    /// @return match_ Whether the path is /proc/self/exe.
+    function isProcSelfExe(bytes32 _memRoot, uint256 _proofOffset, uint32 _addr) internal pure returns (bool match_) {
+        unchecked {
+            // Gather the words holding the path, in order
+            uint32 start = _addr & 0xFFffFFfc;
+            uint32 end = (_addr + PROC_SELF_EXE_LENGTH - 1) & 0xFFffFFfc;
+            uint256 data = 0;
+            for (uint32 i = 0; i <= (end - start) / 4; i++) {
+                data = (data << 32) | MIPSMemory.readMem(_memRoot, start + i * 4, _proofOffset);
+            }
+            // Drop the bytes following the path
+            uint32 trailing = end + 4 - (_addr + PROC_SELF_EXE_LENGTH);
+            match_ = uint120(data >> (trailing * 8)) == PROC_SELF_EXE;
+        }
+    }
+
+    /// @notice Writes the leading bytes of EXE_PATH into a memory word.
+    /// @param _addr The address of the first byte to write.
+    /// @param _mem The memory word holding _addr.
+    /// @param _n The number of bytes to write, which must all lie within the word.
+    /// @return out_ The updated memory word.
+    function writeExePath(uint32 _addr, uint32 _mem, uint32 _n) internal pure returns (uint32 out_) {
+        unchecked {
+            out_ = _mem;
+            for (uint32 i = 0; i < _n; i++) {
+                uint32 shamt = (3 - ((_addr + i) & 3)) * 8;
+                uint32 b = (EXE_PATH >> ((EXE_PATH_LENGTH - 1 - i) * 8)) & 0xFF;
+                out_ = (out_ & ~(uint32(0xFF) << shamt)) | (b << shamt);
+            }
+        }
+    }
+
     function handleSyscallUpdates(
         st.CpuScalars memory _cpu,
         uint32[32] memory _registers,
@@ -182,6 +182,7 @@ contract MIPS2_Test is CommonTest {
             futexAddr: sys.FUTEX_EMPTY_ADDR,
             futexVal: 0,
             futexTimeoutStep: 0,
+            futexBitset: 0,
             pc: 4,
             nextPC: 8,
             lo: 0,
@@ -205,6 +206,7 @@ contract MIPS2_Test is CommonTest {
             step: 1,
             stepsSinceLastContextSwitch: 1,
             wakeup: sys.FUTEX_EMPTY_ADDR,
+            wakeupBitset: 0,
             traverseRight: false,
             leftThreadStack: threadRoot,
             rightThreadStack: EMPTY_THREAD_ROOT,
@@ -352,6 +354,7 @@ contract MIPS2_Test is CommonTest {
         newThread.futexAddr = sys.FUTEX_EMPTY_ADDR;
         newThread.futexVal = 0;
         newThread.futexTimeoutStep = 0;
+        newThread.futexBitset = 0;
         newThread.pc = thread.nextPC;
         newThread.nextPC = thread.nextPC + 4;
         newThread.registers[2] = 0;
@@ -422,6 +425,7 @@ contract MIPS2_Test is CommonTest {
         expectThread.futexAddr = futexAddr;
         expectThread.futexVal = futexVal;
         expectThread.futexTimeoutStep = state.step + 1 + sys.FUTEX_TIMEOUT_STEPS;
+        expectThread.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         threading.replaceCurrent(expectThread);
 
         IMIPS2.State memory expect = copyState(state);
@@ -457,6 +461,7 @@ contract MIPS2_Test is CommonTest {
         expectThread.futexAddr = futexAddr;
         expectThread.futexVal = futexVal;
         expectThread.futexTimeoutStep = sys.FUTEX_NO_TIMEOUT;
+        expectThread.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         threading.replaceCurrent(expectThread);
 
         IMIPS2.State memory expect = copyState(state);
@@ -531,6 +536,7 @@ contract MIPS2_Test is CommonTest {
 
         IMIPS2.State memory expect = copyState(state);
         expect.wakeup = futexAddr;
+        expect.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         expect.step = state.step + 1;
         expect.stepsSinceLastContextSwitch = 0;
         expect.traverseRight = true;
@@ -968,6 +974,7 @@ contract MIPS2_Test is CommonTest {
 
         IMIPS2.State memory state;
         state.wakeup = 0xabba;
+        state.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         finalizeThreadingState(threading, state);
 
         // Preempt the current thread on spurious wakeup
@@ -988,6 +995,7 @@ contract MIPS2_Test is CommonTest {
     function test_threadWakeupFullTraversalNoWakeup_succeeds() public {
         IMIPS2.State memory state;
         state.wakeup = 0x1000;
+        state.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         state.step = 10;
         state.stepsSinceLastContextSwitch = 10;
         finalizeThreadingState(threading, state);
@@ -1046,6 +1054,7 @@ contract MIPS2_Test is CommonTest {
                 // When we reach the last thread, we should clear the wakeup and resume normal execution
                 expect.traverseRight = false;
                 expect.wakeup = sys.FUTEX_EMPTY_ADDR;
+                expect.wakeupBitset = 0;
             }
 
             bytes32 postState = mips.step(encodeState(state), bytes.concat(threadWitness, memProof), 0);
@@ -1063,6 +1072,7 @@ contract MIPS2_Test is CommonTest {
         IMIPS2.State memory state;
         state.traverseRight = true;
         state.wakeup = 0x1000;
+        state.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         state.stepsSinceLastContextSwitch = 10;
         finalizeThreadingState(threading, state);
         bytes memory threadWitness = threading.witness();
@@ -1076,6 +1086,7 @@ contract MIPS2_Test is CommonTest {
         // right stack.
         expect.stepsSinceLastContextSwitch = 0;
         expect.wakeup = sys.FUTEX_EMPTY_ADDR;
+        expect.wakeupBitset = 0;
         expect.traverseRight = false;
         finalizeThreadingState(threading, expect);
 
@@ -1092,6 +1103,7 @@ contract MIPS2_Test is CommonTest {
         threadB.futexAddr = 0x1000;
         threadB.futexVal = 0xdead;
         threadB.futexTimeoutStep = 10;
+        threadB.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         threading.replaceCurrent(threadB);
         bytes memory threadWitness = threading.witness();
 
@@ -1108,6 +1120,7 @@ contract MIPS2_Test is CommonTest {
         expectThread.futexAddr = sys.FUTEX_EMPTY_ADDR;
         expectThread.futexVal = 0x0;
         expectThread.futexTimeoutStep = 0;
+        expectThread.futexBitset = 0;
         expectThread.registers[2] = sys.SYS_ERROR_SIGNAL;
         expectThread.registers[7] = sys.ETIMEDOUT;
         threading.replaceCurrent(expectThread);
@@ -1139,6 +1152,7 @@ contract MIPS2_Test is CommonTest {
         threadB.futexAddr = _wakeup;
         threadB.futexVal = _futexVal;
         threadB.futexTimeoutStep = _futexTimeoutStep;
+        threadB.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         // A thread exit cannot interrupt wakeup traversal. thread.exited during wakeup is technically not a valid
         // state.
         // But we fuzz this anyways to ensure the VM only traverses threads during wakeup
@@ -1150,6 +1164,7 @@ contract MIPS2_Test is CommonTest {
         IMIPS2.State memory state;
         bytes memory memProof; // unused
         state.wakeup = _wakeup;
+        state.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         state.step = 10;
         state.stepsSinceLastContextSwitch = 20; // must be unchanged
         finalizeThreadingState(threading, state);
@@ -1162,6 +1177,42 @@ contract MIPS2_Test is CommonTest {
         IMIPS2.State memory expect = copyState(state);
         expect.step = state.step + 1;
         expect.wakeup = sys.FUTEX_EMPTY_ADDR;
+        expect.wakeupBitset = 0;
+        finalizeThreadingState(threading, expect);
+
+        bytes32 postState = mips.step(encodeState(state), bytes.concat(threadWitness, memProof), 0);
+        assertEq(postState, outputState(expect), "unexpected post state");
+    }
+
+    /// @dev Static unit test asserting wakeup skips a waiter whose bitset is disjoint from the wakeup bitset
+    function testFuzz_wakeupDisjointBitset_succeeds(uint32 _wakeupBitset) public {
+        _wakeupBitset = uint32(_bound(_wakeupBitset, 1, type(uint32).max - 1));
+
+        threading.createThread();
+        threading.createThread();
+        IMIPS2.ThreadState memory threadB = threading.current();
+        threadB.futexAddr = 0x1000;
+        threadB.futexVal = 0xdead;
+        threadB.futexTimeoutStep = sys.FUTEX_NO_TIMEOUT;
+        threadB.futexBitset = ~_wakeupBitset;
+        threading.replaceCurrent(threadB);
+        bytes memory threadWitness = threading.witness();
+
+        IMIPS2.State memory state;
+        bytes memory memProof; // unused
+        state.wakeup = 0x1000;
+        state.wakeupBitset = _wakeupBitset;
+        state.step = 10;
+        state.stepsSinceLastContextSwitch = 20;
+        finalizeThreadingState(threading, state);
+
+        // The waiter is not woken up, so it is preempted like any other thread
+        threading.left().pop();
+        threading.right().push(threadB);
+
+        IMIPS2.State memory expect = copyState(state);
+        expect.step = state.step + 1;
+        expect.stepsSinceLastContextSwitch = 0;
         finalizeThreadingState(threading, expect);
 
         bytes32 postState = mips.step(encodeState(state), bytes.concat(threadWitness, memProof), 0);
@@ -1196,6 +1247,7 @@ contract MIPS2_Test is CommonTest {
         IMIPS2.State memory state;
         bytes memory memProof; // unused
         state.wakeup = _wakeup;
+        state.wakeupBitset = sys.FUTEX_BITSET_MATCH_ANY;
         state.step = 10;
         state.stepsSinceLastContextSwitch = 20;
         finalizeThreadingState(threading, state);
@@ -1224,6 +1276,7 @@ contract MIPS2_Test is CommonTest {
         threadB.futexAddr = 0x1000;
         threadB.futexVal = 0xdead;
         threadB.futexTimeoutStep = 100;
+        threadB.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         threading.replaceCurrent(threadB);
         bytes memory threadWitness = threading.witness();
 
@@ -1242,6 +1295,7 @@ contract MIPS2_Test is CommonTest {
         expectThread.futexAddr = sys.FUTEX_EMPTY_ADDR;
         expectThread.futexVal = 0x0;
         expectThread.futexTimeoutStep = 0;
+        expectThread.futexBitset = 0;
         expectThread.registers[2] = 0;
         expectThread.registers[7] = 0; // errno
         threading.replaceCurrent(expectThread);
@@ -1263,6 +1317,7 @@ contract MIPS2_Test is CommonTest {
         threadB.futexAddr = 0x1000;
         threadB.futexVal = 0xdead;
         threadB.futexTimeoutStep = sys.FUTEX_NO_TIMEOUT;
+        threadB.futexBitset = sys.FUTEX_BITSET_MATCH_ANY;
         threading.replaceCurrent(threadB);
         bytes memory threadWitness = threading.witness();
 
@@ -2780,7 +2835,12 @@ contract MIPS2_Test is CommonTest {
             _state.llOwnerThread, _state.exitCode, _state.exited, _state.step, _state.stepsSinceLastContextSwitch
         );
         bytes memory c = abi.encodePacked(
-            _state.wakeup, _state.traverseRight, _state.leftThreadStack, _state.rightThreadStack, _state.nextThreadID
+            _state.wakeup,
+            _state.wakeupBitset,
+            _state.traverseRight,
+            _state.leftThreadStack,
+            _state.rightThreadStack,
+            _state.nextThreadID
         );
         return abi.encodePacked(a, b, c);
     }
@@ -2849,6 +2909,7 @@ function encodeThread(IMIPS2.ThreadState memory _thread) pure returns (bytes mem
         _thread.futexAddr,
         _thread.futexVal,
         _thread.futexTimeoutStep,
+        _thread.futexBitset,
         _thread.pc,
         _thread.nextPC,
         _thread.lo,